@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"ptp/internal/cli"
 	"ptp/internal/cli/commands"
@@ -23,17 +26,24 @@ func main() {
 		Version: version,
 	}
 
+	// Create initial config from defaults and the project configuration file
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	var debugFlag bool
 	rootCmd.PersistentFlags().BoolVar(&debugFlag, "debug", false, "Enable debug logging to stderr")
 	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
 		if debugFlag {
 			debug.Enable()
 		}
+		if cfg.File != "" {
+			debug.Logf("config: loaded %s", cfg.File)
+		}
 	}
 
-	// Create initial config with defaults
-	cfg := config.New()
-
 	// Create flags struct (will be populated by command flags)
 	var flags cli.Flags
 
@@ -43,8 +53,18 @@ func main() {
 	// Register all commands
 	cmds.Register(rootCmd, &flags, cfg)
 
+	// Ctrl+C / SIGTERM cancel the root context: running phpunit and artisan processes are
+	// killed and the run saves what it has. A second signal terminates immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
 	// Execute root command
-	if err := rootCmd.Execute(); err != nil {
+	err = rootCmd.ExecuteContext(ctx)
+	stop()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -1,14 +1,22 @@
 package commands
 
 import (
+	"fmt"
+	"slices"
+	"strings"
+
 	"ptp/internal/cli"
 	"ptp/internal/config"
+	"ptp/internal/coverage"
 	"ptp/internal/discovery"
 	"ptp/internal/execution"
 	"ptp/internal/migration"
 	"ptp/internal/parser"
+	"ptp/internal/remote"
+	"ptp/internal/report"
 	"ptp/internal/storage"
 	"ptp/internal/ui"
+	"ptp/internal/vcs"
 
 	"github.com/spf13/cobra"
 )
@@ -16,9 +24,15 @@ import (
 // Commands holds all CLI commands
 type Commands struct {
 	Run     *RunCommand
+	Watch   *WatchCommand
 	List    *ListCommand
 	Migrate *MigrateCommand
+	Serve   *ServeWorkerCommand
 	Faills  *FaillsCommand
+	Report  *ReportCommand
+	Flaky   *FlakyCommand
+	History *HistoryCommand
+	Diff    *DiffCommand
 	Upgrade *UpgradeCommand
 }
 
@@ -29,20 +43,28 @@ func NewCommands(cfg *config.Config) *Commands {
 	filter := discovery.NewFilter()
 	testCaseParser := discovery.NewParser()
 	runner := execution.NewRunner(cfg)
-	scheduler := execution.NewRoundRobinScheduler()
-	phpunitParser := parser.NewPHPUnitParser()
-	executor := execution.NewWorkerPool(cfg, runner, scheduler, phpunitParser)
+	scheduler := execution.NewQueueScheduler(nil)
+	resultParser := parser.New(cfg.GetFramework())
+	executor := execution.NewWorkerPool(cfg, runner, scheduler, resultParser)
 	jsonStorage := storage.NewJSONStorage(cfg)
 	formatter := ui.NewFormatter(cfg, testCaseParser)
 	dbManager := migration.NewDatabaseManager(cfg)
 	migrator := migration.NewLaravelMigrator(cfg, dbManager)
-	errorViewer := ui.NewErrorViewer(cfg, jsonStorage, runner, phpunitParser)
+	errorViewer := ui.NewErrorViewer(cfg, jsonStorage, runner, resultParser)
+
+	run := NewRunCommand(cfg, scanner, filter, testCaseParser, executor, resultParser, jsonStorage, formatter, migrator, errorViewer)
 
 	return &Commands{
-		Run:     NewRunCommand(cfg, scanner, filter, executor, phpunitParser, jsonStorage, formatter, migrator, errorViewer),
-		List:    NewListCommand(cfg, scanner, filter, formatter, jsonStorage),
+		Run:     run,
+		Watch:   NewWatchCommand(cfg, run),
+		List:    NewListCommand(cfg, scanner, filter, testCaseParser, formatter, jsonStorage),
 		Migrate: NewMigrateCommand(cfg, migrator),
+		Serve:   NewServeWorkerCommand(cfg, migrator),
 		Faills:  NewFaillsCommand(cfg, jsonStorage, errorViewer),
+		Report:  NewReportCommand(cfg, jsonStorage),
+		Flaky:   NewFlakyCommand(cfg, jsonStorage, formatter),
+		History: NewHistoryCommand(cfg, jsonStorage, formatter),
+		Diff:    NewDiffCommand(cfg, jsonStorage, formatter),
 		Upgrade: NewUpgradeCommand(),
 	}
 }
@@ -54,34 +76,136 @@ func (c *Commands) Register(rootCmd *cobra.Command, flags *cli.Flags, cfg *confi
 		Use:        "run",
 		Short:      "Run PHPUnit tests in parallel",
 		Long:       "Discover and execute PHPUnit tests using parallel workers",
+		Args:       runArgs,
 		RunE:       c.Run.Execute,
 		SilenceUsage: true,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			// Update config with flags after parsing
+			applyMigrationFlags(cmd, flags)
 			cfg.Flags = flags.ToConfigFlags()
 			if flags.Processors > 0 {
 				cfg.Processors = flags.Processors
 			}
+			if flags.Scheduler != "" {
+				cfg.Scheduler = flags.Scheduler
+			}
+			cfg.Split = flags.Split
+			cfg.SplitThreshold = flags.SplitThreshold
+			cfg.Timeout = flags.Timeout
+			if flags.Retries < 0 {
+				return fmt.Errorf("--retries must not be negative, got %d", flags.Retries)
+			}
+			cfg.Retries = flags.Retries
+			if flags.Shard != "" {
+				if _, err := execution.ParseShard(flags.Shard); err != nil {
+					return fmt.Errorf("--shard: %w", err)
+				}
+			}
+			for _, format := range cfg.GetReports() {
+				if format != report.FormatGitHub && format != report.FormatGitLab {
+					return fmt.Errorf("unknown --report %q (expected %s or %s)", format, report.FormatGitHub, report.FormatGitLab)
+				}
+			}
+			if flags.Coverage != "" {
+				if !slices.Contains(coverage.Formats, flags.Coverage) {
+					return fmt.Errorf("unknown --coverage %q (expected %s)", flags.Coverage, strings.Join(coverage.Formats, ", "))
+				}
+				if flags.Remote != "" {
+					return fmt.Errorf("--coverage cannot be combined with --remote: the agents' coverage stays on their machines")
+				}
+			}
+			switch flags.Format {
+			case "terminal":
+			case formatNDJSON:
+				if flags.UI == ui.UIDashboard || flags.OpenFaills {
+					return fmt.Errorf("--format=%s cannot be combined with --ui=%s or --open-faills", formatNDJSON, ui.UIDashboard)
+				}
+			default:
+				return fmt.Errorf("unknown --format %q (expected terminal or %s)", flags.Format, formatNDJSON)
+			}
+			if flags.UI != ui.UIPlain && flags.UI != ui.UIDashboard {
+				return fmt.Errorf("unknown --ui %q (expected %s or %s)", flags.UI, ui.UIPlain, ui.UIDashboard)
+			}
 			return nil
 		},
 	}
-	runCmd.Flags().IntVarP(&flags.Processors, "processors", "p", 4, "Number of processors to use")
-	runCmd.Flags().BoolVar(&flags.SkipMigrate, "skip-migrate", false, "Skip running migrations before tests")
-	runCmd.Flags().BoolVar(&flags.Fresh, "fresh", false, "Run migrate:fresh instead of migrate (drop all tables first)")
+	runCmd.Flags().IntVarP(&flags.Processors, "processors", "p", cfg.Processors, "Number of processors to use")
+	runCmd.Flags().BoolVar(&flags.SkipMigrate, "skip-migrate", cfg.MigrationMode == config.MigrationSkip, "Skip running migrations before tests")
+	runCmd.Flags().BoolVar(&flags.Fresh, "fresh", cfg.MigrationMode == config.MigrationFresh, "Run migrate:fresh instead of migrate (drop all tables first)")
+	runCmd.MarkFlagsMutuallyExclusive("skip-migrate", "fresh")
 	runCmd.Flags().StringVarP(&flags.TestPath, "test-path", "t", "", "Path to the folder where test detection should start")
 	runCmd.Flags().StringVarP(&flags.NameFilter, "filter", "f", "", "Filter tests by name pattern (supports wildcards, e.g., '*UserTest.php' or '*Payment*')")
+	runCmd.Flags().StringVar(&flags.TestSuite, "testsuite", "", "Run only these test suites of phpunit.xml (comma-separated)")
+	runCmd.Flags().StringVar(&flags.Group, "group", "", "Run only tests in these groups (#[Group] or @group, comma-separated)")
+	runCmd.Flags().StringVar(&flags.ExcludeGroup, "exclude-group", "", "Skip tests in these groups (#[Group] or @group, comma-separated)")
 	runCmd.Flags().BoolVar(&flags.FailFast, "fail-fast", false, "Stop on first test failure")
 	runCmd.Flags().BoolVar(&flags.OnlyFailed, "failed", false, "Run only tests that failed in the last run (from storage/test-results.json)")
-	runCmd.Flags().BoolVar(&flags.RerunFailures, "rerun-failures", false, "After running all tests, rerun only failed ones once and save that result")
+	runCmd.Flags().StringVar(&flags.Changed, "changed", "", "Run only tests affected by files changed since this git ref, given as --changed=<ref> (default: the upstream branch, or uncommitted changes)")
+	runCmd.Flags().Lookup("changed").NoOptDefVal = vcs.Upstream
+	runCmd.Flags().StringVar(&flags.Shard, "shard", "", "Run only the i-th of n partitions of the tests, balanced by recorded timings (e.g. 2/4 on the second of four CI machines)")
+	runCmd.Flags().StringVar(&flags.Remote, "remote", "", "Also run tests on these `ptp serve-worker` agents (host[:port], comma-separated)")
+	runCmd.Flags().BoolVar(&flags.RerunFailures, "rerun-failures", false, "Retry failed test cases once (same as --retries=1)")
+	runCmd.Flags().IntVar(&flags.Retries, "retries", cfg.Retries, "Retry failed test cases up to N times; cases that pass on a retry are reported as flaky")
 	runCmd.Flags().BoolVar(&flags.OpenFaills, "open-faills", false, "Open the faills viewer when the run finishes with failures")
+	runCmd.Flags().StringVar(&flags.Format, "format", "terminal", "Output format: terminal, or ndjson to stream one JSON event per line to stdout (for editor plugins)")
+	runCmd.Flags().StringVar(&flags.UI, "ui", ui.UIPlain, "Progress display: plain (progress bar) or dashboard (live workers, failures and ETA; needs a terminal)")
+	runCmd.Flags().StringVar(&flags.ReportJUnit, "report-junit", "", "Write a JUnit XML report of the run to this path (for CI test reporters)")
+	runCmd.Flags().StringVar(&flags.Coverage, "coverage", "", "Collect code coverage across the workers into one report: clover, cobertura or html (html needs phpcov)")
+	runCmd.Flags().StringVar(&flags.CoverageOutput, "coverage-output", "", "Path of the coverage report, a directory for html (default coverage/clover.xml, coverage/cobertura.xml or coverage/html in the project)")
+	runCmd.Flags().StringVar(&flags.Trace, "trace", "", "Write the worker timeline of the run to this path as a Chrome trace (chrome://tracing, ui.perfetto.dev)")
+	runCmd.Flags().StringVar(&flags.Report, "report", "", "CI reports of the failures: github (annotations and job summary) or gitlab (Code Quality report), comma-separated")
+	runCmd.Flags().StringVar(&flags.Scheduler, "scheduler", cfg.Scheduler, "Test scheduling strategy: queue (shared queue, slowest first), lpt (balanced per-worker plan) or round-robin")
+	runCmd.Flags().BoolVar(&flags.Split, "split", cfg.Split, "Split heavy test files (by historical timings) into chunks of test cases run in parallel")
+	runCmd.Flags().DurationVar(&flags.SplitThreshold, "split-threshold", cfg.SplitThreshold, "Split files whose recorded duration is at least this long (default: automatic)")
+	runCmd.Flags().DurationVar(&flags.Timeout, "timeout", cfg.Timeout, "Kill a test file that runs longer than this and record it as timed out (e.g. 5m; 0 = no limit)")
 	rootCmd.AddCommand(runCmd)
 
+	// Watch command
+	watchCmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Rerun affected tests whenever files are saved",
+		Long:  "Watch the project for changes and rerun the changed test files and the tests of changed source files in parallel",
+		RunE:  c.Watch.Execute,
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			applyMigrationFlags(cmd, flags)
+			cfg.Flags = flags.ToConfigFlags()
+			if flags.Processors > 0 {
+				cfg.Processors = flags.Processors
+			}
+			cfg.Timeout = flags.Timeout
+			if flags.Retries < 0 {
+				return fmt.Errorf("--retries must not be negative, got %d", flags.Retries)
+			}
+			cfg.Retries = flags.Retries
+			if flags.PollInterval <= 0 || flags.Debounce < 0 {
+				return fmt.Errorf("--interval must be positive and --debounce must not be negative")
+			}
+			return nil
+		},
+	}
+	watchCmd.Flags().IntVarP(&flags.Processors, "processors", "p", cfg.Processors, "Number of processors to use")
+	watchCmd.Flags().BoolVar(&flags.SkipMigrate, "skip-migrate", cfg.MigrationMode == config.MigrationSkip, "Skip running migrations when watching starts")
+	watchCmd.Flags().BoolVar(&flags.Fresh, "fresh", cfg.MigrationMode == config.MigrationFresh, "Run migrate:fresh instead of migrate when watching starts")
+	watchCmd.MarkFlagsMutuallyExclusive("skip-migrate", "fresh")
+	watchCmd.Flags().StringVarP(&flags.TestPath, "test-path", "t", "", "Path to the folder where test detection should start")
+	watchCmd.Flags().StringVarP(&flags.NameFilter, "filter", "f", "", "Filter tests by name pattern (supports wildcards, e.g., '*UserTest.php' or '*Payment*')")
+	watchCmd.Flags().StringVar(&flags.TestSuite, "testsuite", "", "Watch only these test suites of phpunit.xml (comma-separated)")
+	watchCmd.Flags().StringVar(&flags.Group, "group", "", "Rerun only tests in these groups (#[Group] or @group, comma-separated)")
+	watchCmd.Flags().StringVar(&flags.ExcludeGroup, "exclude-group", "", "Skip tests in these groups (#[Group] or @group, comma-separated)")
+	watchCmd.Flags().IntVar(&flags.Retries, "retries", cfg.Retries, "Retry failed test cases up to N times; cases that pass on a retry are reported as flaky")
+	watchCmd.Flags().DurationVar(&flags.Timeout, "timeout", cfg.Timeout, "Kill a test file that runs longer than this and record it as timed out (e.g. 5m; 0 = no limit)")
+	watchCmd.Flags().DurationVar(&flags.PollInterval, "interval", config.DefaultWatchInterval, "How often to poll the project for changes")
+	watchCmd.Flags().DurationVar(&flags.Debounce, "debounce", config.DefaultWatchDebounce, "Wait this long after the last change of a burst of saves before rerunning")
+	rootCmd.AddCommand(watchCmd)
+
 	// List command
 	listCmd := &cobra.Command{
 		Use:   "list",
 		Short: "List discovered tests",
 		Long:  "Scan and list all PHPUnit tests without executing them",
 		RunE:  c.List.Execute,
+		SilenceUsage: true,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			cfg.Flags = flags.ToConfigFlags()
 			return nil
@@ -89,6 +213,9 @@ func (c *Commands) Register(rootCmd *cobra.Command, flags *cli.Flags, cfg *confi
 	}
 	listCmd.Flags().StringVarP(&flags.NameFilter, "filter", "f", "", "Filter tests by name pattern (supports wildcards, e.g., '*UserTest.php' or '*Payment*')")
 	listCmd.Flags().StringVarP(&flags.TestPath, "test-path", "t", "", "Path to the folder where test detection should start")
+	listCmd.Flags().StringVar(&flags.TestSuite, "testsuite", "", "List only these test suites of phpunit.xml (comma-separated)")
+	listCmd.Flags().StringVar(&flags.Group, "group", "", "List only tests in these groups (#[Group] or @group, comma-separated)")
+	listCmd.Flags().StringVar(&flags.ExcludeGroup, "exclude-group", "", "Skip tests in these groups (#[Group] or @group, comma-separated)")
 	listCmd.Flags().BoolVarP(&flags.TestCases, "test-cases", "c", false, "List test cases instead of test files")
 	rootCmd.AddCommand(listCmd)
 
@@ -106,19 +233,186 @@ func (c *Commands) Register(rootCmd *cobra.Command, flags *cli.Flags, cfg *confi
 			return nil
 		},
 	}
-	migrateCmd.Flags().IntVarP(&flags.Processors, "processors", "p", 4, "Number of processors/workers to use")
-	migrateCmd.Flags().BoolVar(&flags.Fresh, "fresh", false, "Run migrate:fresh instead of migrate (drop all tables first)")
+	migrateCmd.Flags().IntVarP(&flags.Processors, "processors", "p", cfg.Processors, "Number of processors/workers to use")
+	migrateCmd.Flags().BoolVar(&flags.Fresh, "fresh", cfg.MigrationMode == config.MigrationFresh, "Run migrate:fresh instead of migrate (drop all tables first)")
 	rootCmd.AddCommand(migrateCmd)
 
+	// Serve-worker command
+	serveCmd := &cobra.Command{
+		Use:   "serve-worker",
+		Short: "Run tests for other machines (see run --remote)",
+		Long:  "Serve this checkout as a remote worker agent: coordinators started with --remote hand test files to its slots, which run them with the local test runner and worker databases",
+		RunE:  c.Serve.Execute,
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			applyMigrationFlags(cmd, flags)
+			cfg.Flags = flags.ToConfigFlags()
+			if flags.Processors > 0 {
+				cfg.Processors = flags.Processors
+			}
+			return nil
+		},
+	}
+	serveCmd.Flags().StringVar(&flags.Listen, "listen", ":"+remote.DefaultPort, "Address to listen on")
+	serveCmd.Flags().IntVarP(&flags.Processors, "processors", "p", cfg.Processors, "Number of test files run at the same time (slots)")
+	serveCmd.Flags().BoolVar(&flags.SkipMigrate, "skip-migrate", cfg.MigrationMode == config.MigrationSkip, "Skip running migrations before serving")
+	serveCmd.Flags().BoolVar(&flags.Fresh, "fresh", cfg.MigrationMode == config.MigrationFresh, "Run migrate:fresh instead of migrate (drop all tables first)")
+	serveCmd.MarkFlagsMutuallyExclusive("skip-migrate", "fresh")
+	rootCmd.AddCommand(serveCmd)
+
 	// Faills command
 	faillsCmd := &cobra.Command{
 		Use:   "faills",
 		Short: "View test failures interactively",
-		Long:  "Display test failures from the last test run in an interactive viewer",
+		Long:  "Display test failures from the last test run (or an older one, see ptp history) in an interactive viewer",
 		RunE:  c.Faills.Execute,
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			cfg.Flags = flags.ToConfigFlags()
+			return nil
+		},
 	}
+	faillsCmd.Flags().StringVar(&flags.RunID, "run", "", "Open a run from the run history instead of the last one (id or unique id prefix)")
 	rootCmd.AddCommand(faillsCmd)
 
+	// Report command
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Convert stored test results into reports",
+		Long:  "Convert the results of the last run into formats other tools can ingest",
+	}
+	reportJUnitCmd := &cobra.Command{
+		Use:   "junit",
+		Short: "Write the last run as JUnit XML",
+		Long:  "Write the last run as a merged JUnit XML document (one testsuite per test file) for GitLab, Jenkins or GitHub test reporters",
+		RunE:  c.Report.JUnit,
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			cfg.Flags = flags.ToConfigFlags()
+			return nil
+		},
+	}
+	reportJUnitCmd.Flags().StringVarP(&flags.ReportOutput, "output", "o", "", "Write the report to this file instead of stdout")
+	reportJUnitCmd.Flags().StringVar(&flags.RunID, "run", "", "Convert a run from the run history instead of the last one")
+	reportCmd.AddCommand(reportJUnitCmd)
+	reportHTMLCmd := &cobra.Command{
+		Use:   "html",
+		Short: "Write the last run as a self-contained HTML page",
+		Long:  "Write the last run as a single HTML file without external assets (failures with stack traces, failure tree, slowest files and worker timeline), e.g. as a CI artifact",
+		RunE:  c.Report.HTML,
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			cfg.Flags = flags.ToConfigFlags()
+			return nil
+		},
+	}
+	reportHTMLCmd.Flags().StringVarP(&flags.ReportOutput, "output", "o", "", "Write the report to this file instead of stdout")
+	reportHTMLCmd.Flags().StringVar(&flags.RunID, "run", "", "Convert a run from the run history instead of the last one")
+	reportCmd.AddCommand(reportHTMLCmd)
+	reportTraceCmd := &cobra.Command{
+		Use:   "trace",
+		Short: "Write the worker timeline of the last run as a Chrome trace",
+		Long:  "Write when each worker of the last run ran which test file in the Chrome trace event format, to open in chrome://tracing or ui.perfetto.dev",
+		RunE:  c.Report.Trace,
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			cfg.Flags = flags.ToConfigFlags()
+			return nil
+		},
+	}
+	reportTraceCmd.Flags().StringVarP(&flags.ReportOutput, "output", "o", "", "Write the trace to this file instead of stdout")
+	reportTraceCmd.Flags().StringVar(&flags.RunID, "run", "", "Convert a run from the run history instead of the last one")
+	reportCmd.AddCommand(reportTraceCmd)
+	reportMergeCmd := &cobra.Command{
+		Use:   "merge <results.json>...",
+		Short: "Merge the results of CI shards into one run",
+		Long:  "Merge the results JSON files of the machines of a sharded run (--shard) into one result with the combined timings, written to the configured output (storage/test-results.json) or --output",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  c.Report.Merge,
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			cfg.Flags = flags.ToConfigFlags()
+			return nil
+		},
+	}
+	reportMergeCmd.Flags().StringVarP(&flags.ReportOutput, "output", "o", "", "Write the merged results to this file instead of the configured output")
+	reportCmd.AddCommand(reportMergeCmd)
+	reportGitHubCmd := &cobra.Command{
+		Use:   "github",
+		Short: "Annotate the failures of the last run in GitHub Actions",
+		Long:  "Print a workflow command per failure of the last run, so GitHub Actions annotates the failing lines, and append a Markdown job summary to $GITHUB_STEP_SUMMARY",
+		RunE:  c.Report.GitHub,
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			cfg.Flags = flags.ToConfigFlags()
+			return nil
+		},
+	}
+	reportGitHubCmd.Flags().StringVar(&flags.RunID, "run", "", "Report a run from the run history instead of the last one")
+	reportCmd.AddCommand(reportGitHubCmd)
+	reportGitLabCmd := &cobra.Command{
+		Use:   "gitlab",
+		Short: "Write the failures of the last run as a GitLab Code Quality report",
+		Long:  "Write the failures of the last run as a GitLab Code Quality report (JSON), for the artifacts:reports:codequality of a job",
+		RunE:  c.Report.GitLab,
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			cfg.Flags = flags.ToConfigFlags()
+			return nil
+		},
+	}
+	reportGitLabCmd.Flags().StringVarP(&flags.ReportOutput, "output", "o", "", "Write the report to this file instead of stdout")
+	reportGitLabCmd.Flags().StringVar(&flags.RunID, "run", "", "Report a run from the run history instead of the last one")
+	reportCmd.AddCommand(reportGitLabCmd)
+	rootCmd.AddCommand(reportCmd)
+
+	// Flaky command
+	flakyCmd := &cobra.Command{
+		Use:   "flaky",
+		Short: "List the flakiest test cases",
+		Long:  "List test cases that failed and then passed on a retry (see run --retries), most frequent first",
+		RunE:  c.Flaky.Execute,
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			cfg.Flags = flags.ToConfigFlags()
+			return nil
+		},
+	}
+	flakyCmd.Flags().IntVarP(&flags.Limit, "limit", "n", 20, "Number of test cases to show (0 = all)")
+	rootCmd.AddCommand(flakyCmd)
+
+	// History command
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "List the runs kept in the run history",
+		Long:  "List the last runs (see history_size) with their git revision, arguments and results; open one with ptp faills --run <id>",
+		RunE:  c.History.Execute,
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			cfg.Flags = flags.ToConfigFlags()
+			return nil
+		},
+	}
+	rootCmd.AddCommand(historyCmd)
+
+	// Diff command
+	diffCmd := &cobra.Command{
+		Use:   "diff [runA] [runB]",
+		Short: "Compare two runs from the run history",
+		Long:  "Show newly failing, fixed, still failing and slower tests between two runs (default: the previous run against the latest; with one run id, that run against the latest)",
+		Args:  cobra.MaximumNArgs(2),
+		RunE:  c.Diff.Execute,
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			cfg.Flags = flags.ToConfigFlags()
+			return nil
+		},
+	}
+	diffCmd.Flags().StringVar(&flags.Format, "format", "terminal", "Output format: terminal, json or markdown")
+	diffCmd.Flags().StringVarP(&flags.ReportOutput, "output", "o", "", "Write the json or markdown diff to this file instead of stdout")
+	diffCmd.Flags().Float64Var(&flags.SlowerThreshold, "threshold", report.DefaultSlowerThreshold, "Report files that got at least this many percent slower")
+	rootCmd.AddCommand(diffCmd)
+
 	// Upgrade command
 	upgradeCmd := &cobra.Command{
 		Use:   "upgrade",
@@ -128,3 +422,25 @@ func (c *Commands) Register(rootCmd *cobra.Command, flags *cli.Flags, cfg *confi
 	}
 	rootCmd.AddCommand(upgradeCmd)
 }
+
+// runArgs rejects positional arguments of run. Because --changed has an optional value,
+// "--changed main" leaves main as an argument; point to --changed=main instead of silently
+// diffing against the upstream branch.
+func runArgs(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	if changed := cmd.Flags().Lookup("changed"); changed.Changed && changed.Value.String() == changed.NoOptDefVal {
+		return fmt.Errorf("unexpected argument %q: pass the git ref as --changed=%s", args[0], args[0])
+	}
+	return cobra.NoArgs(cmd, args)
+}
+
+// applyMigrationFlags lets an explicit --fresh override "migration: skip" of the
+// configuration file, whose default would otherwise keep --skip-migrate set.
+// Passing both flags is rejected by cobra (MarkFlagsMutuallyExclusive).
+func applyMigrationFlags(cmd *cobra.Command, flags *cli.Flags) {
+	if cmd.Flags().Changed("fresh") && flags.Fresh {
+		flags.SkipMigrate = false
+	}
+}
@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"ptp/internal/config"
+	"ptp/internal/debug"
+	"ptp/internal/domain"
+	"ptp/internal/report"
+	"ptp/internal/storage"
+	"ptp/internal/ui"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// DiffCommand compares two runs from the run history
+type DiffCommand struct {
+	config    *config.Config
+	storage   storage.Storage
+	formatter *ui.Formatter
+}
+
+// NewDiffCommand creates a new DiffCommand
+func NewDiffCommand(cfg *config.Config, st storage.Storage, formatter *ui.Formatter) *DiffCommand {
+	return &DiffCommand{
+		config:    cfg,
+		storage:   st,
+		formatter: formatter,
+	}
+}
+
+// Execute compares [runA] [runB]; without arguments the previous run is compared with the latest,
+// with one argument that run is compared with the latest
+func (dc *DiffCommand) Execute(cmd *cobra.Command, args []string) error {
+	base, head, err := dc.loadRuns(args)
+	if err != nil {
+		return err
+	}
+	debug.Logf("diff: comparing run %s with %s", base.Meta.RunID, head.Meta.RunID)
+
+	var timings map[string]*domain.TestTiming
+	if latest, err := dc.storage.Load(); err == nil && latest.Meta.RunID == head.Meta.RunID {
+		timings = latest.Timings
+	}
+	d := report.CompareRuns(base, head, dc.config.Flags.SlowerThreshold, timings)
+
+	format := dc.config.Flags.Format
+	switch format {
+	case "", "terminal":
+		dc.formatter.PrintRunDiff(d)
+		return nil
+	case "json", "markdown":
+	default:
+		return fmt.Errorf("unknown diff format %q (expected terminal, json or markdown)", format)
+	}
+
+	output := dc.config.Flags.ReportOutput
+	if output == "" {
+		return report.WriteDiff(os.Stdout, d, format)
+	}
+	debug.Logf("diff: writing %s diff to %s", format, output)
+	if err := report.WriteDiffFile(output, d, format); err != nil {
+		return err
+	}
+	color.Green("Diff written to %s", output)
+	return nil
+}
+
+// loadRuns resolves the base and head runs from the command arguments
+func (dc *DiffCommand) loadRuns(args []string) (*domain.TestResultsOutput, *domain.TestResultsOutput, error) {
+	ids := args
+	if len(ids) < 2 {
+		runs, err := dc.storage.ListRuns()
+		if err != nil {
+			return nil, nil, err
+		}
+		switch {
+		case len(ids) == 0 && len(runs) >= 2:
+			ids = []string{runs[1].RunID, runs[0].RunID}
+		case len(ids) == 1 && len(runs) >= 1:
+			ids = []string{ids[0], runs[0].RunID}
+		default:
+			return nil, nil, fmt.Errorf("not enough runs in the run history to compare (found %d, see ptp history)", len(runs))
+		}
+	}
+
+	base, err := dc.storage.LoadRun(ids[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	head, err := dc.storage.LoadRun(ids[1])
+	if err != nil {
+		return nil, nil, err
+	}
+	return base, head, nil
+}
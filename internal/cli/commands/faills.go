@@ -28,7 +28,7 @@ func NewFaillsCommand(cfg *config.Config, st storage.Storage, viewer *ui.ErrorVi
 // Execute runs the command
 func (fc *FaillsCommand) Execute(cmd *cobra.Command, args []string) error {
 	debug.Log("faills: loading results from storage")
-	results, err := fc.storage.Load()
+	results, err := loadResults(fc.storage, fc.config.Flags.RunID)
 	if err != nil {
 		debug.Logf("faills: failed to load results: %v", err)
 		return err
@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"ptp/internal/config"
+	"ptp/internal/debug"
+	"ptp/internal/storage"
+	"ptp/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// FlakyCommand handles the flaky command
+type FlakyCommand struct {
+	config    *config.Config
+	storage   storage.Storage
+	formatter *ui.Formatter
+}
+
+// NewFlakyCommand creates a new FlakyCommand
+func NewFlakyCommand(cfg *config.Config, st storage.Storage, formatter *ui.Formatter) *FlakyCommand {
+	return &FlakyCommand{
+		config:    cfg,
+		storage:   st,
+		formatter: formatter,
+	}
+}
+
+// Execute lists the test cases that were flaky most often across runs
+func (fc *FlakyCommand) Execute(cmd *cobra.Command, args []string) error {
+	results, err := fc.storage.Load()
+	if err != nil {
+		debug.Logf("flaky: failed to load results: %v", err)
+		return err
+	}
+	debug.Logf("flaky: %d test cases in flaky history", len(results.FlakyHistory))
+	fc.formatter.PrintFlakyHistory(results.FlakyHistory, fc.config.Flags.Limit)
+	return nil
+}
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"ptp/internal/config"
+	"ptp/internal/debug"
+	"ptp/internal/domain"
+	"ptp/internal/storage"
+	"ptp/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// HistoryCommand handles the history command
+type HistoryCommand struct {
+	config    *config.Config
+	storage   storage.Storage
+	formatter *ui.Formatter
+}
+
+// NewHistoryCommand creates a new HistoryCommand
+func NewHistoryCommand(cfg *config.Config, st storage.Storage, formatter *ui.Formatter) *HistoryCommand {
+	return &HistoryCommand{
+		config:    cfg,
+		storage:   st,
+		formatter: formatter,
+	}
+}
+
+// Execute lists the retained runs, newest first
+func (hc *HistoryCommand) Execute(cmd *cobra.Command, args []string) error {
+	runs, err := hc.storage.ListRuns()
+	if err != nil {
+		debug.Logf("history: failed to list runs: %v", err)
+		return err
+	}
+	debug.Logf("history: %d runs in %s", len(runs), hc.config.GetHistoryDir())
+	hc.formatter.PrintRunHistory(runs)
+	return nil
+}
+
+// loadResults loads the run with the given id from the run history, or the last run when id is empty
+func loadResults(st storage.Storage, id string) (*domain.TestResultsOutput, error) {
+	if id == "" {
+		return st.Load()
+	}
+	debug.Logf("loading run %s from the run history", id)
+	return st.LoadRun(id)
+}
@@ -1,6 +1,9 @@
 package commands
 
 import (
+	"fmt"
+	"strings"
+
 	"ptp/internal/config"
 	"ptp/internal/debug"
 	"ptp/internal/discovery"
@@ -16,6 +19,7 @@ type ListCommand struct {
 	config    *config.Config
 	scanner   *discovery.Scanner
 	filter    *discovery.Filter
+	parser    *discovery.Parser
 	formatter *ui.Formatter
 	storage   storage.Storage
 }
@@ -25,6 +29,7 @@ func NewListCommand(
 	cfg *config.Config,
 	scanner *discovery.Scanner,
 	filter *discovery.Filter,
+	parser *discovery.Parser,
 	formatter *ui.Formatter,
 	st storage.Storage,
 ) *ListCommand {
@@ -32,13 +37,61 @@ func NewListCommand(
 		config:    cfg,
 		scanner:   scanner,
 		filter:    filter,
+		parser:    parser,
 		formatter: formatter,
 		storage:   st,
 	}
 }
 
+// configureScanner switches discovery to the test suites of the PHPUnit configuration when
+// phpunit_config or --testsuite asks for it. Pest projects use them whenever phpunit.xml exists,
+// as Pest itself does. The <groups> of the configuration become the default groups either way.
+func configureScanner(cfg *config.Config, scanner *discovery.Scanner) error {
+	explicit := cfg.PHPUnitConfig != "" || cfg.Flags.TestSuite != ""
+	path := cfg.GetPHPUnitConfigPath()
+	if path == "" {
+		path = discovery.FindPHPUnitConfig(cfg.ProjectPath)
+	}
+
+	var phpunitConfig *discovery.PHPUnitConfig
+	if path != "" {
+		loaded, err := discovery.LoadPHPUnitConfig(path)
+		switch {
+		case err == nil:
+			phpunitConfig = loaded
+			cfg.PHPUnitGroups, cfg.PHPUnitExcludeGroups = loaded.Groups.Include, loaded.Groups.Exclude
+		case explicit:
+			return err
+		default:
+			debug.Logf("discovery: ignoring %s: %v", path, err)
+		}
+	}
+
+	if !explicit && (cfg.GetFramework() != config.FrameworkPest || phpunitConfig == nil) {
+		scanner.UsePHPUnitConfig(nil, nil)
+		return nil
+	}
+	if phpunitConfig == nil {
+		return fmt.Errorf("no %s found in %s", strings.Join(discovery.PHPUnitConfigNames, " or "), cfg.ProjectPath)
+	}
+	suites := cfg.GetTestSuites()
+	debug.Logf("discovery: using test suites %v of %s", suites, path)
+	scanner.UsePHPUnitConfig(phpunitConfig, suites)
+	return nil
+}
+
+// filterByGroups keeps the test files with test cases in the groups selected by --group and --exclude-group
+// (or the <groups> of the PHPUnit configuration)
+func filterByGroups(cfg *config.Config, parser *discovery.Parser, tests []string) []string {
+	include, exclude := cfg.GetGroups()
+	return parser.FilterByGroups(tests, include, exclude)
+}
+
 // Execute runs the command
 func (lc *ListCommand) Execute(cmd *cobra.Command, args []string) error {
+	if err := configureScanner(lc.config, lc.scanner); err != nil {
+		return err
+	}
 	testPath := lc.config.GetTestPath()
 	debug.Logf("list: scanning for tests in %q", testPath)
 	tests, err := lc.scanner.Scan(testPath)
@@ -48,6 +101,7 @@ func (lc *ListCommand) Execute(cmd *cobra.Command, args []string) error {
 	}
 
 	tests = lc.filter.FilterByName(tests, lc.config.Flags.NameFilter)
+	tests = filterByGroups(lc.config, lc.parser, tests)
 	debug.Logf("list: found %d tests after filtering", len(tests))
 
 	if len(tests) == 0 {
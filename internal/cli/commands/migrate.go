@@ -26,7 +26,7 @@ func NewMigrateCommand(cfg *config.Config, migrator migration.Migrator) *Migrate
 func (mc *MigrateCommand) Execute(cmd *cobra.Command, args []string) error {
 	workerCount := mc.config.Processors
 	debug.Logf("migrate: starting (workers=%d, fresh=%v)", workerCount, mc.config.Flags.Fresh)
-	if err := mc.migrator.Run(workerCount, mc.config.Flags.Fresh); err != nil {
+	if err := mc.migrator.Run(cmd.Context(), workerCount, mc.config.Flags.Fresh); err != nil {
 		debug.Logf("migrate: failed: %v", err)
 		return err
 	}
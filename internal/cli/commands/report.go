@@ -0,0 +1,175 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"ptp/internal/config"
+	"ptp/internal/debug"
+	"ptp/internal/domain"
+	"ptp/internal/report"
+	"ptp/internal/storage"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// ReportCommand converts stored results into reports for other tools
+type ReportCommand struct {
+	config  *config.Config
+	storage storage.Storage
+}
+
+// NewReportCommand creates a new ReportCommand
+func NewReportCommand(cfg *config.Config, st storage.Storage) *ReportCommand {
+	return &ReportCommand{
+		config:  cfg,
+		storage: st,
+	}
+}
+
+// JUnit writes the last stored run (or the --run one) as JUnit XML to --output, or stdout when no output is given
+func (rc *ReportCommand) JUnit(cmd *cobra.Command, args []string) error {
+	results, err := loadResults(rc.storage, rc.config.Flags.RunID)
+	if err != nil {
+		debug.Logf("report: failed to load results: %v", err)
+		return err
+	}
+
+	output := rc.config.Flags.ReportOutput
+	if output == "" {
+		return report.WriteJUnit(os.Stdout, results)
+	}
+	debug.Logf("report: writing junit report to %s", output)
+	if err := report.WriteJUnitFile(output, results); err != nil {
+		return err
+	}
+	color.Green("JUnit report written to %s", output)
+	return nil
+}
+
+// HTML writes the last stored run (or the --run one) as a self-contained HTML page to --output, or
+// stdout when no output is given
+func (rc *ReportCommand) HTML(cmd *cobra.Command, args []string) error {
+	results, err := loadResults(rc.storage, rc.config.Flags.RunID)
+	if err != nil {
+		debug.Logf("report: failed to load results: %v", err)
+		return err
+	}
+
+	output := rc.config.Flags.ReportOutput
+	if output == "" {
+		return report.WriteHTML(os.Stdout, results)
+	}
+	debug.Logf("report: writing html report to %s", output)
+	if err := report.WriteHTMLFile(output, results); err != nil {
+		return err
+	}
+	color.Green("HTML report written to %s", output)
+	return nil
+}
+
+// Trace writes the worker timeline of the last stored run (or the --run one) as a Chrome trace to
+// --output, or stdout when no output is given
+func (rc *ReportCommand) Trace(cmd *cobra.Command, args []string) error {
+	results, err := loadResults(rc.storage, rc.config.Flags.RunID)
+	if err != nil {
+		debug.Logf("report: failed to load results: %v", err)
+		return err
+	}
+
+	output := rc.config.Flags.ReportOutput
+	if output == "" {
+		return report.WriteTrace(os.Stdout, results)
+	}
+	debug.Logf("report: writing trace to %s", output)
+	if err := report.WriteTraceFile(output, results); err != nil {
+		return err
+	}
+	color.Green("Trace written to %s", output)
+	return nil
+}
+
+// Merge combines the results JSON files of the shards of a CI run (see --shard) into one result,
+// written to --output or the configured output so the next pipeline schedules with all timings
+func (rc *ReportCommand) Merge(cmd *cobra.Command, args []string) error {
+	outputs := make([]*domain.TestResultsOutput, 0, len(args))
+	commits := make(map[string]bool)
+	for _, path := range args {
+		out, err := report.ReadResults(path)
+		if err != nil {
+			return err
+		}
+		debug.Logf("report: merging %s (shard %q, %d files)", path, out.Meta.Shard, len(out.Files))
+		if out.Meta.GitCommit != "" {
+			commits[out.Meta.GitCommit] = true
+		}
+		outputs = append(outputs, out)
+	}
+	if missing := report.MissingShards(outputs...); len(missing) > 0 {
+		color.Yellow("Missing results of shard(s) %s", strings.Join(missing, ", "))
+	}
+	if len(commits) > 1 {
+		color.Yellow("The results come from %d different git commits", len(commits))
+	}
+
+	merged := report.MergeResults(outputs...)
+	output := rc.config.Flags.ReportOutput
+	if output == "" {
+		if err := rc.storage.SaveOutput(merged); err != nil {
+			return fmt.Errorf("failed to save merged results: %w", err)
+		}
+		output = rc.config.GetOutputPath()
+	} else if err := report.WriteResults(output, merged); err != nil {
+		return err
+	}
+	m := merged.Meta
+	color.Green("Merged %d result file(s) into %s: %d test files, %d failed, %d failed test cases",
+		len(outputs), output, m.TotalTestFiles, m.FailedTestFiles, m.FailedTestCases)
+	return nil
+}
+
+// GitHub prints the failures of the last stored run (or the --run one) as GitHub Actions
+// annotations and appends the job summary
+func (rc *ReportCommand) GitHub(cmd *cobra.Command, args []string) error {
+	results, err := loadResults(rc.storage, rc.config.Flags.RunID)
+	if err != nil {
+		debug.Logf("report: failed to load results: %v", err)
+		return err
+	}
+	return writeGitHubReport(rc.config, results)
+}
+
+// GitLab writes the failures of the last stored run (or the --run one) as a GitLab Code Quality
+// report to --output, or stdout when no output is given
+func (rc *ReportCommand) GitLab(cmd *cobra.Command, args []string) error {
+	results, err := loadResults(rc.storage, rc.config.Flags.RunID)
+	if err != nil {
+		debug.Logf("report: failed to load results: %v", err)
+		return err
+	}
+	loc := report.NewLocator(rc.config.ProjectPath)
+	output := rc.config.Flags.ReportOutput
+	if output == "" {
+		return report.WriteCodeQuality(os.Stdout, results, loc)
+	}
+	debug.Logf("report: writing code quality report to %s", output)
+	if err := report.WriteCodeQualityFile(output, results, loc); err != nil {
+		return err
+	}
+	color.Green("Code Quality report written to %s", output)
+	return nil
+}
+
+// writeGitHubReport prints the annotations of a run and appends its job summary
+func writeGitHubReport(cfg *config.Config, results *domain.TestResultsOutput) error {
+	loc := report.NewLocator(cfg.ProjectPath)
+	if err := report.WriteGitHubAnnotations(os.Stdout, results, loc); err != nil {
+		return err
+	}
+	if err := report.AppendGitHubSummary(results, loc); err != nil {
+		return fmt.Errorf("failed to write the job summary: %w", err)
+	}
+	return nil
+}
@@ -1,21 +1,28 @@
 package commands
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 	"time"
 
 	"ptp/internal/config"
+	"ptp/internal/coverage"
 	"ptp/internal/debug"
 	"ptp/internal/discovery"
 	"ptp/internal/domain"
+	"ptp/internal/events"
 	"ptp/internal/execution"
 	"ptp/internal/migration"
 	"ptp/internal/parser"
+	"ptp/internal/remote"
+	"ptp/internal/report"
 	"ptp/internal/storage"
 	"ptp/internal/ui"
+	"ptp/internal/vcs"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -37,14 +44,15 @@ func normalizedPathForKey(projectPath, path string) string {
 // RunCommand handles the run command
 type RunCommand struct {
 	config    *config.Config
-	scanner   *discovery.Scanner
-	filter    *discovery.Filter
-	executor  *execution.WorkerPool
-	parser    *parser.PHPUnitParser
-	storage   storage.Storage
-	formatter *ui.Formatter
-	migrator  migration.Migrator
-	viewer    *ui.ErrorViewer
+	scanner    *discovery.Scanner
+	filter     *discovery.Filter
+	caseParser *discovery.Parser
+	executor   *execution.WorkerPool
+	parser     parser.Parser
+	storage    storage.Storage
+	formatter  *ui.Formatter
+	migrator   migration.Migrator
+	viewer     *ui.ErrorViewer
 }
 
 // NewRunCommand creates a new RunCommand
@@ -52,63 +60,170 @@ func NewRunCommand(
 	cfg *config.Config,
 	scanner *discovery.Scanner,
 	filter *discovery.Filter,
+	caseParser *discovery.Parser,
 	executor *execution.WorkerPool,
-	parser *parser.PHPUnitParser,
+	parser parser.Parser,
 	st storage.Storage,
 	formatter *ui.Formatter,
 	migrator migration.Migrator,
 	viewer *ui.ErrorViewer,
 ) *RunCommand {
 	return &RunCommand{
-		config:    cfg,
-		scanner:   scanner,
-		filter:    filter,
-		executor:  executor,
-		parser:    parser,
-		storage:   st,
-		formatter: formatter,
-		migrator:  migrator,
-		viewer:    viewer,
+		config:     cfg,
+		scanner:    scanner,
+		filter:     filter,
+		caseParser: caseParser,
+		executor:   executor,
+		parser:     parser,
+		storage:    st,
+		formatter:  formatter,
+		migrator:   migrator,
+		viewer:     viewer,
 	}
 }
 
-// failedPathsFromOutput returns a set of normalized paths that had failures.
+// failedPathsFromOutput returns a set of normalized paths that had failures or were interrupted.
 func failedPathsFromOutput(projectPath string, out *domain.TestResultsOutput) map[string]struct{} {
 	set := make(map[string]struct{})
 	for _, d := range out.Details {
+		if d.Flaky {
+			continue
+		}
 		key := normalizedPathForKey(projectPath, d.FilePath)
 		set[key] = struct{}{}
 	}
+	for _, f := range out.Files {
+		if f.Interrupted {
+			set[normalizedPathForKey(projectPath, f.Path)] = struct{}{}
+		}
+	}
 	return set
 }
 
-// failedPathsFromFailures returns a set of normalized paths from a failure list.
-func failedPathsFromFailures(projectPath string, failures []domain.TestFailure) map[string]struct{} {
-	set := make(map[string]struct{})
-	for _, f := range failures {
-		key := normalizedPathForKey(projectPath, f.FilePath)
-		set[key] = struct{}{}
+// prepareScheduler configures the worker pool with the selected scheduler, fed by historical
+// timings, and returns the estimator it uses so the post-run load report can compare against it.
+func (rc *RunCommand) prepareScheduler() (*execution.Estimator, error) {
+	estimator := execution.NewEstimator(rc.storage.LoadTimings(), rc.caseParser)
+	scheduler, err := execution.NewScheduler(rc.config.Scheduler, estimator)
+	if err != nil {
+		return nil, err
 	}
-	return set
+	rc.executor.SetScheduler(scheduler)
+	if rc.config.Split && rc.config.GetFramework() == config.FrameworkPest {
+		color.Yellow("--split is not supported with Pest; test files run whole")
+		rc.executor.SetSplitter(nil)
+	} else if rc.config.Split {
+		rc.executor.SetSplitter(execution.NewSplitter(estimator, rc.caseParser, rc.config.SplitThreshold))
+	} else {
+		rc.executor.SetSplitter(nil)
+	}
+	return estimator, nil
 }
 
-// sortTestsByTimings sorts tests with the slowest (highest avg) first so they get
-// dispatched early and don't become a tail bottleneck in the worker pool.
-func sortTestsByTimings(tests []string, timings map[string]*domain.TestTiming) {
-	if len(timings) == 0 {
-		return
+// errInterrupted is returned when the run was cancelled with Ctrl+C (or SIGTERM)
+var errInterrupted = errors.New("interrupted")
+
+// collectFailures parses the failures of unsuccessful or retried results; failures that passed on
+// a retry are kept, flagged as flaky. Interrupted files are skipped: they didn't fail, they are
+// stored as interrupted instead.
+func (rc *RunCommand) collectFailures(results []domain.TestResult) []domain.TestFailure {
+	var failures []domain.TestFailure
+	for _, result := range results {
+		if (!result.Success || len(result.Retries) > 0) && !result.Interrupted {
+			failures = append(failures, execution.MarkFlaky(result, rc.parser.ParseFailure(result))...)
+		}
 	}
-	sort.Slice(tests, func(i, j int) bool {
-		ai, bi := timings[tests[i]], timings[tests[j]]
-		var avgI, avgJ float64
-		if ai != nil {
-			avgI = ai.Avg
+	return failures
+}
+
+// retries returns how many times failed test cases are retried (--rerun-failures means one retry)
+func (rc *RunCommand) retries() int {
+	if rc.config.Retries == 0 && rc.config.Flags.RerunFailures {
+		return 1
+	}
+	return rc.config.Retries
+}
+
+// hasFailedResults reports whether any result failed (interrupted files don't count)
+func hasFailedResults(results []domain.TestResult) bool {
+	for _, r := range results {
+		if !r.Success && !r.Interrupted {
+			return true
 		}
-		if bi != nil {
-			avgJ = bi.Avg
+	}
+	return false
+}
+
+// runInfo describes the run for the run history: the command-line arguments, the shard and the git revision
+func (rc *RunCommand) runInfo(args []string) domain.RunInfo {
+	info := domain.RunInfo{Shard: rc.config.Flags.Shard, Args: args}
+	git, err := vcs.Detect(rc.config.ProjectPath)
+	if err != nil {
+		debug.Logf("run: no git revision recorded: %v", err)
+		return info
+	}
+	info.GitCommit = git.Commit
+	info.GitBranch = git.Branch
+	return info
+}
+
+// connectRemotes adds the slots of the --remote agents to the worker pool. Agents that cannot be
+// reached or serve another revision are skipped with a warning.
+func (rc *RunCommand) connectRemotes(ctx context.Context) error {
+	addrs := rc.config.GetRemotes()
+	if len(addrs) == 0 {
+		return nil
+	}
+	revision, err := remote.Revision(rc.config)
+	if err != nil {
+		return fmt.Errorf("--remote needs a git checkout to verify the agents' revision: %w", err)
+	}
+	var slots []execution.RemoteSlot
+	agents := 0
+	for _, addr := range addrs {
+		agent, err := remote.Connect(ctx, rc.config, addr, revision)
+		if err != nil {
+			color.Yellow("Skipping remote worker: %v", err)
+			continue
 		}
-		return avgI > avgJ
-	})
+		agents++
+		slots = append(slots, agent.Slots()...)
+	}
+	rc.executor.SetRemoteSlots(slots)
+	if agents > 0 && !debug.IsEnabled() {
+		color.Cyan("Running on %d local worker(s) and %d remote slot(s) of %d agent(s)", rc.config.Processors, len(slots), agents)
+	}
+	return nil
+}
+
+// formatNDJSON is the --format of run that streams events instead of the terminal output
+const formatNDJSON = "ndjson"
+
+// streamEvents returns an event writer on stdout and sends all other output, including the
+// messages printed with fmt and color, to stderr until restore is called
+func streamEvents(resultParser parser.Parser) (stream *events.Writer, restore func()) {
+	stdout, colorOutput := os.Stdout, color.Output
+	stream = events.NewWriter(stdout, resultParser)
+	os.Stdout, color.Output = os.Stderr, color.Error
+	return stream, func() {
+		os.Stdout, color.Output = stdout, colorOutput
+	}
+}
+
+// finishStream writes the run_finished event with the saved results
+func (rc *RunCommand) finishStream(stream *events.Writer, interrupted bool) error {
+	saved, err := rc.storage.Load()
+	if err != nil {
+		return err
+	}
+	status := events.StatusPassed
+	if interrupted {
+		status = events.StatusInterrupted
+	} else if saved.Meta.FailedTestCases > 0 || saved.Meta.FailedTestFiles > 0 {
+		status = events.StatusFailed
+	}
+	stream.RunFinished(status, rc.config.GetOutputPath(), saved.Meta)
+	return nil
 }
 
 // filterTestsToFailed returns only tests whose normalized path is in the failed set.
@@ -123,37 +238,77 @@ func filterTestsToFailed(projectPath string, tests []string, failedSet map[strin
 	return out
 }
 
+// writeReports writes the reports requested by flags from the saved results
+func (rc *RunCommand) writeReports() error {
+	reports := rc.config.GetReports()
+	if rc.config.Flags.ReportJUnit == "" && rc.config.Flags.Trace == "" && len(reports) == 0 {
+		return nil
+	}
+	saved, err := rc.storage.Load()
+	if err != nil {
+		return err
+	}
+	if path := rc.config.Flags.ReportJUnit; path != "" {
+		debug.Logf("run: writing junit report to %s", path)
+		if err := report.WriteJUnitFile(path, saved); err != nil {
+			return fmt.Errorf("failed to write junit report: %w", err)
+		}
+	}
+	if path := rc.config.Flags.Trace; path != "" && len(saved.Timeline) > 0 {
+		debug.Logf("run: writing trace to %s", path)
+		if err := report.WriteTraceFile(path, saved); err != nil {
+			return fmt.Errorf("failed to write trace: %w", err)
+		}
+	}
+	for _, format := range reports {
+		switch format {
+		case report.FormatGitHub:
+			if err := writeGitHubReport(rc.config, saved); err != nil {
+				return err
+			}
+		case report.FormatGitLab:
+			path := filepath.Join(rc.config.ProjectPath, report.CodeQualityFile)
+			debug.Logf("run: writing code quality report to %s", path)
+			if err := report.WriteCodeQualityFile(path, saved, report.NewLocator(rc.config.ProjectPath)); err != nil {
+				return fmt.Errorf("failed to write code quality report: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
 // RunOnlyFailedAndSave runs only the test files that failed in the last run, saves results, and returns the new output.
 // Used by the faills TUI "R" key. Returns (nil, nil) if no previous run or no failures to run.
-func (rc *RunCommand) RunOnlyFailedAndSave() (*domain.TestResultsOutput, error) {
+func (rc *RunCommand) RunOnlyFailedAndSave(ctx context.Context) (*domain.TestResultsOutput, error) {
 	last, err := rc.storage.Load()
 	if err != nil || last == nil || len(last.Details) == 0 {
 		return nil, nil
 	}
 	failedSet := failedPathsFromOutput(rc.config.ProjectPath, last)
+	if err := configureScanner(rc.config, rc.scanner); err != nil {
+		return nil, err
+	}
 	testPath := rc.config.GetTestPath()
 	discovered, err := rc.scanner.Scan(testPath)
 	if err != nil {
 		return nil, err
 	}
 	discovered = rc.filter.FilterByName(discovered, rc.config.Flags.NameFilter)
+	discovered = filterByGroups(rc.config, rc.caseParser, discovered)
 	tests := filterTestsToFailed(rc.config.ProjectPath, discovered, failedSet)
 	if len(tests) == 0 {
 		return nil, nil
 	}
-	sortTestsByTimings(tests, rc.storage.LoadTimings())
+	if _, err := rc.prepareScheduler(); err != nil {
+		return nil, err
+	}
 	rc.executor.SetProgress(nil)
-	results, duration, err := rc.executor.ExecuteWithOptions(tests, rc.config.Flags.FailFast)
+	results, duration, err := rc.executor.ExecuteWithOptions(ctx, tests, rc.config.Flags.FailFast)
 	if err != nil {
 		return nil, err
 	}
-	var failures []domain.TestFailure
-	for _, result := range results {
-		if !result.Success {
-			failures = append(failures, rc.parser.ParseFailure(result)...)
-		}
-	}
-	if err := rc.storage.Save(results, failures, duration, rc.config.Processors); err != nil {
+	failures := rc.collectFailures(results)
+	if err := rc.storage.Save(results, failures, duration, rc.config.Processors, rc.runInfo([]string{"run", "--failed"})); err != nil {
 		return nil, err
 	}
 	return rc.storage.Load()
@@ -161,14 +316,61 @@ func (rc *RunCommand) RunOnlyFailedAndSave() (*domain.TestResultsOutput, error)
 
 // Execute runs the command
 func (rc *RunCommand) Execute(cmd *cobra.Command, args []string) error {
-	debug.Logf("run: starting (processors=%d, testPath=%q, failFast=%v, onlyFailed=%v, rerunFailures=%v, skipMigrate=%v, fresh=%v)",
+	if rc.config.Flags.Format != formatNDJSON {
+		return ignoreNothingToRun(rc.execute(cmd.Context(), nil))
+	}
+
+	// With --format=ndjson the events are the only output on stdout, and every exit ends the stream
+	stream, restore := streamEvents(rc.parser)
+	defer restore()
+	err := rc.execute(cmd.Context(), stream)
+	switch {
+	case err == nil:
+	case errors.Is(err, errNothingToRun):
+		stream.RunEnded(events.StatusNoTests, nil)
+	case errors.Is(err, errInterrupted):
+		stream.RunEnded(events.StatusInterrupted, nil)
+	default:
+		stream.RunEnded(events.StatusError, err)
+	}
+	return ignoreNothingToRun(err)
+}
+
+// errNothingToRun ends a run whose selection matched no test files; it is not an error for the user
+var errNothingToRun = errors.New("nothing to run")
+
+func ignoreNothingToRun(err error) error {
+	if errors.Is(err, errNothingToRun) {
+		return nil
+	}
+	return err
+}
+
+// execute runs the tests; stream is the event writer of --format=ndjson (nil otherwise)
+func (rc *RunCommand) execute(ctx context.Context, stream *events.Writer) error {
+	debug.Logf("run: starting (processors=%d, testPath=%q, failFast=%v, onlyFailed=%v, retries=%d, skipMigrate=%v, fresh=%v, scheduler=%s)",
 		rc.config.Processors, rc.config.GetTestPath(), rc.config.Flags.FailFast, rc.config.Flags.OnlyFailed,
-		rc.config.Flags.RerunFailures, rc.config.Flags.SkipMigrate, rc.config.Flags.Fresh)
+		rc.retries(), rc.config.Flags.SkipMigrate, rc.config.Flags.Fresh, rc.config.Scheduler)
+
+	// Validate the scheduler and the PHPUnit config before spending time on migrations
+	estimator, err := rc.prepareScheduler()
+	if err != nil {
+		return err
+	}
+	if err := configureScanner(rc.config, rc.scanner); err != nil {
+		return err
+	}
+	if err := rc.connectRemotes(ctx); err != nil {
+		return err
+	}
 
 	if !rc.config.Flags.SkipMigrate {
 		debug.Log("run: starting pre-test migrations")
-		if err := rc.migrator.Run(rc.config.Processors, rc.config.Flags.Fresh); err != nil {
+		if err := rc.migrator.Run(ctx, rc.config.Processors, rc.config.Flags.Fresh); err != nil {
 			debug.Logf("run: migration failed: %v", err)
+			if ctx.Err() != nil {
+				return errInterrupted
+			}
 			return fmt.Errorf("migration failed: %w", err)
 		}
 		debug.Log("run: migrations completed")
@@ -181,7 +383,7 @@ func (rc *RunCommand) Execute(cmd *cobra.Command, args []string) error {
 	testPath := rc.config.GetTestPath()
 	failFast := rc.config.Flags.FailFast
 	onlyFailed := rc.config.Flags.OnlyFailed
-	rerunFailures := rc.config.Flags.RerunFailures
+	retries := rc.retries()
 
 	var tests []string
 	if onlyFailed {
@@ -195,7 +397,7 @@ func (rc *RunCommand) Execute(cmd *cobra.Command, args []string) error {
 			failedSet := failedPathsFromOutput(projectPath, last)
 			if len(failedSet) == 0 {
 				color.Green("No failed tests in last run. Nothing to run.")
-				return nil
+				return errNothingToRun
 			}
 			discovered, err := rc.scanner.Scan(testPath)
 			if err != nil {
@@ -203,11 +405,12 @@ func (rc *RunCommand) Execute(cmd *cobra.Command, args []string) error {
 				return err
 			}
 			discovered = rc.filter.FilterByName(discovered, rc.config.Flags.NameFilter)
+			discovered = filterByGroups(rc.config, rc.caseParser, discovered)
 			tests = filterTestsToFailed(projectPath, discovered, failedSet)
 			if len(tests) == 0 {
 				debug.Log("run: no matching test files for previous failures")
 				color.Yellow("No matching test files for last run's failures. Run all tests? Skipping.")
-				return nil
+				return errNothingToRun
 			}
 			debug.Logf("run: filtered to %d previously failed tests", len(tests))
 		}
@@ -220,115 +423,170 @@ func (rc *RunCommand) Execute(cmd *cobra.Command, args []string) error {
 			return err
 		}
 		tests = rc.filter.FilterByName(discovered, rc.config.Flags.NameFilter)
+		tests = filterByGroups(rc.config, rc.caseParser, tests)
 		debug.Logf("run: discovered %d test files", len(tests))
 	}
+	if ref := rc.config.Flags.Changed; ref != "" && len(tests) > 0 {
+		changed, err := vcs.ChangedFiles(projectPath, ref)
+		if err != nil {
+			return fmt.Errorf("--changed: %w", err)
+		}
+		tests = rc.caseParser.AffectedTests(tests, changed)
+		if len(tests) == 0 {
+			color.Green("No tests affected by the %d changed file(s). Nothing to run.", len(changed))
+			return errNothingToRun
+		}
+		if !debug.IsEnabled() {
+			color.Cyan("Running %d test file(s) affected by %d changed file(s)", len(tests), len(changed))
+		}
+	}
+
+	if spec := rc.config.Flags.Shard; spec != "" && len(tests) > 0 {
+		shard, err := execution.ParseShard(spec)
+		if err != nil {
+			return fmt.Errorf("--shard: %w", err)
+		}
+		total := len(tests)
+		tests = shard.Select(tests, estimator)
+		debug.Logf("run: shard %s selected %d of %d test files", shard, len(tests), total)
+		if len(tests) == 0 {
+			// Still save (empty) results, so `ptp report merge` finds every shard's file
+			color.Yellow("Shard %s has no tests to run (%d test files in total)", shard, total)
+			if err := rc.storage.Save(nil, nil, 0, rc.config.Processors, rc.runInfo(os.Args[1:])); err != nil {
+				return err
+			}
+			return errNothingToRun
+		}
+		if !debug.IsEnabled() {
+			color.Cyan("Running shard %s: %d of %d test files", shard, len(tests), total)
+		}
+	}
 
 	if len(tests) == 0 {
 		color.Yellow("No tests to execute")
-		return nil
+		return errNothingToRun
 	}
 
-	timings := rc.storage.LoadTimings()
-	sortTestsByTimings(tests, timings)
-	debug.Logf("run: sorted %d tests by historical duration (slowest first)", len(tests))
+	// With --coverage every PHPUnit invocation writes a fragment, merged into one report after the run
+	var collector *coverage.Collector
+	if format := rc.config.Flags.Coverage; format != "" {
+		var err error
+		collector, err = coverage.NewCollector(rc.config, format, rc.config.Flags.CoverageOutput)
+		if err != nil {
+			return fmt.Errorf("--coverage: %w", err)
+		}
+		defer collector.Close()
+	}
 
-	if !debug.IsEnabled() {
+	var dashboard *ui.Dashboard
+	if stream != nil {
+		testCaseCount, _ := rc.formatter.CountTestCases(tests)
+		stream.RunStarted(len(tests), testCaseCount, rc.executor.WorkerCount(), rc.config.Flags.Shard, os.Args[1:])
+		rc.executor.SetProgress(stream)
+	} else if !debug.IsEnabled() {
 		testCaseCount, _ := rc.formatter.CountTestCases(tests)
-		progressBar := ui.NewProgressBar(len(tests), testCaseCount)
-		rc.executor.SetProgress(progressBar)
+		// Without a terminal (CI logs, pipes) the dashboard falls back to the progress bar
+		if rc.config.Flags.UI == ui.UIDashboard && ui.DashboardSupported() {
+			// The dashboard reads Ctrl+C as a key, so it cancels the run itself
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithCancel(ctx)
+			defer cancel()
+			dashboard = ui.NewDashboard(rc.parser, tests, testCaseCount, rc.executor.WorkerCount(), estimator.Estimate)
+			if err := dashboard.Start(cancel); err != nil {
+				color.Yellow("Showing the progress bar instead of the dashboard: %v", err)
+				dashboard = nil
+			} else {
+				defer dashboard.Stop()
+			}
+		}
+		if dashboard != nil {
+			rc.executor.SetProgress(dashboard)
+		} else {
+			rc.executor.SetProgress(ui.NewProgressBar(len(tests), testCaseCount))
+		}
 	}
 
-	debug.Logf("run: executing %d tests (failFast=%v, workers=%d)", len(tests), failFast, rc.config.Processors)
-	results, duration, err := rc.executor.ExecuteWithOptions(tests, failFast)
+	debug.Logf("run: executing %d tests (failFast=%v, workers=%d)", len(tests), failFast, rc.executor.WorkerCount())
+	results, duration, err := rc.executor.ExecuteWithOptions(ctx, tests, failFast)
 	if err != nil {
 		debug.Logf("run: execution error: %v", err)
 		return err
 	}
-	debug.Logf("run: execution finished in %s (%d results)", duration, len(results))
+	interrupted := ctx.Err() != nil
+	debug.Logf("run: execution finished in %s (%d results, interrupted=%v)", duration, len(results), interrupted)
+	loads := rc.executor.WorkerLoads(estimator)
 
-	var failures []domain.TestFailure
-	for _, result := range results {
-		if !result.Success {
-			failures = append(failures, rc.parser.ParseFailure(result)...)
+	if retries > 0 && !interrupted && hasFailedResults(results) {
+		if dashboard != nil {
+			dashboard.SetStatus(fmt.Sprintf("Retrying failed test cases (up to %d time(s))...", retries))
+		} else if !debug.IsEnabled() {
+			color.Yellow("\nRetrying failed test cases (up to %d time(s))...", retries)
 		}
+		results = rc.executor.Retry(ctx, results, retries)
+		interrupted = ctx.Err() != nil
 	}
 
-	debug.Logf("run: %d failures found across %d results", len(failures), len(results))
+	failures := rc.collectFailures(results)
 
-	if rerunFailures && len(failures) > 0 {
-		failedSet := failedPathsFromFailures(projectPath, failures)
-		rerunTests := filterTestsToFailed(projectPath, tests, failedSet)
-		if len(rerunTests) > 0 {
-			if !debug.IsEnabled() {
-				progressBar2 := ui.NewProgressBar(len(rerunTests), 0)
-				rc.executor.SetProgress(progressBar2)
-			}
-			results2, duration2, err2 := rc.executor.ExecuteWithOptions(rerunTests, failFast)
-			if err2 != nil {
-				return err2
-			}
-			var failures2 []domain.TestFailure
-			for _, result := range results2 {
-				if !result.Success {
-					failures2 = append(failures2, rc.parser.ParseFailure(result)...)
-				}
-			}
-			results = results2
-			failures = failures2
-			duration = duration2
-			if err := rc.storage.Save(results, failures, duration, rc.config.Processors); err != nil {
-				return fmt.Errorf("failed to save test results: %w", err)
-			}
-			if !debug.IsEnabled() {
-				if err := rc.formatter.PrintMetaStats(); err != nil {
-					return err
-				}
-			}
-			if rc.config.Flags.OpenFaills && len(failures) > 0 && rc.viewer != nil && !debug.IsEnabled() {
-				passed, failed := 0, 0
-				for _, r := range results {
-					if r.Success {
-						passed++
-					} else {
-						failed++
-					}
-				}
-				output := &domain.TestResultsOutput{
-					Meta: domain.TestResultsMeta{
-						TotalTestFiles:  len(results),
-						FailedTestFiles: failed,
-						PassedTestFiles: passed,
-						FailedTestCases: len(failures),
-						Duration:        duration.String(),
-						DurationSeconds: duration.Seconds(),
-						Workers:         rc.config.Processors,
-						Timestamp:       time.Now().Format(time.RFC3339),
-					},
-					Details: failures,
-				}
-				if err := rc.viewer.View(output); err != nil {
-					return err
-				}
-			}
-			if len(failures) > 0 {
-				return fmt.Errorf("%d test case(s) failed", len(failures))
-			}
-			return nil
-		}
-	}
+	debug.Logf("run: %d failures (%d flaky) found across %d results", len(failures), domain.CountFlaky(failures), len(results))
 
-	if err := rc.storage.Save(results, failures, duration, rc.config.Processors); err != nil {
+	if err := rc.storage.Save(results, failures, duration, rc.executor.WorkerCount(), rc.runInfo(os.Args[1:])); err != nil {
 		debug.Logf("run: failed to save results: %v", err)
 		return fmt.Errorf("failed to save test results: %w", err)
 	}
 	debug.Log("run: results saved")
-	if !debug.IsEnabled() {
+	realFailures := domain.WithoutFlaky(failures)
+	openViewer := rc.config.Flags.OpenFaills
+	if dashboard != nil {
+		// With failures, the dashboard waits for a key: f opens the faills viewer
+		waitFor := len(realFailures)
+		if interrupted {
+			waitFor = 0
+		}
+		openViewer = dashboard.Close(waitFor) || openViewer
+	}
+	if err := rc.writeReports(); err != nil {
+		return err
+	}
+	// A coverage failure (no driver, phpcov errors) must not hide the results of the tests: it is
+	// reported after them and returned at the end
+	var coverageSummary *domain.CoverageSummary
+	var coverageErr error
+	if collector != nil && !interrupted {
+		summary, err := collector.Merge(ctx)
+		if err != nil {
+			debug.Logf("run: coverage merge failed: %v", err)
+			coverageErr = fmt.Errorf("failed to merge coverage: %w", err)
+		} else {
+			coverageSummary = &summary
+		}
+	}
+	if stream != nil {
+		if err := rc.finishStream(stream, interrupted); err != nil {
+			return err
+		}
+	} else if !debug.IsEnabled() {
 		if err := rc.formatter.PrintMetaStats(); err != nil {
 			debug.Logf("run: failed to print stats: %v", err)
 			return err
 		}
+		if coverageSummary != nil {
+			rc.formatter.PrintCoverage(*coverageSummary, collector.Output())
+		}
+		rc.formatter.PrintWorkerLoads(loads)
+	} else {
+		for _, l := range loads {
+			debug.Logf("run: worker %d load: predicted=%s actual=%s files=%d", l.WorkerID, l.Predicted, l.Actual, l.Files)
+		}
+	}
+	if coverageErr != nil {
+		color.Yellow("\nNo coverage report: %v", coverageErr)
 	}
-	if rc.config.Flags.OpenFaills && len(failures) > 0 && rc.viewer != nil && !debug.IsEnabled() {
+	if interrupted {
+		color.Yellow("\nRun interrupted: partial results saved to %s", rc.config.GetOutputPath())
+		return errInterrupted
+	}
+	if openViewer && len(realFailures) > 0 && rc.viewer != nil && !debug.IsEnabled() {
 		passed, failed := 0, 0
 		for _, r := range results {
 			if r.Success {
@@ -342,7 +600,8 @@ func (rc *RunCommand) Execute(cmd *cobra.Command, args []string) error {
 				TotalTestFiles:  len(results),
 				FailedTestFiles: failed,
 				PassedTestFiles: passed,
-				FailedTestCases: len(failures),
+				FailedTestCases: len(realFailures),
+				FlakyTestCases:  len(failures) - len(realFailures),
 				Duration:        duration.String(),
 				DurationSeconds: duration.Seconds(),
 				Workers:         rc.config.Processors,
@@ -354,8 +613,8 @@ func (rc *RunCommand) Execute(cmd *cobra.Command, args []string) error {
 			return err
 		}
 	}
-	if len(failures) > 0 {
-		return fmt.Errorf("%d test case(s) failed", len(failures))
+	if len(realFailures) > 0 {
+		return errors.Join(fmt.Errorf("%d test case(s) failed", len(realFailures)), coverageErr)
 	}
-	return nil
+	return coverageErr
 }
@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"fmt"
+	"net"
+
+	"ptp/internal/config"
+	"ptp/internal/debug"
+	"ptp/internal/migration"
+	"ptp/internal/remote"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// ServeWorkerCommand handles the serve-worker command: an agent running the test files of
+// coordinators started with --remote
+type ServeWorkerCommand struct {
+	config   *config.Config
+	migrator migration.Migrator
+}
+
+// NewServeWorkerCommand creates a new ServeWorkerCommand
+func NewServeWorkerCommand(cfg *config.Config, migrator migration.Migrator) *ServeWorkerCommand {
+	return &ServeWorkerCommand{
+		config:   cfg,
+		migrator: migrator,
+	}
+}
+
+// Execute runs the command until it is interrupted
+func (sc *ServeWorkerCommand) Execute(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	slots := sc.config.Processors
+	revision, err := remote.Revision(sc.config)
+	if err != nil {
+		return fmt.Errorf("serve-worker needs a git checkout to verify the coordinators' revision: %w", err)
+	}
+	if !sc.config.Flags.SkipMigrate {
+		if err := sc.migrator.Run(ctx, slots, sc.config.Flags.Fresh); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("migration failed: %w", err)
+		}
+		fmt.Println()
+	}
+
+	l, err := net.Listen("tcp", sc.config.Flags.Listen)
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	server := remote.NewServer(sc.config, slots)
+	color.Cyan("Serving %d slot(s) on %s at revision %s (Ctrl+C to stop)", server.Slots(), l.Addr(), revision)
+	debug.Logf("serve-worker: listening on %s", l.Addr())
+	return server.Serve(ctx, l)
+}
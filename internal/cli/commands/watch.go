@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ptp/internal/config"
+	"ptp/internal/debug"
+	"ptp/internal/watch"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// WatchCommand handles the watch command: it reruns the tests affected by each burst of saves
+// through the run command's worker pool
+type WatchCommand struct {
+	config *config.Config
+	run    *RunCommand
+}
+
+// NewWatchCommand creates a new WatchCommand
+func NewWatchCommand(cfg *config.Config, run *RunCommand) *WatchCommand {
+	return &WatchCommand{
+		config: cfg,
+		run:    run,
+	}
+}
+
+// migrationsDir is watched even though database/ is ignored by default, to keep the workers'
+// databases migrated
+func (wc *WatchCommand) migrationsDir() string {
+	return filepath.Join(wc.config.ProjectPath, "database", "migrations")
+}
+
+// Execute runs the command
+func (wc *WatchCommand) Execute(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	rc := wc.run
+	if _, err := rc.prepareScheduler(); err != nil {
+		return err
+	}
+	if err := configureScanner(wc.config, rc.scanner); err != nil {
+		return err
+	}
+	if _, err := wc.discover(); err != nil {
+		return err
+	}
+	if !wc.config.Flags.SkipMigrate {
+		if err := wc.migrate(ctx, wc.config.Flags.Fresh); err != nil {
+			return err
+		}
+	}
+
+	roots := []string{wc.config.ProjectPath}
+	if info, err := os.Stat(wc.migrationsDir()); err == nil && info.IsDir() {
+		roots = append(roots, wc.migrationsDir())
+	}
+	poller := watch.NewPoller(roots, wc.config.PathsToIgnore, ".php")
+	color.Cyan("Watching %s for changes (Ctrl+C to stop)", wc.config.ProjectPath)
+	watch.Watch(ctx, poller, wc.config.Flags.PollInterval, wc.config.Flags.Debounce, func(changed []string) {
+		if err := wc.rerun(ctx, changed); err != nil {
+			color.Red("\n%v", err)
+		}
+	})
+	fmt.Println()
+	return nil
+}
+
+// discover returns the test files selected by the discovery flags
+func (wc *WatchCommand) discover() ([]string, error) {
+	rc := wc.run
+	tests, err := rc.scanner.Scan(wc.config.GetTestPath())
+	if err != nil {
+		return nil, err
+	}
+	tests = rc.filter.FilterByName(tests, wc.config.Flags.NameFilter)
+	return filterByGroups(wc.config, rc.caseParser, tests), nil
+}
+
+// migrate runs the migrations of every worker database
+func (wc *WatchCommand) migrate(ctx context.Context, fresh bool) error {
+	if err := wc.run.migrator.Run(ctx, wc.config.Processors, fresh); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("migration failed: %w", err)
+	}
+	return nil
+}
+
+// rerun migrates when a migration changed and runs the tests affected by the changed files,
+// merging the results into the stored results
+func (wc *WatchCommand) rerun(ctx context.Context, changed []string) error {
+	rc := wc.run
+	debug.Logf("watch: changed %v", changed)
+	migrations := wc.migrationsDir()
+	if abs, err := filepath.Abs(migrations); err == nil {
+		migrations = abs
+	}
+	for _, path := range changed {
+		if strings.HasPrefix(path, migrations+string(filepath.Separator)) {
+			color.Cyan("\nMigrations changed, migrating the worker databases")
+			if err := wc.migrate(ctx, false); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	tests, err := wc.discover()
+	if err != nil {
+		return err
+	}
+	tests = rc.caseParser.AffectedTests(tests, changed)
+	if len(tests) == 0 {
+		fmt.Printf("\r\033[K%s", color.HiBlackString("No tests affected by %d changed file(s)", len(changed)))
+		return nil
+	}
+
+	rc.executor.SetProgress(nil)
+	if !debug.IsEnabled() {
+		rc.formatter.PrintWatchRunning(len(tests), len(changed))
+	}
+	results, duration, err := rc.executor.ExecuteWithOptions(ctx, tests, false)
+	if err != nil {
+		return err
+	}
+	if retries := rc.retries(); retries > 0 && ctx.Err() == nil && hasFailedResults(results) {
+		results = rc.executor.Retry(ctx, results, retries)
+	}
+	failures := rc.collectFailures(results)
+	if err := rc.storage.Update(results, failures); err != nil {
+		return fmt.Errorf("failed to save test results: %w", err)
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	total := 0
+	if out, err := rc.storage.Load(); err == nil {
+		total = out.Meta.FailedTestCases
+	}
+	rc.formatter.PrintWatchStatus(results, failures, duration, total)
+	return nil
+}
@@ -1,36 +1,89 @@
 package cli
 
-import "ptp/internal/config"
+import (
+	"time"
+
+	"ptp/internal/config"
+)
 
 // Flags holds command-line flags
 type Flags struct {
-	Processors    int
-	Filter        string
-	SkipMigrate   bool
-	Fresh         bool
-	TestPath      string
-	NameFilter    string
-	TestCases     bool
-	FailFast      bool
-	OnlyFailed    bool
-	RerunFailures bool
-	OpenFaills    bool
+	Processors      int
+	Filter          string
+	SkipMigrate     bool
+	Fresh           bool
+	TestPath        string
+	NameFilter      string
+	TestCases       bool
+	FailFast        bool
+	OnlyFailed      bool
+	Changed         string
+	TestSuite       string
+	Group           string
+	ExcludeGroup    string
+	Shard           string
+	Remote          string
+	RerunFailures   bool
+	OpenFaills      bool
+	UI              string
+	Scheduler       string
+	Split           bool
+	SplitThreshold  time.Duration
+	Timeout         time.Duration
+	Retries         int
+	Limit           int
+	ReportJUnit     string
+	Report          string
+	Trace           string
+	Coverage        string
+	CoverageOutput  string
+	ReportOutput    string
+	RunID           string
+	Format          string
+	SlowerThreshold float64
+	PollInterval    time.Duration
+	Debounce        time.Duration
+	Listen          string
 }
 
 // ToConfigFlags converts CLI flags to config flags
 func (f *Flags) ToConfigFlags() config.Flags {
 	return config.Flags{
-		Processors:    f.Processors,
-		Filter:        f.Filter,
-		SkipMigrate:   f.SkipMigrate,
-		Fresh:         f.Fresh,
-		TestPath:      f.TestPath,
-		NameFilter:    f.NameFilter,
-		TestCases:     f.TestCases,
-		FailFast:      f.FailFast,
-		OnlyFailed:    f.OnlyFailed,
-		RerunFailures: f.RerunFailures,
-		OpenFaills:    f.OpenFaills,
+		Processors:      f.Processors,
+		Filter:          f.Filter,
+		SkipMigrate:     f.SkipMigrate,
+		Fresh:           f.Fresh,
+		TestPath:        f.TestPath,
+		NameFilter:      f.NameFilter,
+		TestCases:       f.TestCases,
+		FailFast:        f.FailFast,
+		OnlyFailed:      f.OnlyFailed,
+		Changed:         f.Changed,
+		TestSuite:       f.TestSuite,
+		Group:           f.Group,
+		ExcludeGroup:    f.ExcludeGroup,
+		Shard:           f.Shard,
+		Remote:          f.Remote,
+		RerunFailures:   f.RerunFailures,
+		OpenFaills:      f.OpenFaills,
+		UI:              f.UI,
+		Scheduler:       f.Scheduler,
+		Split:           f.Split,
+		SplitThreshold:  f.SplitThreshold,
+		Timeout:         f.Timeout,
+		Retries:         f.Retries,
+		Limit:           f.Limit,
+		ReportJUnit:     f.ReportJUnit,
+		Report:          f.Report,
+		Trace:           f.Trace,
+		Coverage:        f.Coverage,
+		CoverageOutput:  f.CoverageOutput,
+		ReportOutput:    f.ReportOutput,
+		RunID:           f.RunID,
+		Format:          f.Format,
+		SlowerThreshold: f.SlowerThreshold,
+		PollInterval:    f.PollInterval,
+		Debounce:        f.Debounce,
+		Listen:          f.Listen,
 	}
 }
-
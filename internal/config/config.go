@@ -4,6 +4,26 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PHPUnitConfigAuto makes discovery use the PHPUnit configuration file PHPUnit itself would load
+const PHPUnitConfigAuto = "auto"
+
+// Test frameworks accepted by the "framework" key of the configuration file
+const (
+	FrameworkAuto    = "auto"
+	FrameworkPHPUnit = "phpunit"
+	FrameworkPest    = "pest"
+)
+
+// Migration modes accepted by the "migration" key of the configuration file
+const (
+	MigrationMigrate = "migrate"
+	MigrationFresh   = "fresh"
+	MigrationSkip    = "skip"
 )
 
 // Config holds all configuration for the application
@@ -16,29 +36,113 @@ type Config struct {
 	OutputJSONFile string
 	OutputJSONDir  string
 
+	// HistorySize is how many runs are kept in the run history (0 disables it)
+	HistorySize int
+
 	// Execution settings
 	Processors int
+	Scheduler  string
+
+	// Split enables running heavy test files as several chunks of test cases
+	Split bool
+	// SplitThreshold is the historical duration from which a file is split (0 = automatic)
+	SplitThreshold time.Duration
+
+	// Retries is how many times failed test cases are rerun; a case that passes on a retry is flaky
+	Retries int
+
+	// CoverageDir receives a coverage fragment of kind CoverageFragment from every PHPUnit
+	// invocation while `ptp run --coverage` executes ("" when coverage is off)
+	CoverageDir      string
+	CoverageFragment string
+
+	// Timeout limits each PHPUnit invocation (0 = no limit); Timeouts overrides it per path (see TimeoutFor)
+	Timeout  time.Duration
+	Timeouts map[string]time.Duration
 
 	// Paths to ignore when scanning
 	PathsToIgnore []string
 
+	// Framework is the test framework running the tests ("" or FrameworkAuto detects it, see GetFramework)
+	Framework string
+
+	// PHPUnitBinary overrides vendor/bin/phpunit (relative to ProjectPath unless absolute)
+	PHPUnitBinary string
+
+	// PestBinary overrides vendor/bin/pest (relative to ProjectPath unless absolute)
+	PestBinary string
+
+	// PHPUnitConfig makes discovery use the test suites of a PHPUnit configuration file:
+	// "" walks TestPath for *Test.php, PHPUnitConfigAuto uses phpunit.xml(.dist), anything
+	// else is the path of the file (relative to ProjectPath unless absolute)
+	PHPUnitConfig string
+
+	// PHPUnitGroups and PHPUnitExcludeGroups are the <groups> of the PHPUnit configuration file,
+	// the defaults of --group and --exclude-group (see GetGroups)
+	PHPUnitGroups        []string
+	PHPUnitExcludeGroups []string
+
+	// MigrationMode is the default migration behavior before a run (migrate, fresh or skip)
+	MigrationMode string
+
+	// Env holds extra environment variables passed to PHPUnit and artisan
+	Env map[string]string
+
+	// WorkerEnvTemplates are KEY=VALUE entries expanded per worker (see WorkerEnv)
+	WorkerEnvTemplates []string
+
+	// RunnerCommand is the command template tests run through instead of the local runner binary,
+	// split into words (see HasRunnerCommand); RunnerMigrateCommand is the template artisan
+	// migrations run through with it (see ExpandCommand); PathMappings translate paths for both
+	RunnerCommand        []string
+	RunnerMigrateCommand []string
+	PathMappings         []PathMapping
+
+	// File is the configuration file the config was loaded from ("" if none)
+	File string
+
 	// Command flags
 	Flags Flags
 }
 
 // Flags holds command-line flags
 type Flags struct {
-	Processors    int
-	Filter        string
-	SkipMigrate   bool
-	Fresh         bool
-	TestPath      string
-	NameFilter    string
-	TestCases     bool
-	FailFast      bool
-	OnlyFailed    bool
-	RerunFailures bool
-	OpenFaills    bool // after run, open faills viewer if there are failures
+	Processors      int
+	Filter          string
+	SkipMigrate     bool
+	Fresh           bool
+	TestPath        string
+	NameFilter      string
+	TestCases       bool
+	FailFast        bool
+	OnlyFailed      bool
+	Changed         string // git ref: run only tests affected by changes since it
+	TestSuite       string // comma-separated PHPUnit test suites to run
+	Group           string // comma-separated PHPUnit groups to run
+	ExcludeGroup    string // comma-separated PHPUnit groups to skip
+	Shard           string // "i/n": run only the i-th of n partitions of the tests (CI sharding)
+	Remote          string // comma-separated `ptp serve-worker` agents (host[:port]) to run tests on
+	RerunFailures   bool
+	OpenFaills      bool   // after run, open faills viewer if there are failures
+	UI              string // progress display of `ptp run`: plain or dashboard
+	Scheduler       string
+	Split           bool
+	SplitThreshold  time.Duration
+	Timeout         time.Duration
+	Retries         int
+	Limit           int           // number of entries listed by `ptp flaky`
+	ReportJUnit     string        // write a JUnit XML report here after the run
+	Report          string        // comma-separated CI reports written after the run: github, gitlab
+	Trace           string        // write a Chrome trace of the worker timeline here after the run
+	Coverage        string        // merged coverage report written after the run: clover, cobertura or html
+	CoverageOutput  string        // path of the coverage report (a directory for html)
+	ReportOutput    string        // output path for `ptp report` subcommands
+	RunID           string        // run from the history to load instead of the last one
+	Format          string        // output format of `ptp run` and `ptp diff`
+	SlowerThreshold float64       // percent slowdown from which `ptp diff` reports a file as slower
+	PollInterval    time.Duration // how often `ptp watch` polls for changes
+	Debounce        time.Duration // how long `ptp watch` waits for a burst of saves to end
+	Listen          string        // address `ptp serve-worker` listens on
 }
 
 // New creates a new Config with defaults
@@ -49,6 +153,9 @@ func New() *Config {
 		OutputJSONFile: DefaultOutputJSONFile,
 		OutputJSONDir:  DefaultOutputJSONDir,
 		Processors:     DefaultProcessors,
+		Scheduler:      DefaultScheduler,
+		HistorySize:    DefaultHistorySize,
+		MigrationMode:  MigrationMigrate,
 		Flags:          Flags{Processors: DefaultProcessors},
 	}
 	// Copy default paths to ignore
@@ -57,17 +164,24 @@ func New() *Config {
 	return cfg
 }
 
-// Load creates a config and applies flags
-func Load(flags Flags) *Config {
+// Load creates a config with defaults and overlays the project configuration file, if one exists.
+// CLI flags are applied on top of the result by the commands.
+func Load() (*Config, error) {
 	cfg := New()
-	cfg.Flags = flags
-
-	// Apply flag overrides
-	if flags.Processors > 0 {
-		cfg.Processors = flags.Processors
+	path := FindFile(cfg.ProjectPath)
+	if path == "" {
+		return cfg, nil
 	}
 
-	return cfg
+	f, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.ApplyFile(f); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	cfg.File = path
+	return cfg, nil
 }
 
 // GetTestPath returns the test path, using flag if provided
@@ -94,14 +208,131 @@ func (c *Config) GetOutputPath() string {
 	return p
 }
 
+// GetPHPUnitConfigPath returns the explicitly configured PHPUnit configuration file, or "" when
+// PHPUnit's own lookup (phpunit.xml, phpunit.xml.dist) applies
+func (c *Config) GetPHPUnitConfigPath() string {
+	if c.PHPUnitConfig == "" || c.PHPUnitConfig == PHPUnitConfigAuto {
+		return ""
+	}
+	if filepath.IsAbs(c.PHPUnitConfig) {
+		return c.PHPUnitConfig
+	}
+	return filepath.Join(c.ProjectPath, c.PHPUnitConfig)
+}
+
+// GetTestSuites returns the PHPUnit test suites selected with --testsuite
+func (c *Config) GetTestSuites() []string {
+	return splitList(c.Flags.TestSuite)
+}
+
+// GetGroups returns the groups selected with --group and excluded with --exclude-group. As in
+// PHPUnit, each flag falls back to the <groups> of the PHPUnit configuration when not given.
+func (c *Config) GetGroups() (include, exclude []string) {
+	include, exclude = splitList(c.Flags.Group), splitList(c.Flags.ExcludeGroup)
+	if len(include) == 0 {
+		include = c.PHPUnitGroups
+	}
+	if len(exclude) == 0 {
+		exclude = c.PHPUnitExcludeGroups
+	}
+	return include, exclude
+}
+
+// GetRemotes returns the remote worker agents selected with --remote
+func (c *Config) GetRemotes() []string {
+	return splitList(c.Flags.Remote)
+}
+
+// GetReports returns the CI reports selected with --report
+func (c *Config) GetReports() []string {
+	return splitList(c.Flags.Report)
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// GetHistoryDir returns the directory holding one JSON file per retained run
+func (c *Config) GetHistoryDir() string {
+	return filepath.Join(filepath.Dir(c.GetOutputPath()), HistoryDirName)
+}
+
 // GetPHPUnitPath returns the path to PHPUnit binary
 func (c *Config) GetPHPUnitPath() string {
-	return filepath.Join(c.ProjectPath, "vendor", "bin", "phpunit")
+	return c.binaryPath(c.PHPUnitBinary, "phpunit")
+}
+
+// GetPestPath returns the path to Pest binary
+func (c *Config) GetPestPath() string {
+	return c.binaryPath(c.PestBinary, "pest")
+}
+
+func (c *Config) binaryPath(configured, name string) string {
+	if configured != "" {
+		if filepath.IsAbs(configured) {
+			return configured
+		}
+		return filepath.Join(c.ProjectPath, configured)
+	}
+	return filepath.Join(c.ProjectPath, "vendor", "bin", name)
+}
+
+// GetFramework returns the test framework running the tests. Unless configured, Pest is used
+// when a pest binary is configured or installed in vendor/bin and no phpunit binary is configured.
+func (c *Config) GetFramework() string {
+	if c.Framework != "" && c.Framework != FrameworkAuto {
+		return c.Framework
+	}
+	if c.PestBinary != "" {
+		return FrameworkPest
+	}
+	if c.PHPUnitBinary == "" {
+		if _, err := os.Stat(c.GetPestPath()); err == nil {
+			return FrameworkPest
+		}
+	}
+	return FrameworkPHPUnit
+}
+
+// GetTestRunnerPath returns the binary of the test framework (see GetFramework)
+func (c *Config) GetTestRunnerPath() string {
+	if c.GetFramework() == FrameworkPest {
+		return c.GetPestPath()
+	}
+	return c.GetPHPUnitPath()
+}
+
+// Getenv returns an environment variable, preferring the configured env overrides
+func (c *Config) Getenv(key string) string {
+	if v, ok := c.Env[key]; ok {
+		return v
+	}
+	return os.Getenv(key)
+}
+
+// Environ returns the process environment with the configured env overrides appended
+func (c *Config) Environ() []string {
+	env := os.Environ()
+	keys := make([]string, 0, len(c.Env))
+	for k := range c.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+c.Env[k])
+	}
+	return env
 }
 
 // GetDatabaseName returns the database name for a worker
 func (c *Config) GetDatabaseName(workerID int) string {
-	prefix := os.Getenv("DB_DATABASE_PREFIX")
+	prefix := c.Getenv("DB_DATABASE_PREFIX")
 	if prefix == "" {
 		prefix = "testing"
 	}
@@ -109,6 +340,6 @@ func (c *Config) GetDatabaseName(workerID int) string {
 	if workerID == 0 {
 		return prefix
 	}
-	
+
 	return fmt.Sprintf("%s_%d", prefix, workerID)
 }
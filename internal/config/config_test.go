@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"testing"
 )
 
@@ -72,8 +73,8 @@ func TestConfig_GetDatabaseName(t *testing.T) {
 				t.Errorf("database name should not be empty for worker %d", i)
 			}
 			// Check it follows the pattern testing_N
-			if len(name) < 15 {
-				t.Errorf("database name seems too short for worker %d: %s", i, name)
+			if expected := fmt.Sprintf("testing_%d", i); name != expected {
+				t.Errorf("expected %s for worker %d, got %s", expected, i, name)
 			}
 		}
 	})
@@ -95,3 +96,22 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestConfig_GetGroups(t *testing.T) {
+	cfg := &Config{PHPUnitGroups: []string{"default"}, PHPUnitExcludeGroups: []string{"slow", "external"}}
+
+	include, exclude := cfg.GetGroups()
+	if fmt.Sprint(include) != "[default]" || fmt.Sprint(exclude) != "[slow external]" {
+		t.Errorf("expected the phpunit.xml groups without flags, got %v / %v", include, exclude)
+	}
+
+	cfg.Flags = Flags{ExcludeGroup: "db"}
+	include, exclude = cfg.GetGroups()
+	if fmt.Sprint(include) != "[default]" || fmt.Sprint(exclude) != "[db]" {
+		t.Errorf("expected --exclude-group to replace only the excluded groups, got %v / %v", include, exclude)
+	}
+
+	cfg.Flags = Flags{Group: "billing, api"}
+	if include, _ = cfg.GetGroups(); fmt.Sprint(include) != "[billing api]" {
+		t.Errorf("expected --group to replace the included groups, got %v", include)
+	}
+}
@@ -0,0 +1,19 @@
+package config
+
+// Kinds of the coverage fragment written by each PHPUnit invocation (see Config.CoverageDir)
+const (
+	CoverageFragmentPHP    = "php"    // --coverage-php, merged by phpcov
+	CoverageFragmentClover = "clover" // --coverage-clover, merged by ptp
+)
+
+// CoverageOption returns the PHPUnit option writing a coverage fragment of the configured kind,
+// or "" when coverage is off
+func (c *Config) CoverageOption() string {
+	if c.CoverageDir == "" {
+		return ""
+	}
+	if c.CoverageFragment == CoverageFragmentPHP {
+		return "--coverage-php"
+	}
+	return "--coverage-clover"
+}
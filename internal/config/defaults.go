@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 const (
 	// DefaultProjectPath is the default project path
 	DefaultProjectPath = "."
@@ -11,6 +13,16 @@ const (
 	DefaultOutputJSONDir = "storage"
 	// DefaultProcessors is the default number of processors
 	DefaultProcessors = 4
+	// DefaultScheduler is the default test scheduler (shared queue, slowest first)
+	DefaultScheduler = "queue"
+	// DefaultHistorySize is the default number of runs kept in the run history
+	DefaultHistorySize = 20
+	// DefaultWatchInterval is how often `ptp watch` polls the project tree for changes
+	DefaultWatchInterval = 500 * time.Millisecond
+	// DefaultWatchDebounce is how long `ptp watch` waits for a burst of saves to end
+	DefaultWatchDebounce = 300 * time.Millisecond
+	// HistoryDirName is the directory, next to the output JSON file, holding the run history
+	HistoryDirName = "ptp-runs"
 )
 
 // DefaultPathsToIgnore are the default directories to ignore when scanning for tests
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileNames are the project configuration files looked up in the project root, in order of precedence.
+var FileNames = []string{".ptp.yml", ".ptp.yaml", "ptp.json"}
+
+// File mirrors the project configuration file (.ptp.yml or ptp.json).
+// Unset keys keep their defaults; CLI flags always win over the file.
+type File struct {
+	Processors     int               `yaml:"processors" json:"processors"`
+	Scheduler      string            `yaml:"scheduler" json:"scheduler"`
+	Split          bool              `yaml:"split" json:"split"`
+	SplitThreshold string            `yaml:"split_threshold" json:"split_threshold"`
+	Timeout        string            `yaml:"timeout" json:"timeout"`
+	Timeouts       map[string]string `yaml:"timeouts" json:"timeouts"`
+	Retries        int               `yaml:"retries" json:"retries"`
+	TestPath       string            `yaml:"test_path" json:"test_path"`
+	PathsToIgnore  []string          `yaml:"ignore_paths" json:"ignore_paths"`
+	Output         FileOutput        `yaml:"output" json:"output"`
+	HistorySize    *int              `yaml:"history_size" json:"history_size"`
+	Framework      string            `yaml:"framework" json:"framework"`
+	PHPUnit        string            `yaml:"phpunit" json:"phpunit"`
+	Pest           string            `yaml:"pest" json:"pest"`
+	PHPUnitConfig  string            `yaml:"phpunit_config" json:"phpunit_config"`
+	Migration      string            `yaml:"migration" json:"migration"`
+	Env            map[string]string `yaml:"env" json:"env"`
+	WorkerEnv      []string          `yaml:"worker_env" json:"worker_env"`
+	Runner         FileRunner        `yaml:"runner" json:"runner"`
+}
+
+// FileRunner holds the runner command settings of the configuration file
+type FileRunner struct {
+	Command string   `yaml:"command" json:"command"`
+	Migrate string   `yaml:"migrate" json:"migrate"`
+	Paths   []string `yaml:"paths" json:"paths"`
+}
+
+// FileOutput holds the output location settings of the configuration file
+type FileOutput struct {
+	Dir  string `yaml:"dir" json:"dir"`
+	File string `yaml:"file" json:"file"`
+}
+
+// FindFile returns the path of the first configuration file found in dir, or "" if there is none.
+func FindFile(dir string) string {
+	for _, name := range FileNames {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// ReadFile parses a configuration file. The format is picked by extension (.json or YAML).
+// Unknown keys are rejected so typos don't silently fall back to defaults.
+func ReadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	var f File
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&f); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", filepath.Base(path), err)
+		}
+		return &f, nil
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parse %s: %w", filepath.Base(path), err)
+	}
+	return &f, nil
+}
+
+// ApplyFile overlays the values set in the configuration file onto the config
+func (c *Config) ApplyFile(f *File) error {
+	if f.Processors < 0 {
+		return fmt.Errorf("processors must be positive, got %d", f.Processors)
+	}
+	if f.Processors > 0 {
+		c.Processors = f.Processors
+		c.Flags.Processors = f.Processors
+	}
+	if f.Scheduler != "" {
+		c.Scheduler = f.Scheduler
+	}
+	if f.Split {
+		c.Split = true
+	}
+	if f.SplitThreshold != "" {
+		d, err := time.ParseDuration(f.SplitThreshold)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid split_threshold %q (expected a duration such as 45s)", f.SplitThreshold)
+		}
+		c.SplitThreshold = d
+	}
+	if f.Retries < 0 {
+		return fmt.Errorf("retries must not be negative, got %d", f.Retries)
+	}
+	if f.Retries > 0 {
+		c.Retries = f.Retries
+	}
+	if f.Timeout != "" {
+		d, err := parseTimeout("timeout", f.Timeout)
+		if err != nil {
+			return err
+		}
+		c.Timeout = d
+	}
+	for pattern, value := range f.Timeouts {
+		d, err := parseTimeout("timeout for "+pattern, value)
+		if err != nil {
+			return err
+		}
+		if c.Timeouts == nil {
+			c.Timeouts = make(map[string]time.Duration)
+		}
+		c.Timeouts[pattern] = d
+	}
+	if f.TestPath != "" {
+		c.TestPath = f.TestPath
+	}
+	if f.PathsToIgnore != nil {
+		c.PathsToIgnore = append([]string(nil), f.PathsToIgnore...)
+	}
+	if f.Output.Dir != "" {
+		c.OutputJSONDir = f.Output.Dir
+	}
+	if f.Output.File != "" {
+		c.OutputJSONFile = f.Output.File
+	}
+	if f.HistorySize != nil {
+		if *f.HistorySize < 0 {
+			return fmt.Errorf("history_size must not be negative, got %d", *f.HistorySize)
+		}
+		c.HistorySize = *f.HistorySize
+	}
+	switch f.Framework {
+	case "":
+	case FrameworkAuto, FrameworkPHPUnit, FrameworkPest:
+		c.Framework = f.Framework
+	default:
+		return fmt.Errorf("invalid framework %q (expected %s, %s or %s)", f.Framework, FrameworkAuto, FrameworkPHPUnit, FrameworkPest)
+	}
+	if f.PHPUnit != "" {
+		c.PHPUnitBinary = f.PHPUnit
+	}
+	if f.Pest != "" {
+		c.PestBinary = f.Pest
+	}
+	if f.PHPUnitConfig != "" {
+		c.PHPUnitConfig = f.PHPUnitConfig
+	}
+	switch f.Migration {
+	case "":
+	case MigrationMigrate, MigrationFresh, MigrationSkip:
+		c.MigrationMode = f.Migration
+	default:
+		return fmt.Errorf("invalid migration mode %q (expected %s, %s or %s)", f.Migration, MigrationMigrate, MigrationFresh, MigrationSkip)
+	}
+	if f.Runner.Command != "" {
+		words, err := splitCommand(f.Runner.Command)
+		if err != nil {
+			return err
+		}
+		if len(words) == 0 {
+			return fmt.Errorf("empty runner command")
+		}
+		c.RunnerCommand = words
+	}
+	if f.Runner.Migrate != "" {
+		words, err := splitCommand(f.Runner.Migrate)
+		if err != nil {
+			return err
+		}
+		if len(words) == 0 {
+			return fmt.Errorf("empty runner migrate command")
+		}
+		c.RunnerMigrateCommand = words
+	}
+	for _, p := range f.Runner.Paths {
+		m, err := parsePathMapping(p)
+		if err != nil {
+			return err
+		}
+		c.PathMappings = append(c.PathMappings, m)
+	}
+	if err := validateWorkerEnv(f.WorkerEnv); err != nil {
+		return err
+	}
+	c.WorkerEnvTemplates = append(c.WorkerEnvTemplates, f.WorkerEnv...)
+	for k, v := range f.Env {
+		if c.Env == nil {
+			c.Env = make(map[string]string)
+		}
+		c.Env[k] = v
+	}
+	return nil
+}
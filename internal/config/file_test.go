@@ -0,0 +1,219 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestFindFile(t *testing.T) {
+	t.Run("no config file", func(t *testing.T) {
+		if path := FindFile(t.TempDir()); path != "" {
+			t.Errorf("expected no file, got %s", path)
+		}
+	})
+
+	t.Run("yaml takes precedence over json", func(t *testing.T) {
+		dir := t.TempDir()
+		writeConfigFile(t, dir, "ptp.json", "{}")
+		expected := writeConfigFile(t, dir, ".ptp.yml", "")
+		if path := FindFile(dir); path != expected {
+			t.Errorf("expected %s, got %s", expected, path)
+		}
+	})
+}
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("yaml", func(t *testing.T) {
+		path := writeConfigFile(t, dir, ".ptp.yml", `
+processors: 8
+test_path: tests
+ignore_paths: [vendor, legacy]
+output:
+  dir: build
+  file: results.json
+phpunit: bin/phpunit
+migration: fresh
+env:
+  APP_ENV: testing
+`)
+		f, err := ReadFile(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if f.Processors != 8 || f.TestPath != "tests" || f.PHPUnit != "bin/phpunit" || f.Migration != "fresh" {
+			t.Errorf("unexpected scalar values: %+v", f)
+		}
+		if len(f.PathsToIgnore) != 2 || f.Output.Dir != "build" || f.Output.File != "results.json" {
+			t.Errorf("unexpected nested values: %+v", f)
+		}
+		if f.Env["APP_ENV"] != "testing" {
+			t.Errorf("expected APP_ENV=testing, got %q", f.Env["APP_ENV"])
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		path := writeConfigFile(t, dir, "ptp.json", `{"processors": 2, "migration": "skip"}`)
+		f, err := ReadFile(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if f.Processors != 2 || f.Migration != "skip" {
+			t.Errorf("unexpected values: %+v", f)
+		}
+	})
+
+	t.Run("empty yaml", func(t *testing.T) {
+		path := writeConfigFile(t, dir, "empty.yml", "")
+		if _, err := ReadFile(path); err != nil {
+			t.Errorf("unexpected error for empty file: %v", err)
+		}
+	})
+
+	t.Run("unknown key is rejected", func(t *testing.T) {
+		path := writeConfigFile(t, dir, "typo.yml", "procesors: 8\n")
+		if _, err := ReadFile(path); err == nil {
+			t.Error("expected error for unknown key")
+		}
+	})
+}
+
+func TestConfig_ApplyFile(t *testing.T) {
+	t.Run("overrides only set values", func(t *testing.T) {
+		cfg := New()
+		err := cfg.ApplyFile(&File{
+			Processors: 6,
+			PHPUnit:    "/usr/bin/phpunit",
+			Migration:  MigrationSkip,
+			Env:        map[string]string{"DB_DATABASE_PREFIX": "ci"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.Processors != 6 || cfg.Flags.Processors != 6 {
+			t.Errorf("expected 6 processors, got %d/%d", cfg.Processors, cfg.Flags.Processors)
+		}
+		if cfg.OutputJSONDir != DefaultOutputJSONDir {
+			t.Errorf("expected default output dir, got %s", cfg.OutputJSONDir)
+		}
+		if len(cfg.PathsToIgnore) != len(DefaultPathsToIgnore) {
+			t.Errorf("expected default paths to ignore, got %v", cfg.PathsToIgnore)
+		}
+		if cfg.GetPHPUnitPath() != "/usr/bin/phpunit" {
+			t.Errorf("expected configured phpunit path, got %s", cfg.GetPHPUnitPath())
+		}
+		if cfg.MigrationMode != MigrationSkip {
+			t.Errorf("expected migration mode skip, got %s", cfg.MigrationMode)
+		}
+		if name := cfg.GetDatabaseName(2); name != "ci_2" {
+			t.Errorf("expected env override to set database prefix, got %s", name)
+		}
+	})
+
+	t.Run("split threshold", func(t *testing.T) {
+		cfg := New()
+		if err := cfg.ApplyFile(&File{Split: true, SplitThreshold: "45s"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !cfg.Split || cfg.SplitThreshold != 45*time.Second {
+			t.Errorf("expected split with 45s threshold, got %v/%s", cfg.Split, cfg.SplitThreshold)
+		}
+		if err := New().ApplyFile(&File{SplitThreshold: "soon"}); err == nil {
+			t.Error("expected error for invalid split threshold")
+		}
+	})
+
+	t.Run("history size", func(t *testing.T) {
+		if cfg := New(); cfg.HistorySize != DefaultHistorySize {
+			t.Errorf("expected default history size %d, got %d", DefaultHistorySize, cfg.HistorySize)
+		}
+		cfg := New()
+		zero := 0
+		if err := cfg.ApplyFile(&File{HistorySize: &zero}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.HistorySize != 0 {
+			t.Errorf("expected history_size: 0 to disable the history, got %d", cfg.HistorySize)
+		}
+		negative := -1
+		if err := New().ApplyFile(&File{HistorySize: &negative}); err == nil {
+			t.Error("expected error for negative history size")
+		}
+	})
+
+	t.Run("invalid migration mode", func(t *testing.T) {
+		if err := New().ApplyFile(&File{Migration: "sometimes"}); err == nil {
+			t.Error("expected error for invalid migration mode")
+		}
+	})
+
+	t.Run("framework", func(t *testing.T) {
+		cfg := New()
+		cfg.ProjectPath = t.TempDir()
+		if cfg.GetFramework() != FrameworkPHPUnit {
+			t.Errorf("expected phpunit without vendor/bin/pest, got %s", cfg.GetFramework())
+		}
+		if err := os.MkdirAll(filepath.Join(cfg.ProjectPath, "vendor", "bin"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		writeConfigFile(t, filepath.Join(cfg.ProjectPath, "vendor", "bin"), "pest", "#!/bin/sh\n")
+		if cfg.GetFramework() != FrameworkPest || cfg.GetTestRunnerPath() != cfg.GetPestPath() {
+			t.Errorf("expected pest to be detected, got %s (%s)", cfg.GetFramework(), cfg.GetTestRunnerPath())
+		}
+		if err := cfg.ApplyFile(&File{Framework: FrameworkPHPUnit}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.GetFramework() != FrameworkPHPUnit || cfg.GetTestRunnerPath() != cfg.GetPHPUnitPath() {
+			t.Errorf("expected the configured framework to win, got %s", cfg.GetFramework())
+		}
+		if err := New().ApplyFile(&File{Framework: "codeception"}); err == nil {
+			t.Error("expected error for an unknown framework")
+		}
+	})
+
+	t.Run("runner", func(t *testing.T) {
+		cfg := New()
+		err := cfg.ApplyFile(&File{Runner: FileRunner{
+			Command: `docker compose exec -T -e "DB_DATABASE={{db}}" app vendor/bin/phpunit {{file}} {{args}}`,
+			Migrate: `docker compose exec -T -e "DB_DATABASE={{db}}" app php {{file}} {{args}}`,
+			Paths:   []string{".:/var/www/html"},
+		}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := []string{"docker", "compose", "exec", "-T", "-e", "DB_DATABASE={{db}}", "app", "vendor/bin/phpunit", "{{file}}", "{{args}}"}
+		if !cfg.HasRunnerCommand() || strings.Join(cfg.RunnerCommand, "|") != strings.Join(want, "|") {
+			t.Errorf("unexpected runner command %q", cfg.RunnerCommand)
+		}
+		if got := cfg.ExpandCommand(cfg.RunnerMigrateCommand, 2, cfg.ToContainer("artisan"), []string{"migrate", "--force"}); strings.Join(got, " ") !=
+			"docker compose exec -T -e DB_DATABASE="+cfg.GetDatabaseName(2)+" app php /var/www/html/artisan migrate --force" {
+			t.Errorf("unexpected migrate command %q", got)
+		}
+		if len(cfg.PathMappings) != 1 || cfg.PathMappings[0] != (PathMapping{Host: ".", Container: "/var/www/html"}) {
+			t.Errorf("unexpected path mappings %+v", cfg.PathMappings)
+		}
+		for _, f := range []File{
+			{Runner: FileRunner{Command: `docker compose exec "app`}},
+			{Runner: FileRunner{Migrate: `php 'artisan`}},
+			{Runner: FileRunner{Paths: []string{"/var/www/html"}}},
+			{Runner: FileRunner{Paths: []string{".:relative"}}},
+		} {
+			if err := New().ApplyFile(&f); err == nil {
+				t.Errorf("expected an error for %+v", f.Runner)
+			}
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Placeholders available in the runner command templates (besides {{worker}} and {{db}})
+const (
+	FilePlaceholder = "{{file}}"
+	ArgsPlaceholder = "{{args}}"
+)
+
+// PathMapping maps a host directory to the path it has where the runner command executes the
+// tests (e.g. a docker volume)
+type PathMapping struct {
+	Host      string // absolute, or relative to ProjectPath
+	Container string
+}
+
+// HasRunnerCommand reports whether tests run through the runner command template instead of the
+// local test runner binary
+func (c *Config) HasRunnerCommand() bool {
+	return len(c.RunnerCommand) > 0
+}
+
+// ExpandCommand expands a runner command template for a worker: {{worker}} and {{db}} become the
+// worker's number and database, {{file}} the file and {{args}} the arguments. Without {{file}} and
+// {{args}} in the template, the file and the arguments are appended.
+func (c *Config) ExpandCommand(template []string, workerID int, file string, args []string) []string {
+	replacer := strings.NewReplacer(
+		WorkerPlaceholder, strconv.Itoa(workerID),
+		DatabasePlaceholder, c.GetDatabaseName(workerID),
+		FilePlaceholder, file,
+	)
+	var words []string
+	hasFile, hasArgs := false, false
+	for _, word := range template {
+		if word == ArgsPlaceholder {
+			words = append(words, args...)
+			hasArgs = true
+			continue
+		}
+		hasFile = hasFile || strings.Contains(word, FilePlaceholder)
+		words = append(words, replacer.Replace(word))
+	}
+	if !hasFile {
+		words = append(words, file)
+	}
+	if !hasArgs {
+		words = append(words, args...)
+	}
+	return words
+}
+
+// ToContainer translates a host path (absolute or relative to ProjectPath) into the runner
+// command's file system. Paths outside every mapping are returned unchanged.
+func (c *Config) ToContainer(path string) string {
+	abs := path
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(c.ProjectPath, path)
+	}
+	if a, err := filepath.Abs(abs); err == nil {
+		abs = a
+	}
+	for _, m := range c.sortedMappings(func(m PathMapping) string { return c.hostRoot(m) }) {
+		rel, err := filepath.Rel(c.hostRoot(m), abs)
+		if err != nil || !filepath.IsLocal(rel) {
+			continue
+		}
+		container := strings.TrimSuffix(m.Container, "/")
+		if rel == "." {
+			return container
+		}
+		return container + "/" + filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// ToHost replaces the container paths in s (output or logs of the runner command) with the host
+// paths they map to, so failures point at files the host's editor can open
+func (c *Config) ToHost(s string) string {
+	for _, m := range c.sortedMappings(func(m PathMapping) string { return m.Container }) {
+		container := strings.TrimSuffix(m.Container, "/")
+		s = strings.ReplaceAll(s, container+"/", filepath.ToSlash(c.hostRoot(m))+"/")
+	}
+	return s
+}
+
+func (c *Config) hostRoot(m PathMapping) string {
+	root := m.Host
+	if !filepath.IsAbs(root) {
+		root = filepath.Join(c.ProjectPath, root)
+	}
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	return root
+}
+
+// sortedMappings returns the mappings with the longest key first, so nested mappings win
+func (c *Config) sortedMappings(key func(PathMapping) string) []PathMapping {
+	mappings := append([]PathMapping(nil), c.PathMappings...)
+	sort.SliceStable(mappings, func(i, j int) bool { return len(key(mappings[i])) > len(key(mappings[j])) })
+	return mappings
+}
+
+// parsePathMapping parses a "host:container" mapping, as in a docker volume
+func parsePathMapping(s string) (PathMapping, error) {
+	i := strings.LastIndex(s, ":")
+	if i <= 0 || i == len(s)-1 || !strings.HasPrefix(s[i+1:], "/") {
+		return PathMapping{}, fmt.Errorf("invalid runner path mapping %q (expected host:/container/path)", s)
+	}
+	return PathMapping{Host: s[:i], Container: s[i+1:]}, nil
+}
+
+// splitCommand splits a command line into words. Single and double quotes group words; there is
+// no other shell syntax.
+func splitCommand(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in runner command %q", s)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
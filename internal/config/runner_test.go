@@ -0,0 +1,53 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestConfig_PathMappings(t *testing.T) {
+	cfg := New()
+	cfg.ProjectPath = t.TempDir()
+	cfg.PathMappings = []PathMapping{
+		{Host: ".", Container: "/var/www/html"},
+		{Host: "packages", Container: "/opt/packages/"},
+	}
+	root := filepath.ToSlash(cfg.ProjectPath)
+
+	for path, want := range map[string]string{
+		"tests/Unit/UserTest.php":                                    "/var/www/html/tests/Unit/UserTest.php",
+		filepath.Join(cfg.ProjectPath, "phpunit.xml"):                "/var/www/html/phpunit.xml",
+		filepath.Join(cfg.ProjectPath, "packages", "a", "ATest.php"): "/opt/packages/a/ATest.php",
+		cfg.ProjectPath:       "/var/www/html",
+		"/elsewhere/Test.php": "/elsewhere/Test.php",
+	} {
+		if got := cfg.ToContainer(path); got != want {
+			t.Errorf("ToContainer(%q) = %q, want %q", path, got, want)
+		}
+	}
+
+	output := "1) Tests\\UserTest::testCreate\n/var/www/html/tests/Unit/UserTest.php:12\n/opt/packages/a/ATest.php:3"
+	want := "1) Tests\\UserTest::testCreate\n" + root + "/tests/Unit/UserTest.php:12\n" + root + "/packages/a/ATest.php:3"
+	if got := cfg.ToHost(output); got != want {
+		t.Errorf("ToHost() = %q, want %q", got, want)
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	words, err := splitCommand(`docker compose exec  -e 'A=b c' -e "D={{db}}" app`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"docker", "compose", "exec", "-e", "A=b c", "-e", "D={{db}}", "app"}
+	if len(words) != len(want) {
+		t.Fatalf("splitCommand() = %q, want %q", words, want)
+	}
+	for i := range want {
+		if words[i] != want[i] {
+			t.Errorf("word %d = %q, want %q", i, words[i], want[i])
+		}
+	}
+	if words, _ := splitCommand(`run ""`); len(words) != 2 || words[1] != "" {
+		t.Errorf("expected an empty quoted word, got %q", words)
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TimeoutFor returns how long a single PHPUnit invocation for testPath may run (0 = no limit).
+// Per-path overrides are matched against the path relative to the project: a pattern matches a
+// directory prefix ("tests/Integration") or the path as a glob ("tests/*/Slow*Test.php").
+// The longest matching pattern wins; without a match the global Timeout applies.
+func (c *Config) TimeoutFor(testPath string) time.Duration {
+	path := testPath
+	if rel, err := filepath.Rel(c.ProjectPath, testPath); err == nil && !strings.HasPrefix(rel, "..") {
+		path = rel
+	}
+	path = filepath.ToSlash(path)
+
+	timeout := c.Timeout
+	best := -1
+	for pattern, d := range c.Timeouts {
+		if len(pattern) > best && matchTimeoutPattern(pattern, path) {
+			timeout = d
+			best = len(pattern)
+		}
+	}
+	return timeout
+}
+
+func matchTimeoutPattern(pattern, path string) bool {
+	pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+	if ok, err := filepath.Match(pattern, path); err == nil && ok {
+		return true
+	}
+	dir := strings.TrimSuffix(pattern, "/")
+	return path == dir || strings.HasPrefix(path, dir+"/")
+}
+
+// parseTimeout parses a timeout value from the configuration file
+func parseTimeout(key, value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s %q (expected a duration such as 5m)", key, value)
+	}
+	return d, nil
+}
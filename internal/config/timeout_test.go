@@ -0,0 +1,44 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfig_TimeoutFor(t *testing.T) {
+	cfg := New()
+	cfg.Timeout = time.Minute
+	cfg.Timeouts = map[string]time.Duration{
+		"tests/Integration":          10 * time.Minute,
+		"tests/Integration/Payments": 20 * time.Minute,
+		"tests/Unit/*/Slow*Test.php": 3 * time.Minute,
+	}
+
+	cases := map[string]time.Duration{
+		"tests/Unit/UserTest.php":                   time.Minute,
+		"tests/Integration/OrderTest.php":           10 * time.Minute,
+		"tests/Integration/Payments/RefundTest.php": 20 * time.Minute,
+		"tests/IntegrationHelpersTest.php":          time.Minute,
+		"tests/Unit/Reports/SlowExportTest.php":     3 * time.Minute,
+	}
+	for path, want := range cases {
+		if got := cfg.TimeoutFor(path); got != want {
+			t.Errorf("%s: expected %s, got %s", path, want, got)
+		}
+	}
+}
+
+func TestConfig_ApplyFile_Timeouts(t *testing.T) {
+	cfg := New()
+	err := cfg.ApplyFile(&File{Timeout: "5m", Timeouts: map[string]string{"tests/Feature": "15m"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Timeout != 5*time.Minute || cfg.TimeoutFor("tests/Feature/ApiTest.php") != 15*time.Minute {
+		t.Errorf("unexpected timeouts: %s / %v", cfg.Timeout, cfg.Timeouts)
+	}
+
+	if err := New().ApplyFile(&File{Timeouts: map[string]string{"tests": "forever"}}); err == nil {
+		t.Error("expected error for invalid timeout")
+	}
+}
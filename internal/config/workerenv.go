@@ -0,0 +1,46 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Placeholders available in worker env templates
+const (
+	WorkerPlaceholder   = "{{worker}}"
+	DatabasePlaceholder = "{{db}}"
+)
+
+// WorkerEnv returns the per-worker variables (KEY=VALUE) for the given worker: the worker's database,
+// its TEST_TOKEN and the expanded worker_env templates. Both the runner and the migrator use it, so
+// a worker always runs its tests against the database that was migrated for it.
+func (c *Config) WorkerEnv(workerID int) []string {
+	db := c.GetDatabaseName(workerID)
+	env := []string{
+		"DB_DATABASE=" + db,
+		"TEST_TOKEN=" + strconv.Itoa(workerID),
+	}
+	r := strings.NewReplacer(WorkerPlaceholder, strconv.Itoa(workerID), DatabasePlaceholder, db)
+	for _, tmpl := range c.WorkerEnvTemplates {
+		env = append(env, r.Replace(tmpl))
+	}
+	return env
+}
+
+// WorkerEnviron returns the full environment for a worker process (see Environ and WorkerEnv).
+// Later entries win, so worker variables override anything inherited from the parent process.
+func (c *Config) WorkerEnviron(workerID int) []string {
+	return append(c.Environ(), c.WorkerEnv(workerID)...)
+}
+
+// validateWorkerEnv checks that every template has the KEY=VALUE form
+func validateWorkerEnv(templates []string) error {
+	for _, tmpl := range templates {
+		key, _, ok := strings.Cut(tmpl, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return fmt.Errorf("invalid worker_env entry %q (expected KEY=VALUE)", tmpl)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfig_WorkerEnv(t *testing.T) {
+	cfg := New()
+	cfg.WorkerEnvTemplates = []string{"REDIS_DB={{worker}}", "CACHE_PREFIX=w{{worker}}_", "SCOUT_PREFIX={{db}}_"}
+
+	env := cfg.WorkerEnv(3)
+	expected := []string{
+		"DB_DATABASE=testing_3",
+		"TEST_TOKEN=3",
+		"REDIS_DB=3",
+		"CACHE_PREFIX=w3_",
+		"SCOUT_PREFIX=testing_3_",
+	}
+	if len(env) != len(expected) {
+		t.Fatalf("expected %d entries, got %d: %v", len(expected), len(env), env)
+	}
+	for i := range expected {
+		if env[i] != expected[i] {
+			t.Errorf("entry %d: expected %s, got %s", i, expected[i], env[i])
+		}
+	}
+}
+
+func TestConfig_WorkerEnviron(t *testing.T) {
+	cfg := New()
+	cfg.Env = map[string]string{"DB_DATABASE": "shared"}
+
+	env := cfg.WorkerEnviron(2)
+	// The worker database must come after the generic override so it wins
+	last := ""
+	for _, kv := range env {
+		if strings.HasPrefix(kv, "DB_DATABASE=") {
+			last = kv
+		}
+	}
+	if last != "DB_DATABASE=testing_2" {
+		t.Errorf("expected worker database to win, got %s", last)
+	}
+}
+
+func TestConfig_ApplyFile_WorkerEnv(t *testing.T) {
+	cfg := New()
+	if err := cfg.ApplyFile(&File{WorkerEnv: []string{"REDIS_DB={{worker}}"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.WorkerEnvTemplates) != 1 {
+		t.Errorf("expected 1 template, got %v", cfg.WorkerEnvTemplates)
+	}
+
+	if err := New().ApplyFile(&File{WorkerEnv: []string{"REDIS_DB"}}); err == nil {
+		t.Error("expected error for entry without '='")
+	}
+}
@@ -0,0 +1,418 @@
+package coverage
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ptp/internal/domain"
+)
+
+// Clover XML as written by PHPUnit's --coverage-clover: files sit in a package per namespace or
+// directly in the project
+type cloverReport struct {
+	XMLName   xml.Name      `xml:"coverage"`
+	Generated string        `xml:"generated,attr"`
+	Project   cloverProject `xml:"project"`
+}
+
+type cloverProject struct {
+	Timestamp string          `xml:"timestamp,attr"`
+	Name      string          `xml:"name,attr,omitempty"`
+	Packages  []cloverPackage `xml:"package"`
+	Files     []cloverFile    `xml:"file"`
+	Metrics   cloverMetrics   `xml:"metrics"`
+}
+
+type cloverPackage struct {
+	Name  string       `xml:"name,attr"`
+	Files []cloverFile `xml:"file"`
+}
+
+type cloverFile struct {
+	Name    string        `xml:"name,attr"`
+	Classes []cloverClass `xml:"class"`
+	Lines   []cloverLine  `xml:"line"`
+	Metrics cloverMetrics `xml:"metrics"`
+}
+
+type cloverClass struct {
+	Name      string        `xml:"name,attr"`
+	Namespace string        `xml:"namespace,attr,omitempty"`
+	Metrics   cloverMetrics `xml:"metrics"`
+}
+
+type cloverLine struct {
+	Num        int    `xml:"num,attr"`
+	Type       string `xml:"type,attr"`
+	Name       string `xml:"name,attr,omitempty"`
+	Visibility string `xml:"visibility,attr,omitempty"`
+	Complexity string `xml:"complexity,attr,omitempty"`
+	Crap       string `xml:"crap,attr,omitempty"`
+	Count      int    `xml:"count,attr"`
+}
+
+type cloverMetrics struct {
+	Files               int `xml:"files,attr,omitempty"`
+	LOC                 int `xml:"loc,attr,omitempty"`
+	NCLOC               int `xml:"ncloc,attr,omitempty"`
+	Classes             int `xml:"classes,attr,omitempty"`
+	Methods             int `xml:"methods,attr"`
+	CoveredMethods      int `xml:"coveredmethods,attr"`
+	Conditionals        int `xml:"conditionals,attr"`
+	CoveredConditionals int `xml:"coveredconditionals,attr"`
+	Statements          int `xml:"statements,attr"`
+	CoveredStatements   int `xml:"coveredstatements,attr"`
+	Elements            int `xml:"elements,attr"`
+	CoveredElements     int `xml:"coveredelements,attr"`
+}
+
+// Line types of clover reports
+const (
+	lineStatement = "stmt"
+	lineMethod    = "method"
+)
+
+// mergedFile is a source file across the fragments: the execution counts of its lines are summed
+type mergedFile struct {
+	name    string
+	pkg     string
+	classes []cloverClass
+	lines   map[int]cloverLine
+	metrics cloverMetrics // static metrics (loc, conditionals) of the fragments
+}
+
+// cloverMerger sums the line counts of clover fragments; each fragment covers the tests of one
+// PHPUnit invocation, so the sums are the number of tests that executed each line
+type cloverMerger struct {
+	files map[string]*mergedFile
+	// path translates the file names of the fragments (e.g. container paths back to host paths)
+	path func(string) string
+}
+
+func newCloverMerger(path func(string) string) *cloverMerger {
+	return &cloverMerger{files: make(map[string]*mergedFile), path: path}
+}
+
+// Add merges a clover fragment
+func (m *cloverMerger) Add(r io.Reader) error {
+	var report cloverReport
+	if err := xml.NewDecoder(r).Decode(&report); err != nil {
+		return fmt.Errorf("parse clover report: %w", err)
+	}
+	for _, f := range report.Project.Files {
+		m.addFile("", f)
+	}
+	for _, p := range report.Project.Packages {
+		for _, f := range p.Files {
+			m.addFile(p.Name, f)
+		}
+	}
+	return nil
+}
+
+func (m *cloverMerger) addFile(pkg string, f cloverFile) {
+	name := f.Name
+	if m.path != nil {
+		name = m.path(name)
+	}
+	merged := m.files[name]
+	if merged == nil {
+		merged = &mergedFile{name: name, pkg: pkg, lines: make(map[int]cloverLine)}
+		m.files[name] = merged
+	}
+	for _, c := range f.Classes {
+		merged.addClass(c)
+	}
+	for _, l := range f.Lines {
+		if prev, ok := merged.lines[l.Num]; ok {
+			l.Count += prev.Count
+		}
+		merged.lines[l.Num] = l
+	}
+	merged.metrics.LOC = max(merged.metrics.LOC, f.Metrics.LOC)
+	merged.metrics.NCLOC = max(merged.metrics.NCLOC, f.Metrics.NCLOC)
+}
+
+// addClass keeps a class of the file; without the line ranges of classes, the covered counts of a
+// class are the best of the fragments (a lower bound) unless it is the only class of its file.
+// Conditionals are dropped: clover does not say which branches a fragment covered, so they cannot
+// be merged and the merged report records no branches.
+func (f *mergedFile) addClass(c cloverClass) {
+	c.Metrics.Elements -= c.Metrics.Conditionals
+	c.Metrics.CoveredElements -= c.Metrics.CoveredConditionals
+	c.Metrics.Conditionals, c.Metrics.CoveredConditionals = 0, 0
+	for i, prev := range f.classes {
+		if prev.Name == c.Name {
+			m := &f.classes[i].Metrics
+			m.CoveredMethods = max(m.CoveredMethods, c.Metrics.CoveredMethods)
+			m.CoveredStatements = max(m.CoveredStatements, c.Metrics.CoveredStatements)
+			m.CoveredElements = max(m.CoveredElements, c.Metrics.CoveredElements)
+			return
+		}
+	}
+	f.classes = append(f.classes, c)
+}
+
+// sortedLines returns the lines of the file by number, with the counts of its methods recomputed:
+// a method runs from its line to the next method's, its count is the highest count of its
+// statements, and it is covered when all its statements are
+func (f *mergedFile) sortedLines() (lines []cloverLine, methods, coveredMethods int) {
+	for _, l := range f.lines {
+		lines = append(lines, l)
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i].Num < lines[j].Num })
+	method := -1
+	statements, covered := 0, 0
+	closeMethod := func() {
+		if method < 0 {
+			return
+		}
+		methods++
+		if statements > 0 && covered == statements {
+			coveredMethods++
+		}
+	}
+	for i, l := range lines {
+		switch l.Type {
+		case lineMethod:
+			closeMethod()
+			method, statements, covered = i, 0, 0
+			lines[i].Count = 0
+		case lineStatement:
+			if method >= 0 {
+				statements++
+				if l.Count > 0 {
+					covered++
+				}
+				lines[method].Count = max(lines[method].Count, l.Count)
+			}
+		}
+	}
+	closeMethod()
+	return lines, methods, coveredMethods
+}
+
+// report builds the merged clover report
+func (m *cloverMerger) report(now time.Time) cloverReport {
+	report := cloverReport{
+		Generated: strconv.FormatInt(now.Unix(), 10),
+		Project:   cloverProject{Timestamp: strconv.FormatInt(now.Unix(), 10)},
+	}
+	packages := make(map[string]int)
+	total := &report.Project.Metrics
+	for _, f := range m.sortedFiles() {
+		lines, methods, coveredMethods := f.sortedLines()
+		metrics := f.metrics
+		metrics.Classes = len(f.classes)
+		metrics.Methods, metrics.CoveredMethods = methods, coveredMethods
+		for _, l := range lines {
+			if l.Type == lineStatement {
+				metrics.Statements++
+				if l.Count > 0 {
+					metrics.CoveredStatements++
+				}
+			}
+		}
+		metrics.Elements = metrics.Methods + metrics.Conditionals + metrics.Statements
+		metrics.CoveredElements = metrics.CoveredMethods + metrics.CoveredConditionals + metrics.CoveredStatements
+		classes := append([]cloverClass(nil), f.classes...)
+		if len(classes) == 1 {
+			classes[0].Metrics = metrics
+			classes[0].Metrics.LOC, classes[0].Metrics.NCLOC, classes[0].Metrics.Classes = 0, 0, 0
+		}
+		file := cloverFile{Name: f.name, Classes: classes, Lines: lines, Metrics: metrics}
+
+		if f.pkg == "" {
+			report.Project.Files = append(report.Project.Files, file)
+		} else {
+			i, ok := packages[f.pkg]
+			if !ok {
+				i = len(report.Project.Packages)
+				packages[f.pkg] = i
+				report.Project.Packages = append(report.Project.Packages, cloverPackage{Name: f.pkg})
+			}
+			report.Project.Packages[i].Files = append(report.Project.Packages[i].Files, file)
+		}
+
+		total.Files++
+		total.LOC += metrics.LOC
+		total.NCLOC += metrics.NCLOC
+		total.Classes += metrics.Classes
+		total.Methods += metrics.Methods
+		total.CoveredMethods += metrics.CoveredMethods
+		total.Conditionals += metrics.Conditionals
+		total.CoveredConditionals += metrics.CoveredConditionals
+		total.Statements += metrics.Statements
+		total.CoveredStatements += metrics.CoveredStatements
+		total.Elements += metrics.Elements
+		total.CoveredElements += metrics.CoveredElements
+	}
+	return report
+}
+
+func (m *cloverMerger) sortedFiles() []*mergedFile {
+	files := make([]*mergedFile, 0, len(m.files))
+	for _, f := range m.files {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+	return files
+}
+
+// Summary returns the covered lines and branches of the merged report
+func (m *cloverMerger) Summary() domain.CoverageSummary {
+	metrics := m.report(time.Now()).Project.Metrics
+	return domain.CoverageSummary{
+		Lines:           metrics.Statements,
+		CoveredLines:    metrics.CoveredStatements,
+		Branches:        metrics.Conditionals,
+		CoveredBranches: metrics.CoveredConditionals,
+	}
+}
+
+// WriteClover writes the merged report as clover XML
+func (m *cloverMerger) WriteClover(w io.Writer) error {
+	return writeXML(w, m.report(time.Now()), "")
+}
+
+// Cobertura XML, as GitLab and Jenkins read it: a package per directory and a class per file
+type coberturaReport struct {
+	XMLName         xml.Name           `xml:"coverage"`
+	LineRate        string             `xml:"line-rate,attr"`
+	BranchRate      string             `xml:"branch-rate,attr"`
+	LinesCovered    int                `xml:"lines-covered,attr"`
+	LinesValid      int                `xml:"lines-valid,attr"`
+	BranchesCovered int                `xml:"branches-covered,attr"`
+	BranchesValid   int                `xml:"branches-valid,attr"`
+	Complexity      int                `xml:"complexity,attr"`
+	Version         string             `xml:"version,attr"`
+	Timestamp       int64              `xml:"timestamp,attr"`
+	Sources         []string           `xml:"sources>source"`
+	Packages        []coberturaPackage `xml:"packages>package"`
+}
+
+type coberturaPackage struct {
+	Name       string           `xml:"name,attr"`
+	LineRate   string           `xml:"line-rate,attr"`
+	BranchRate string           `xml:"branch-rate,attr"`
+	Complexity int              `xml:"complexity,attr"`
+	Classes    []coberturaClass `xml:"classes>class"`
+}
+
+type coberturaClass struct {
+	Name       string          `xml:"name,attr"`
+	Filename   string          `xml:"filename,attr"`
+	LineRate   string          `xml:"line-rate,attr"`
+	BranchRate string          `xml:"branch-rate,attr"`
+	Complexity int             `xml:"complexity,attr"`
+	Methods    struct{}        `xml:"methods"`
+	Lines      []coberturaLine `xml:"lines>line"`
+}
+
+type coberturaLine struct {
+	Number int `xml:"number,attr"`
+	Hits   int `xml:"hits,attr"`
+}
+
+const coberturaDoctype = `<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">` + "\n"
+
+// WriteCobertura writes the merged report as Cobertura XML with file names relative to source
+func (m *cloverMerger) WriteCobertura(w io.Writer, source string) error {
+	now := time.Now()
+	summary := m.report(now).Project.Metrics
+	report := coberturaReport{
+		LineRate:        rate(summary.CoveredStatements, summary.Statements),
+		BranchRate:      rate(summary.CoveredConditionals, summary.Conditionals),
+		LinesCovered:    summary.CoveredStatements,
+		LinesValid:      summary.Statements,
+		BranchesCovered: summary.CoveredConditionals,
+		BranchesValid:   summary.Conditionals,
+		Version:         "0.4",
+		Timestamp:       now.Unix(),
+		Sources:         []string{source},
+	}
+	packages := make(map[string]int)
+	pkgStatements := make(map[string][2]int)
+	for _, f := range m.sortedFiles() {
+		name := f.name
+		if rel, err := filepath.Rel(source, name); err == nil && filepath.IsLocal(rel) {
+			name = rel
+		}
+		name = filepath.ToSlash(name)
+		dir := strings.ReplaceAll(filepath.ToSlash(filepath.Dir(name)), "/", ".")
+		lines, _, _ := f.sortedLines()
+		class := coberturaClass{Name: strings.TrimSuffix(filepath.Base(name), ".php"), Filename: name, BranchRate: "0"}
+		var statements, covered int
+		for _, l := range lines {
+			if l.Type != lineStatement {
+				continue
+			}
+			class.Lines = append(class.Lines, coberturaLine{Number: l.Num, Hits: l.Count})
+			statements++
+			if l.Count > 0 {
+				covered++
+			}
+		}
+		class.LineRate = rate(covered, statements)
+		i, ok := packages[dir]
+		if !ok {
+			i = len(report.Packages)
+			packages[dir] = i
+			report.Packages = append(report.Packages, coberturaPackage{Name: dir, BranchRate: "0"})
+		}
+		report.Packages[i].Classes = append(report.Packages[i].Classes, class)
+		counts := pkgStatements[dir]
+		pkgStatements[dir] = [2]int{counts[0] + covered, counts[1] + statements}
+	}
+	for i, p := range report.Packages {
+		counts := pkgStatements[p.Name]
+		report.Packages[i].LineRate = rate(counts[0], counts[1])
+	}
+	return writeXML(w, report, coberturaDoctype)
+}
+
+// readCoberturaSummary reads the totals of a Cobertura report (e.g. the one phpcov wrote)
+func readCoberturaSummary(path string) (domain.CoverageSummary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return domain.CoverageSummary{}, err
+	}
+	defer f.Close()
+	var report coberturaReport
+	if err := xml.NewDecoder(f).Decode(&report); err != nil {
+		return domain.CoverageSummary{}, fmt.Errorf("parse cobertura report: %w", err)
+	}
+	return domain.CoverageSummary{
+		Lines:           report.LinesValid,
+		CoveredLines:    report.LinesCovered,
+		Branches:        report.BranchesValid,
+		CoveredBranches: report.BranchesCovered,
+	}, nil
+}
+
+func rate(covered, total int) string {
+	if total == 0 {
+		return "0"
+	}
+	return strconv.FormatFloat(float64(covered)/float64(total), 'f', 4, 64)
+}
+
+func writeXML(w io.Writer, v any, doctype string) error {
+	if _, err := io.WriteString(w, xml.Header+doctype); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("encode coverage report: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
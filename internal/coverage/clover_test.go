@@ -0,0 +1,153 @@
+package coverage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ptp/internal/config"
+)
+
+// cloverFragment is the coverage of one invocation: UserTest ran User::create only
+const cloverFragment = `<?xml version="1.0" encoding="UTF-8"?>
+<coverage generated="1">
+  <project timestamp="1">
+    <package name="App">
+      <file name="/app/src/User.php">
+        <class name="App\User" namespace="App"><metrics methods="2" coveredmethods="1" conditionals="0" coveredconditionals="0" statements="3" coveredstatements="2" elements="5" coveredelements="3"/></class>
+        <line num="10" type="method" name="create" visibility="public" complexity="1" crap="1" count="1"/>
+        <line num="11" type="stmt" count="1"/>
+        <line num="12" type="stmt" count="1"/>
+        <line num="20" type="method" name="delete" visibility="public" complexity="1" crap="2" count="0"/>
+        <line num="21" type="stmt" count="0"/>
+        <metrics loc="25" ncloc="20" classes="1" methods="2" coveredmethods="1" conditionals="0" coveredconditionals="0" statements="3" coveredstatements="2" elements="5" coveredelements="3"/>
+      </file>
+    </package>
+    <file name="/app/src/helpers.php">
+      <line num="3" type="stmt" count="0"/>
+      <metrics loc="5" ncloc="5" classes="0" methods="0" coveredmethods="0" conditionals="0" coveredconditionals="0" statements="1" coveredstatements="0" elements="1" coveredelements="0"/>
+    </file>
+    <metrics files="2" loc="30" ncloc="25" classes="1" methods="2" coveredmethods="1" conditionals="0" coveredconditionals="0" statements="4" coveredstatements="2" elements="6" coveredelements="3"/>
+  </project>
+</coverage>`
+
+func TestCloverMerger(t *testing.T) {
+	merger := newCloverMerger(func(path string) string { return strings.Replace(path, "/app/", "/home/dev/shop/", 1) })
+	// DeleteTest ran User::delete and the helper
+	second := strings.NewReplacer(
+		`num="11" type="stmt" count="1"`, `num="11" type="stmt" count="0"`,
+		`num="12" type="stmt" count="1"`, `num="12" type="stmt" count="0"`,
+		`num="21" type="stmt" count="0"`, `num="21" type="stmt" count="2"`,
+		`num="3" type="stmt" count="0"`, `num="3" type="stmt" count="1"`,
+	).Replace(cloverFragment)
+	for _, fragment := range []string{cloverFragment, second, cloverFragment} {
+		if err := merger.Add(strings.NewReader(fragment)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	summary := merger.Summary()
+	if summary.Lines != 4 || summary.CoveredLines != 4 || summary.Branches != 0 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+
+	var clover bytes.Buffer
+	if err := merger.WriteClover(&clover); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<package name="App">`,
+		`<file name="/home/dev/shop/src/User.php">`,
+		`<line num="10" type="method" name="create" visibility="public" complexity="1" crap="1" count="2">`,
+		`<line num="11" type="stmt" count="2">`,
+		`<line num="20" type="method" name="delete" visibility="public" complexity="1" crap="2" count="2">`,
+		`<metrics files="2" loc="30" ncloc="25" classes="1" methods="2" coveredmethods="2" conditionals="0" coveredconditionals="0" statements="4" coveredstatements="4" elements="6" coveredelements="6">`,
+	} {
+		if !strings.Contains(clover.String(), want) {
+			t.Errorf("expected the clover report to contain %q:\n%s", want, clover.String())
+		}
+	}
+
+	var cobertura bytes.Buffer
+	if err := merger.WriteCobertura(&cobertura, "/home/dev/shop"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<coverage line-rate="1.0000" branch-rate="0" lines-covered="4" lines-valid="4"`,
+		`<source>/home/dev/shop</source>`,
+		`<package name="src" line-rate="1.0000"`,
+		`<class name="User" filename="src/User.php" line-rate="1.0000"`,
+		`<line number="21" hits="2"></line>`,
+	} {
+		if !strings.Contains(cobertura.String(), want) {
+			t.Errorf("expected the cobertura report to contain %q:\n%s", want, cobertura.String())
+		}
+	}
+}
+
+func TestCloverMerger_DropsBranches(t *testing.T) {
+	merger := newCloverMerger(nil)
+	fragment := strings.ReplaceAll(cloverFragment, `conditionals="0" coveredconditionals="0"`, `conditionals="4" coveredconditionals="3"`)
+	if err := merger.Add(strings.NewReader(fragment)); err != nil {
+		t.Fatal(err)
+	}
+	if summary := merger.Summary(); summary.Branches != 0 || summary.CoveredBranches != 0 {
+		t.Errorf("expected no branches from merged clover fragments, got %+v", summary)
+	}
+	var clover bytes.Buffer
+	if err := merger.WriteClover(&clover); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(clover.String(), `conditionals="4"`) {
+		t.Errorf("expected the merged report to drop the conditionals:\n%s", clover.String())
+	}
+}
+
+func TestCollector(t *testing.T) {
+	cfg := config.New()
+	cfg.ProjectPath = t.TempDir()
+	output := filepath.Join(cfg.ProjectPath, "build", "clover.xml")
+	c, err := NewCollector(cfg, FormatClover, output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CoverageDir == "" || cfg.CoverageOption() != "--coverage-clover" {
+		t.Fatalf("expected clover fragments to be turned on, got %q (%q)", cfg.CoverageDir, cfg.CoverageFragment)
+	}
+
+	// An invocation that died before writing its fragment leaves an empty file
+	os.WriteFile(filepath.Join(cfg.CoverageDir, "w1-1.xml"), nil, 0644)
+	if _, err := c.Merge(context.Background()); !errors.Is(err, ErrNoCoverage) {
+		t.Errorf("expected ErrNoCoverage without fragments, got %v", err)
+	}
+
+	os.WriteFile(filepath.Join(cfg.CoverageDir, "w2-1.xml"), []byte(cloverFragment), 0644)
+	summary, err := c.Merge(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.CoveredLines != 2 || summary.Lines != 4 || summary.LinePercent() != 50 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+	if _, err := os.Stat(output); err != nil {
+		t.Errorf("expected the report at %s: %v", output, err)
+	}
+
+	dir := cfg.CoverageDir
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) || cfg.CoverageDir != "" {
+		t.Errorf("expected Close to remove the fragments and turn them off")
+	}
+
+	if findPHPCov(cfg) == "" {
+		if _, err := NewCollector(cfg, FormatHTML, ""); err == nil {
+			t.Error("expected an html report to need phpcov")
+		}
+	}
+}
@@ -0,0 +1,221 @@
+// Package coverage collects code coverage across the parallel PHPUnit invocations of a run: every
+// invocation writes a fragment of its own, and the fragments are merged into one report afterwards.
+package coverage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"ptp/internal/config"
+	"ptp/internal/debug"
+	"ptp/internal/domain"
+)
+
+// Report formats of `ptp run --coverage`
+const (
+	FormatClover    = "clover"
+	FormatCobertura = "cobertura"
+	FormatHTML      = "html"
+)
+
+// Formats lists the report formats in the order they are documented
+var Formats = []string{FormatClover, FormatCobertura, FormatHTML}
+
+// defaultOutputs are the report paths, relative to the project, used without --coverage-output
+var defaultOutputs = map[string]string{
+	FormatClover:    filepath.Join("coverage", "clover.xml"),
+	FormatCobertura: filepath.Join("coverage", "cobertura.xml"),
+	FormatHTML:      filepath.Join("coverage", "html"),
+}
+
+// dirName is the directory, next to the output JSON file, holding the fragments of the runner
+// command: like its JUnit logs, they must be inside the project to be visible to a container.
+const dirName = "ptp-coverage"
+
+// ErrNoCoverage is returned when no invocation wrote coverage
+var ErrNoCoverage = errors.New("no coverage was collected: PHPUnit needs pcov or Xdebug (XDEBUG_MODE=coverage) and a source filter in its configuration")
+
+// Collector makes every PHPUnit invocation of a run write a coverage fragment into a directory of
+// its own and merges the fragments into one report. With phpcov, the fragments are PHP
+// (--coverage-php) and phpcov merges them into any format; without it, they are clover XML merged
+// by ptp, for clover and cobertura reports.
+type Collector struct {
+	config *config.Config
+	format string
+	output string
+	phpcov string
+	dir    string
+}
+
+// NewCollector prepares the fragment directory of a run and turns on the fragments in cfg (see
+// Config.CoverageDir) until Close. output is the report path ("" for coverage/<format> in the
+// project); an html report is a directory.
+func NewCollector(cfg *config.Config, format, output string) (*Collector, error) {
+	if _, ok := defaultOutputs[format]; !ok {
+		return nil, fmt.Errorf("unknown coverage format %q (expected %s)", format, strings.Join(Formats, ", "))
+	}
+	if output == "" {
+		output = filepath.Join(cfg.ProjectPath, defaultOutputs[format])
+	}
+	if abs, err := filepath.Abs(output); err == nil {
+		output = abs
+	}
+	c := &Collector{config: cfg, format: format, output: output, phpcov: findPHPCov(cfg)}
+	if format == FormatHTML && c.phpcov == "" {
+		return nil, errors.New("an html coverage report needs phpcov in the project (composer require --dev phpunit/phpcov) and the local runner")
+	}
+
+	var err error
+	if cfg.HasRunnerCommand() {
+		c.dir = filepath.Join(filepath.Dir(cfg.GetOutputPath()), dirName)
+		if err = os.RemoveAll(c.dir); err == nil {
+			err = os.MkdirAll(c.dir, 0755)
+		}
+	} else {
+		c.dir, err = os.MkdirTemp("", "ptp-coverage-*")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create coverage dir: %w", err)
+	}
+
+	cfg.CoverageDir = c.dir
+	cfg.CoverageFragment = config.CoverageFragmentClover
+	if c.phpcov != "" {
+		cfg.CoverageFragment = config.CoverageFragmentPHP
+	}
+	debug.Logf("coverage: %s fragments in %s for a %s report at %s (phpcov=%q)", cfg.CoverageFragment, c.dir, format, output, c.phpcov)
+	return c, nil
+}
+
+// Output returns the path of the report
+func (c *Collector) Output() string {
+	return c.output
+}
+
+// Merge merges the fragments written so far into the report and returns its line and branch totals
+func (c *Collector) Merge(ctx context.Context) (domain.CoverageSummary, error) {
+	fragments, err := c.fragments()
+	if err != nil {
+		return domain.CoverageSummary{}, err
+	}
+	if len(fragments) == 0 {
+		return domain.CoverageSummary{}, ErrNoCoverage
+	}
+	debug.Logf("coverage: merging %d fragment(s)", len(fragments))
+	if err := os.MkdirAll(filepath.Dir(c.output), 0755); err != nil {
+		return domain.CoverageSummary{}, fmt.Errorf("create coverage report dir: %w", err)
+	}
+	if c.phpcov != "" {
+		return c.mergeWithPHPCov(ctx)
+	}
+	return c.mergeClover(fragments)
+}
+
+// Close removes the fragments and turns them off again
+func (c *Collector) Close() error {
+	c.config.CoverageDir, c.config.CoverageFragment = "", ""
+	return os.RemoveAll(c.dir)
+}
+
+// fragments returns the fragments PHPUnit wrote; invocations that died before writing theirs leave
+// an empty file behind, which is removed so phpcov does not trip over it
+func (c *Collector) fragments() ([]string, error) {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, fmt.Errorf("read coverage dir: %w", err)
+	}
+	var fragments []string
+	for _, e := range entries {
+		path := filepath.Join(c.dir, e.Name())
+		if info, err := e.Info(); err != nil || info.Size() == 0 {
+			os.Remove(path)
+			continue
+		}
+		fragments = append(fragments, path)
+	}
+	sort.Strings(fragments)
+	return fragments, nil
+}
+
+// mergeWithPHPCov runs `phpcov merge` on the fragment directory; the totals come from a Cobertura
+// report written alongside (the report itself when it is one)
+func (c *Collector) mergeWithPHPCov(ctx context.Context) (domain.CoverageSummary, error) {
+	summaryPath := c.output
+	args := []string{"merge", "--" + c.format, c.output}
+	if c.format != FormatCobertura {
+		summaryPath = filepath.Join(c.dir, "summary.cobertura")
+		args = append(args, "--cobertura", summaryPath)
+	}
+	args = append(args, c.dir)
+	debug.Logf("coverage: exec %s %v", c.phpcov, args)
+	cmd := exec.CommandContext(ctx, c.phpcov, args...)
+	cmd.Dir = c.config.ProjectPath
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return domain.CoverageSummary{}, fmt.Errorf("phpcov merge: %w\n%s", err, strings.TrimSpace(string(out)))
+	}
+	return readCoberturaSummary(summaryPath)
+}
+
+// mergeClover merges clover fragments and writes the report
+func (c *Collector) mergeClover(fragments []string) (domain.CoverageSummary, error) {
+	merger := newCloverMerger(nil)
+	if c.config.HasRunnerCommand() {
+		merger.path = c.config.ToHost
+	}
+	for _, path := range fragments {
+		f, err := os.Open(path)
+		if err != nil {
+			return domain.CoverageSummary{}, fmt.Errorf("read coverage fragment: %w", err)
+		}
+		err = merger.Add(f)
+		f.Close()
+		if err != nil {
+			return domain.CoverageSummary{}, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+	}
+
+	f, err := os.Create(c.output)
+	if err != nil {
+		return domain.CoverageSummary{}, fmt.Errorf("create coverage report: %w", err)
+	}
+	var write func(io.Writer) error = merger.WriteClover
+	if c.format == FormatCobertura {
+		source, err := filepath.Abs(c.config.ProjectPath)
+		if err != nil {
+			source = c.config.ProjectPath
+		}
+		write = func(w io.Writer) error { return merger.WriteCobertura(w, source) }
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return domain.CoverageSummary{}, err
+	}
+	if err := f.Close(); err != nil {
+		return domain.CoverageSummary{}, fmt.Errorf("write coverage report: %w", err)
+	}
+	return merger.Summary(), nil
+}
+
+// findPHPCov returns the phpcov of the project or, failing that, the one on the PATH. Tests run
+// through the runner command write fragments with the paths of its file system, so they are
+// always merged by ptp.
+func findPHPCov(cfg *config.Config) string {
+	if cfg.HasRunnerCommand() {
+		return ""
+	}
+	local := filepath.Join(cfg.ProjectPath, "vendor", "bin", "phpcov")
+	if _, err := os.Stat(local); err == nil {
+		return local
+	}
+	if path, err := exec.LookPath("phpcov"); err == nil {
+		return path
+	}
+	return ""
+}
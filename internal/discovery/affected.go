@@ -0,0 +1,197 @@
+package discovery
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"ptp/internal/debug"
+)
+
+var (
+	namespacePattern = regexp.MustCompile(`(?m)^\s*namespace\s+([\w\\]+)\s*[;{]`)
+	classPattern     = regexp.MustCompile(`(?m)^\s*(?:(?:abstract|final|readonly)\s+)*(?:class|interface|trait|enum)\s+(\w+)`)
+	usePattern       = regexp.MustCompile(`(?m)^\s*use\s+(?:function\s+|const\s+)?([^;]+);`)
+	qualifiedPattern = regexp.MustCompile(`\\?[A-Za-z_]\w*(?:\\[A-Za-z_]\w*)+`)
+	wordPattern      = regexp.MustCompile(`[A-Za-z_]\w*`)
+)
+
+// phpSource is what the dependency scan knows about a PHP file. Class names are
+// lower-cased because PHP resolves them case-insensitively.
+type phpSource struct {
+	namespace string
+	classes   []string        // fully qualified names of the declared classes, interfaces, traits and enums
+	imports   map[string]bool // fully qualified names imported with use statements
+	qualified map[string]bool // fully qualified names referenced in the code
+	words     map[string]bool // identifiers, for unqualified references within the same namespace
+}
+
+// AffectedTests returns the tests affected by the changed files: changed test files, and tests that
+// reference a class declared in a changed PHP file through a use statement, a fully qualified name,
+// an unqualified name from the same namespace, or by naming convention (User.php -> UserTest.php).
+// Only direct references are followed. Both lists hold file paths; the result keeps the order of tests.
+func (p *Parser) AffectedTests(tests, changed []string) []string {
+	isTest := make(map[string]bool, len(tests))
+	for _, t := range tests {
+		isTest[canonicalPath(t)] = true
+	}
+
+	affected := make(map[string]bool)
+	type changedClass struct {
+		fqcn  string // "" when the file is gone and only its name is known
+		short string
+	}
+	var classes []changedClass
+	for _, path := range changed {
+		cp := canonicalPath(path)
+		if isTest[cp] {
+			affected[cp] = true
+			continue
+		}
+		if !strings.EqualFold(filepath.Ext(path), ".php") {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			// Deleted (or unreadable): the tests still referencing it are the ones to run
+			short := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+			classes = append(classes, changedClass{short: strings.ToLower(short)})
+			continue
+		}
+		src := parsePHPSource(string(content))
+		if len(src.classes) == 0 {
+			debug.Logf("discovery: %s declares no class, not mapped to tests", path)
+		}
+		for _, fqcn := range src.classes {
+			classes = append(classes, changedClass{fqcn: fqcn, short: shortName(fqcn)})
+		}
+	}
+
+	if len(classes) > 0 {
+		for _, test := range tests {
+			cp := canonicalPath(test)
+			if affected[cp] {
+				continue
+			}
+			content, err := os.ReadFile(test)
+			if err != nil {
+				debug.Logf("discovery: cannot read %s: %v", test, err)
+				continue
+			}
+			src := parsePHPSource(string(content))
+			testName := strings.ToLower(strings.TrimSuffix(filepath.Base(test), "Test.php"))
+			for _, c := range classes {
+				if c.short == testName || src.references(c.fqcn, c.short) {
+					affected[cp] = true
+					break
+				}
+			}
+		}
+	}
+
+	var out []string
+	for _, t := range tests {
+		if affected[canonicalPath(t)] {
+			out = append(out, t)
+		}
+	}
+	debug.Logf("discovery: %d of %d tests affected by %d changed files (%d changed classes)", len(out), len(tests), len(changed), len(classes))
+	return out
+}
+
+// references reports whether the source refers to the class fqcn. With an unknown fqcn (deleted
+// file) any imported or qualified name ending in the short name counts.
+func (s phpSource) references(fqcn, short string) bool {
+	if fqcn == "" {
+		for name := range s.imports {
+			if shortName(name) == short {
+				return true
+			}
+		}
+		for name := range s.qualified {
+			if shortName(name) == short {
+				return true
+			}
+		}
+		return false
+	}
+	if s.imports[fqcn] || s.qualified[fqcn] {
+		return true
+	}
+	return s.namespace == namespaceOf(fqcn) && s.words[short]
+}
+
+func parsePHPSource(content string) phpSource {
+	src := phpSource{
+		imports:   make(map[string]bool),
+		qualified: make(map[string]bool),
+		words:     make(map[string]bool),
+	}
+	if m := namespacePattern.FindStringSubmatch(content); m != nil {
+		src.namespace = strings.ToLower(strings.Trim(m[1], `\`))
+	}
+	for _, m := range classPattern.FindAllStringSubmatch(content, -1) {
+		name := strings.ToLower(m[1])
+		if src.namespace != "" {
+			name = src.namespace + `\` + name
+		}
+		src.classes = append(src.classes, name)
+	}
+	for _, m := range usePattern.FindAllStringSubmatch(content, -1) {
+		for _, name := range expandUse(m[1]) {
+			src.imports[strings.ToLower(name)] = true
+		}
+	}
+	for _, name := range qualifiedPattern.FindAllString(content, -1) {
+		src.qualified[strings.ToLower(strings.TrimPrefix(name, `\`))] = true
+	}
+	for _, word := range wordPattern.FindAllString(content, -1) {
+		src.words[strings.ToLower(word)] = true
+	}
+	return src
+}
+
+// expandUse returns the names imported by the clause of a use statement:
+// "App\Models\User as U", "A, B" and group uses such as "App\Models\{User, Post}"
+func expandUse(clause string) []string {
+	var names []string
+	prefix := ""
+	if open := strings.Index(clause, "{"); open >= 0 {
+		prefix = strings.TrimSpace(clause[:open])
+		clause = strings.TrimSuffix(strings.TrimSpace(clause[open+1:]), "}")
+	}
+	for _, part := range strings.Split(clause, ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		names = append(names, strings.Trim(prefix+fields[0], `\`))
+	}
+	return names
+}
+
+func shortName(fqcn string) string {
+	if i := strings.LastIndex(fqcn, `\`); i >= 0 {
+		return fqcn[i+1:]
+	}
+	return fqcn
+}
+
+func namespaceOf(fqcn string) string {
+	if i := strings.LastIndex(fqcn, `\`); i >= 0 {
+		return fqcn[:i]
+	}
+	return ""
+}
+
+// canonicalPath makes paths from git and from the scanner comparable
+func canonicalPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	return path
+}
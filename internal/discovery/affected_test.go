@@ -0,0 +1,65 @@
+package discovery
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writePHP(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParser_AffectedTests(t *testing.T) {
+	dir := t.TempDir()
+	user := writePHP(t, dir, "app/Models/User.php", "<?php\nnamespace App\\Models;\n\nclass User extends Model {}\n")
+	billing := writePHP(t, dir, "app/Services/Billing.php", "<?php\nnamespace App\\Services;\n\nfinal class Billing {}\n")
+	helper := writePHP(t, dir, "app/Support/Money.php", "<?php\nnamespace App\\Support;\n\nenum Money {}\n")
+	routes := writePHP(t, dir, "routes/web.php", "<?php\nRoute::get('/', fn () => 'ok');\n")
+	deleted := filepath.Join(dir, "app/Legacy/Invoice.php")
+
+	tests := []string{
+		writePHP(t, dir, "tests/Unit/UserTest.php", "<?php\nnamespace Tests\\Unit;\n\nclass UserTest extends TestCase {}\n"),
+		writePHP(t, dir, "tests/Unit/ProfileTest.php", "<?php\nnamespace Tests\\Unit;\n\nuse App\\Models\\{User as Member, Team};\n\nclass ProfileTest extends TestCase {}\n"),
+		writePHP(t, dir, "tests/Feature/CheckoutTest.php", "<?php\nnamespace Tests\\Feature;\n\nclass CheckoutTest extends TestCase {\n  public function testPay() { new \\App\\Services\\Billing(); }\n}\n"),
+		writePHP(t, dir, "tests/Unit/InvoiceExportTest.php", "<?php\nnamespace Tests\\Unit;\n\nuse App\\Legacy\\Invoice;\n\nclass InvoiceExportTest extends TestCase {}\n"),
+		writePHP(t, dir, "tests/Unit/TeamTest.php", "<?php\nnamespace Tests\\Unit;\n\nuse App\\Models\\Team;\n// mentions User and Billing only in a comment\nclass TeamTest extends TestCase {}\n"),
+		writePHP(t, dir, "tests/Unit/ChangedTest.php", "<?php\nclass ChangedTest extends TestCase {}\n"),
+		writePHP(t, dir, "app/Support/MoneyTest.php", "<?php\nnamespace App\\Support;\n\nclass MoneyTest extends TestCase { function testAdd() { Money::cases(); } }\n"),
+	}
+
+	changed := []string{user, billing, helper, routes, deleted, tests[5], filepath.Join(dir, "README.md")}
+	got := NewParser().AffectedTests(tests, changed)
+
+	want := []string{tests[0], tests[1], tests[2], tests[3], tests[5], tests[6]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AffectedTests() =\n  %v\nwant\n  %v", got, want)
+	}
+
+	if got := NewParser().AffectedTests(tests, []string{routes}); len(got) != 0 {
+		t.Errorf("expected no tests for a file without classes, got %v", got)
+	}
+}
+
+func TestExpandUse(t *testing.T) {
+	cases := map[string][]string{
+		`App\Models\User`:                    {`App\Models\User`},
+		`\App\Models\User as Member`:         {`App\Models\User`},
+		`App\Models\User, App\Models\Team`:   {`App\Models\User`, `App\Models\Team`},
+		`App\Models\{User, Team as Squad, }`: {`App\Models\User`, `App\Models\Team`},
+	}
+	for clause, want := range cases {
+		if got := expandUse(clause); !reflect.DeepEqual(got, want) {
+			t.Errorf("expandUse(%q) = %v, want %v", clause, got, want)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package discovery
+
+// Framework recognises the test files of a PHP test framework and reads their test cases
+type Framework interface {
+	// Name is the framework name shown in logs
+	Name() string
+	// Owns reports whether a test file source is written for this framework
+	Owns(src string) bool
+	// FindTestCases returns the names of the test cases declared in the source
+	FindTestCases(src string) []string
+	// Parse returns the test cases of the source with their groups and other metadata
+	Parse(src string) *TestFile
+}
+
+// Frameworks are the supported frameworks in detection order; PHPUnit owns every file no
+// other framework claims
+var Frameworks = []Framework{Pest{}, PHPUnit{}}
+
+// frameworkFor returns the framework a test file source is written for
+func frameworkFor(src string) Framework {
+	for _, f := range Frameworks {
+		if f.Owns(src) {
+			return f
+		}
+	}
+	return PHPUnit{}
+}
@@ -0,0 +1,82 @@
+package discovery
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// NormalizeTestName strips the data provider suffix and trims (e.g. "test_foo with data set #0" -> "test_foo").
+func NormalizeTestName(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, " with "); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+// snakeToCamel converts test_foo_bar to testFooBar.
+func snakeToCamel(s string) string {
+	parts := strings.Split(s, "_")
+	for i := 1; i < len(parts); i++ {
+		if len(parts[i]) > 0 {
+			parts[i] = strings.ToUpper(string(parts[i][0])) + strings.ToLower(parts[i][1:])
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// camelToSnake converts testFooBar to test_foo_bar.
+func camelToSnake(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if i > 0 && unicode.IsUpper(r) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// FindTestFunctionLine returns the 1-based line number of the PHP function matching testName in the file, or 0 if not found.
+func FindTestFunctionLine(filePath, testName string) int {
+	testName = NormalizeTestName(testName)
+	if testName == "" {
+		return 0
+	}
+	// Try exact name and common PHP naming variants (snake_case vs camelCase).
+	namesToTry := []string{testName}
+	if strings.Contains(testName, "_") {
+		namesToTry = append(namesToTry, snakeToCamel(testName))
+	} else {
+		namesToTry = append(namesToTry, camelToSnake(testName))
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		idx := strings.Index(line, "function ")
+		if idx < 0 {
+			continue
+		}
+		after := strings.TrimSpace(line[idx+len("function "):])
+		// Strip trailing ( and everything after for comparison
+		if p := strings.Index(after, "("); p >= 0 {
+			after = strings.TrimSpace(after[:p])
+		}
+		for _, name := range namesToTry {
+			if after == name || strings.HasPrefix(after, name+" ") {
+				return lineNum
+			}
+		}
+	}
+	return 0
+}
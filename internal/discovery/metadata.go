@@ -0,0 +1,336 @@
+package discovery
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"ptp/internal/debug"
+)
+
+// TestFile is the metadata of a test class: groups and covered classes declared on the class
+// apply to every test case
+type TestFile struct {
+	Path   string
+	Class  string
+	Groups []string
+	Covers []string
+	Cases  []TestCase
+}
+
+// TestCase is a test method with the metadata of its attributes and docblock
+type TestCase struct {
+	Name          string
+	Groups        []string
+	Covers        []string
+	DataProviders []string
+	Depends       []string
+}
+
+var (
+	methodDeclPattern = regexp.MustCompile(`function\s+(\w+)\s*\(`)
+	classDeclPattern  = regexp.MustCompile(`(?m)^\s*(?:(?:abstract|final|readonly)\s+)*class\s+(\w+)`)
+	attributePattern  = regexp.MustCompile(`([A-Za-z_\\][\w\\]*)\s*(?:\(([^()]*)\))?`)
+	annotationPattern = regexp.MustCompile(`@(\w+)(?:[ \t]+([^\s*]+))?`)
+	stringArgPattern  = regexp.MustCompile(`'([^']*)'|"([^"]*)"|([\w\\]+)::class`)
+	modifierPattern   = regexp.MustCompile(`(?:public|protected|private|static|final|abstract)$`)
+)
+
+// ParseTestFile parses the test cases of a file and their metadata with the framework the file is
+// written for (see PHPUnit.Parse and Pest.Parse)
+func (p *Parser) ParseTestFile(filePath string) (*TestFile, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file %s: %w", filePath, err)
+	}
+	file := frameworkFor(string(content)).Parse(string(content))
+	file.Path = filePath
+	return file, nil
+}
+
+// parseTestSource parses a PHPUnit test class: its test cases (test* methods, @test and #[Test])
+// with their #[Group]/@group, #[CoversClass]/@covers, #[DataProvider]/@dataProvider and
+// #[Depends]/@depends metadata. #[Ticket]/@ticket and the size attributes count as groups, as in PHPUnit.
+func parseTestSource(src string) *TestFile {
+	file := &TestFile{}
+	if loc := classDeclPattern.FindStringSubmatchIndex(src); loc != nil {
+		file.Class = src[loc[2]:loc[3]]
+		meta := parseMetadata(declarationPrefix(src, loc[0]))
+		file.Groups, file.Covers = meta.groups, meta.covers
+	}
+
+	seen := make(map[string]bool)
+	for _, loc := range methodDeclPattern.FindAllStringSubmatchIndex(src, -1) {
+		name := src[loc[2]:loc[3]]
+		meta := parseMetadata(declarationPrefix(src, loc[0]))
+		if seen[name] || !(strings.HasPrefix(name, "test") || meta.test) {
+			continue
+		}
+		seen[name] = true
+		file.Cases = append(file.Cases, TestCase{
+			Name:          name,
+			Groups:        meta.groups,
+			Covers:        meta.covers,
+			DataProviders: meta.dataProviders,
+			Depends:       meta.depends,
+		})
+	}
+	sort.Slice(file.Cases, func(i, j int) bool { return file.Cases[i].Name < file.Cases[j].Name })
+	return file
+}
+
+// CaseGroups returns the groups of a test case, including the groups of its class
+func (f *TestFile) CaseGroups(c TestCase) []string {
+	return append(append([]string(nil), f.Groups...), c.Groups...)
+}
+
+// declarationPrefix returns the docblocks, attributes and modifiers written directly before
+// the declaration keyword at pos
+func declarationPrefix(src string, pos int) string {
+	end := pos
+	start := pos
+	for {
+		trimmed := strings.TrimRight(src[:start], " \t\r\n")
+		switch {
+		case strings.HasSuffix(trimmed, "*/"):
+			open := strings.LastIndex(trimmed, "/*")
+			if open < 0 {
+				return src[start:end]
+			}
+			start = open
+		case strings.HasSuffix(trimmed, "]"):
+			open := attributeStart(trimmed)
+			if open < 0 {
+				return src[start:end]
+			}
+			start = open
+		default:
+			m := modifierPattern.FindStringIndex(trimmed)
+			if m == nil || (m[0] > 0 && isWordByte(trimmed[m[0]-1])) {
+				return src[start:end]
+			}
+			start = m[0]
+		}
+	}
+}
+
+// attributeStart returns the index of the "#[" opening the attribute that ends s, or -1
+func attributeStart(s string) int {
+	depth := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		switch s[i] {
+		case ']':
+			depth++
+		case '[':
+			depth--
+			if depth == 0 {
+				if i > 0 && s[i-1] == '#' {
+					return i - 1
+				}
+				return -1
+			}
+		}
+	}
+	return -1
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b == '$' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+type metadata struct {
+	test          bool
+	groups        []string
+	covers        []string
+	dataProviders []string
+	depends       []string
+}
+
+// parseMetadata reads the attributes and docblock annotations of a declaration prefix
+func parseMetadata(prefix string) metadata {
+	var m metadata
+	for _, attr := range attributeBodies(prefix) {
+		for _, match := range attributePattern.FindAllStringSubmatch(attr, -1) {
+			name := match[1]
+			if i := strings.LastIndex(name, `\`); i >= 0 {
+				name = name[i+1:]
+			}
+			arg := firstArgument(match[2])
+			switch strings.ToLower(name) {
+			case "test":
+				m.test = true
+			case "group", "ticket":
+				m.groups = appendNonEmpty(m.groups, arg)
+			case "small", "medium", "large":
+				// PHPUnit puts sized tests in the group of their size
+				m.groups = append(m.groups, strings.ToLower(name))
+			case "coversclass", "coversfunction", "coverstrait", "coversmethod":
+				m.covers = appendNonEmpty(m.covers, arg)
+			case "dataprovider", "dataproviderexternal":
+				m.dataProviders = appendNonEmpty(m.dataProviders, arg)
+			case "depends", "dependsexternal", "dependsusingdeepclone", "dependsusingshallowclone":
+				m.depends = appendNonEmpty(m.depends, arg)
+			}
+		}
+	}
+	for _, doc := range docblocks(prefix) {
+		for _, match := range annotationPattern.FindAllStringSubmatch(doc, -1) {
+			arg := strings.TrimSpace(match[2])
+			switch strings.ToLower(match[1]) {
+			case "test":
+				m.test = true
+			case "group", "ticket":
+				m.groups = appendNonEmpty(m.groups, arg)
+			case "small", "medium", "large":
+				m.groups = append(m.groups, strings.ToLower(match[1]))
+			case "covers":
+				m.covers = appendNonEmpty(m.covers, strings.TrimPrefix(arg, `\`))
+			case "dataprovider":
+				m.dataProviders = appendNonEmpty(m.dataProviders, arg)
+			case "depends":
+				m.depends = appendNonEmpty(m.depends, arg)
+			}
+		}
+	}
+	return m
+}
+
+// attributeBodies returns the contents of the #[...] attributes in a declaration prefix
+func attributeBodies(prefix string) []string {
+	var bodies []string
+	for i := 0; i < len(prefix); i++ {
+		if i+1 >= len(prefix) || prefix[i] != '#' || prefix[i+1] != '[' {
+			continue
+		}
+		depth := 0
+		for j := i + 1; j < len(prefix); j++ {
+			if prefix[j] == '[' {
+				depth++
+			} else if prefix[j] == ']' {
+				depth--
+				if depth == 0 {
+					bodies = append(bodies, prefix[i+2:j])
+					i = j
+					break
+				}
+			}
+		}
+	}
+	return bodies
+}
+
+// docblocks returns the /** ... */ comments of a declaration prefix
+func docblocks(prefix string) []string {
+	var docs []string
+	for {
+		start := strings.Index(prefix, "/**")
+		if start < 0 {
+			return docs
+		}
+		end := strings.Index(prefix[start:], "*/")
+		if end < 0 {
+			return docs
+		}
+		docs = append(docs, prefix[start:start+end])
+		prefix = prefix[start+end+2:]
+	}
+}
+
+// firstArgument returns the first string or Foo::class argument of an attribute
+func firstArgument(args string) string {
+	m := stringArgPattern.FindStringSubmatch(args)
+	if m == nil {
+		return ""
+	}
+	for _, v := range m[1:] {
+		if v != "" {
+			return strings.TrimPrefix(v, `\`)
+		}
+	}
+	return ""
+}
+
+func appendNonEmpty(values []string, v string) []string {
+	if v == "" {
+		return values
+	}
+	return append(values, v)
+}
+
+// FilterByGroups keeps the tests with at least one test case selected by the groups: in one of
+// include (any case when include is empty) and in none of exclude. Files without parseable
+// test cases are judged by their class-level groups. PHPUnit's --group and --exclude-group
+// then select the cases within each file.
+func (p *Parser) FilterByGroups(tests []string, include, exclude []string) []string {
+	if len(include) == 0 && len(exclude) == 0 {
+		return tests
+	}
+	var out []string
+	for _, test := range tests {
+		file, err := p.ParseTestFile(test)
+		if err != nil {
+			debug.Logf("discovery: cannot parse %s for groups: %v", test, err)
+			continue
+		}
+		if file.selected(include, exclude) {
+			out = append(out, test)
+		}
+	}
+	debug.Logf("discovery: %d of %d tests selected by groups (include=%v, exclude=%v)", len(out), len(tests), include, exclude)
+	return out
+}
+
+// FindTestCasesInGroups is FindTestCases limited to the test cases selected by the groups
+func (p *Parser) FindTestCasesInGroups(filePath string, include, exclude []string) ([]string, error) {
+	cases, err := p.FindTestCases(filePath)
+	if err != nil || (len(include) == 0 && len(exclude) == 0) {
+		return cases, err
+	}
+	file, err := p.ParseTestFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]TestCase, len(file.Cases))
+	for _, c := range file.Cases {
+		byName[c.Name] = c
+	}
+	var out []string
+	for _, name := range cases {
+		if groupsSelected(file.CaseGroups(byName[name]), include, exclude) {
+			out = append(out, name)
+		}
+	}
+	return out, nil
+}
+
+func (f *TestFile) selected(include, exclude []string) bool {
+	if len(f.Cases) == 0 {
+		return groupsSelected(f.Groups, include, exclude)
+	}
+	for _, c := range f.Cases {
+		if groupsSelected(f.CaseGroups(c), include, exclude) {
+			return true
+		}
+	}
+	return false
+}
+
+func groupsSelected(groups, include, exclude []string) bool {
+	if containsAny(groups, exclude) {
+		return false
+	}
+	return len(include) == 0 || containsAny(groups, include)
+}
+
+func containsAny(values, candidates []string) bool {
+	for _, v := range values {
+		for _, c := range candidates {
+			if strings.EqualFold(v, c) {
+				return true
+			}
+		}
+	}
+	return false
+}
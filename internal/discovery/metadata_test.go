@@ -0,0 +1,152 @@
+package discovery
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const attributedTest = `<?php
+
+namespace Tests\Unit;
+
+use PHPUnit\Framework\Attributes\CoversClass;
+use PHPUnit\Framework\Attributes\DataProvider;
+use PHPUnit\Framework\Attributes\Group;
+use PHPUnit\Framework\Attributes\Test;
+
+#[CoversClass(\App\Billing\Invoice::class)]
+#[Group('billing')]
+final class InvoiceTest extends TestCase
+{
+    #[Test]
+    #[Group('slow'), DataProvider('amounts')]
+    public function it_totals_lines(int $amount): void
+    {
+    }
+
+    /**
+     * @group external
+     * @depends testCreate
+     */
+    public function testSend(): void
+    {
+    }
+
+    public function testCreate(): void
+    {
+    }
+
+    #[\PHPUnit\Framework\Attributes\Test]
+    #[\PHPUnit\Framework\Attributes\Large]
+    public static function renders_pdf(): void
+    {
+    }
+
+    public static function amounts(): array
+    {
+        return [[1], [2]];
+    }
+}
+`
+
+func TestParser_ParseTestFile(t *testing.T) {
+	dir := t.TempDir()
+	path := writePHP(t, dir, "InvoiceTest.php", attributedTest)
+
+	file, err := NewParser().ParseTestFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if file.Class != "InvoiceTest" {
+		t.Errorf("Class = %q, want InvoiceTest", file.Class)
+	}
+	if !reflect.DeepEqual(file.Groups, []string{"billing"}) || !reflect.DeepEqual(file.Covers, []string{`App\Billing\Invoice`}) {
+		t.Errorf("unexpected class metadata: groups %v, covers %v", file.Groups, file.Covers)
+	}
+
+	want := []TestCase{
+		{Name: "it_totals_lines", Groups: []string{"slow"}, DataProviders: []string{"amounts"}},
+		{Name: "renders_pdf", Groups: []string{"large"}},
+		{Name: "testCreate"},
+		{Name: "testSend", Groups: []string{"external"}, Depends: []string{"testCreate"}},
+	}
+	if !reflect.DeepEqual(file.Cases, want) {
+		t.Errorf("Cases = %+v\nwant %+v", file.Cases, want)
+	}
+
+	cases, err := NewParser().FindTestCases(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"it_totals_lines", "renders_pdf", "testCreate", "testSend"}; !reflect.DeepEqual(cases, want) {
+		t.Errorf("FindTestCases() = %v, want %v", cases, want)
+	}
+}
+
+func TestParser_FilterByGroups(t *testing.T) {
+	dir := t.TempDir()
+	invoice := writePHP(t, dir, "InvoiceTest.php", attributedTest)
+	user := writePHP(t, dir, "UserTest.php", `<?php
+/**
+ * @group slow
+ */
+class UserTest extends TestCase
+{
+    public function testCreate() {}
+}
+`)
+	plain := writePHP(t, dir, "PlainTest.php", `<?php
+class PlainTest extends TestCase
+{
+    public function testNothing() {}
+}
+`)
+	tests := []string{invoice, plain, user}
+	parser := NewParser()
+
+	filter := func(include, exclude []string) []string {
+		var out []string
+		for _, f := range parser.FilterByGroups(tests, include, exclude) {
+			out = append(out, filepath.Base(f))
+		}
+		return out
+	}
+
+	cases := []struct {
+		name             string
+		include, exclude []string
+		want             []string
+	}{
+		{"no groups", nil, nil, []string{"InvoiceTest.php", "PlainTest.php", "UserTest.php"}},
+		{"method group", []string{"external"}, nil, []string{"InvoiceTest.php"}},
+		{"class group", []string{"SLOW"}, nil, []string{"InvoiceTest.php", "UserTest.php"}},
+		{"exclude keeps files with other cases", nil, []string{"slow"}, []string{"InvoiceTest.php", "PlainTest.php"}},
+		{"exclude class group", nil, []string{"billing"}, []string{"PlainTest.php", "UserTest.php"}},
+		{"include and exclude", []string{"billing"}, []string{"slow", "external", "large"}, []string{"InvoiceTest.php"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := filter(tc.include, tc.exclude); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("FilterByGroups(%v, %v) = %v, want %v", tc.include, tc.exclude, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParser_FindTestCasesInGroups(t *testing.T) {
+	path := writePHP(t, t.TempDir(), "InvoiceTest.php", attributedTest)
+	parser := NewParser()
+
+	got, err := parser.FindTestCasesInGroups(path, nil, []string{"slow", "large"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"testCreate", "testSend"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("excluding slow and large = %v, want %v", got, want)
+	}
+	got, _ = parser.FindTestCasesInGroups(path, []string{"external"}, nil)
+	if want := []string{"testSend"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("including external = %v, want %v", got, want)
+	}
+}
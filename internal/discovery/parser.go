@@ -16,14 +16,29 @@ func NewParser() *Parser {
 	return &Parser{}
 }
 
-// FindTestCases finds all test cases in a test file
+// FindTestCases finds all test cases in a test file, with the framework the file is written for
 func (p *Parser) FindTestCases(filePath string) ([]string, error) {
 	content, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("error reading file %s: %w", filePath, err)
 	}
+	return frameworkFor(string(content)).FindTestCases(string(content)), nil
+}
+
+// PHPUnit reads PHPUnit test classes
+type PHPUnit struct{}
+
+// Name implements Framework
+func (PHPUnit) Name() string { return "phpunit" }
+
+// Owns implements Framework; PHPUnit is the fallback for every file
+func (PHPUnit) Owns(src string) bool { return true }
 
-	fileContent := string(content)
+// Parse implements Framework
+func (PHPUnit) Parse(src string) *TestFile { return parseTestSource(src) }
+
+// FindTestCases implements Framework
+func (PHPUnit) FindTestCases(fileContent string) []string {
 	testCasesMap := make(map[string]bool) // Use map to avoid duplicates
 
 	// Pattern 1: Methods starting with "test" (more comprehensive)
@@ -71,6 +86,11 @@ func (p *Parser) FindTestCases(filePath string) ([]string, error) {
 		}
 	}
 
+	// Pattern 3: Methods with the PHPUnit 10+ #[Test] attribute
+	for _, c := range parseTestSource(fileContent).Cases {
+		testCasesMap[c.Name] = true
+	}
+
 	// Convert map to sorted slice for consistent output
 	var testCases []string
 	for testCase := range testCasesMap {
@@ -80,5 +100,5 @@ func (p *Parser) FindTestCases(filePath string) ([]string, error) {
 	// Sort for consistent output
 	sort.Strings(testCases)
 
-	return testCases, nil
+	return testCases
 }
@@ -0,0 +1,264 @@
+package discovery
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Pest reads Pest test files: it() and test() closures, optionally nested in describe() blocks
+type Pest struct{}
+
+var (
+	pestCallPattern   = regexp.MustCompile(`(?:^|[;{}(,\s])(describe|it|test)\s*\(`)
+	pestFilePattern   = regexp.MustCompile(`(?m)^\s*(?:describe|it|test)\s*\(\s*['"]`)
+	pestConfigPattern = regexp.MustCompile(`(?m)^\s*(uses|pest|covers)\s*\(`)
+	phpClassPattern   = regexp.MustCompile(`(?m)^\s*(?:(?:abstract|final|readonly)\s+)*class\s+\w+`)
+)
+
+// pestBlock is a describe() block: its name, groups and the source range of its arguments
+type pestBlock struct {
+	name        string
+	open, close int
+	meta        metadata
+}
+
+// Name implements Framework
+func (Pest) Name() string { return "pest" }
+
+// Owns implements Framework: a Pest file calls it(), test() or describe() at the top level and
+// declares no class
+func (Pest) Owns(src string) bool {
+	return pestFilePattern.MatchString(src) && !phpClassPattern.MatchString(src)
+}
+
+// FindTestCases implements Framework
+func (p Pest) FindTestCases(src string) []string {
+	var names []string
+	for _, c := range p.Parse(src).Cases {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// Parse implements Framework. Test cases are named the way Pest reports them: "it does x" for
+// it('does x'), "does x" for test('does x'), prefixed by "`Block` → " inside describe('Block').
+// ->group(), ->covers(), ->depends() and ->with('dataset') are read from the calls chained to a
+// test or describe block, and from uses()/pest() chains for the whole file.
+func (Pest) Parse(src string) *TestFile {
+	src = stripComments(src)
+	file := &TestFile{}
+	for _, loc := range pestConfigPattern.FindAllStringSubmatchIndex(src, -1) {
+		open := loc[1] - 1
+		close := matchParen(src, open)
+		if close < 0 {
+			continue
+		}
+		meta := pestChain(src, close+1)
+		if src[loc[2]:loc[3]] == "covers" {
+			meta.covers = append(meta.covers, classArguments(src[open+1:close])...)
+		}
+		file.Groups = append(file.Groups, meta.groups...)
+		file.Covers = append(file.Covers, meta.covers...)
+	}
+
+	var blocks []pestBlock
+	type call struct {
+		kind, name  string
+		open, close int
+		meta        metadata
+	}
+	var calls []call
+	for _, loc := range pestCallPattern.FindAllStringSubmatchIndex(src, -1) {
+		kind := src[loc[2]:loc[3]]
+		if strings.HasSuffix(strings.TrimRight(src[:loc[2]], " \t\r\n"), "function") {
+			continue
+		}
+		open := loc[1] - 1
+		name, ok := readString(src, open+1)
+		if !ok {
+			continue
+		}
+		close := matchParen(src, open)
+		if close < 0 {
+			continue
+		}
+		c := call{kind: kind, name: name, open: open, close: close, meta: pestChain(src, close+1)}
+		if kind == "describe" {
+			blocks = append(blocks, pestBlock{name: name, open: open, close: close, meta: c.meta})
+			continue
+		}
+		calls = append(calls, c)
+	}
+
+	for _, c := range calls {
+		name := c.name
+		if c.kind == "it" {
+			name = "it " + name
+		}
+		tc := TestCase{
+			Groups:        c.meta.groups,
+			Covers:        c.meta.covers,
+			DataProviders: c.meta.dataProviders,
+			Depends:       c.meta.depends,
+		}
+		// describe blocks are found in source order, so outer blocks come first
+		var prefix []string
+		for _, b := range blocks {
+			if b.open < c.open && c.close < b.close {
+				prefix = append(prefix, "`"+b.name+"`")
+				tc.Groups = append(tc.Groups, b.meta.groups...)
+				tc.Covers = append(tc.Covers, b.meta.covers...)
+			}
+		}
+		if len(prefix) > 0 {
+			name = strings.Join(prefix, " → ") + " → " + name
+		}
+		tc.Name = name
+		file.Cases = append(file.Cases, tc)
+	}
+	return file
+}
+
+// pestChain reads the ->method(...) calls chained from pos
+func pestChain(src string, pos int) metadata {
+	var m metadata
+	for {
+		rest := strings.TrimLeft(src[pos:], " \t\r\n")
+		if !strings.HasPrefix(rest, "->") {
+			return m
+		}
+		pos = len(src) - len(rest) + 2
+		i := pos
+		for i < len(src) && isWordByte(src[i]) {
+			i++
+		}
+		method := src[pos:i]
+		rest = strings.TrimLeft(src[i:], " \t\r\n")
+		if !strings.HasPrefix(rest, "(") {
+			return m
+		}
+		open := len(src) - len(rest)
+		close := matchParen(src, open)
+		if close < 0 {
+			return m
+		}
+		args := src[open+1 : close]
+		switch strings.ToLower(method) {
+		case "group":
+			m.groups = append(m.groups, stringArguments(args)...)
+		case "covers", "coversclass", "coversfunction":
+			m.covers = append(m.covers, classArguments(args)...)
+		case "depends":
+			m.depends = append(m.depends, stringArguments(args)...)
+		case "with":
+			if name, ok := readString(src, open+1); ok {
+				m.dataProviders = append(m.dataProviders, name)
+			}
+		}
+		pos = close + 1
+	}
+}
+
+// stringArguments returns the string literal arguments of a call
+func stringArguments(args string) []string {
+	var out []string
+	for _, m := range stringArgPattern.FindAllStringSubmatch(args, -1) {
+		if m[3] != "" {
+			continue
+		}
+		out = append(out, m[1]+m[2])
+	}
+	return out
+}
+
+// classArguments returns the Foo::class and string arguments of a call
+func classArguments(args string) []string {
+	var out []string
+	for _, m := range stringArgPattern.FindAllStringSubmatch(args, -1) {
+		out = appendNonEmpty(out, strings.TrimPrefix(m[1]+m[2]+m[3], `\`))
+	}
+	return out
+}
+
+// readString reads the PHP string literal starting at pos (after optional whitespace)
+func readString(src string, pos int) (string, bool) {
+	for pos < len(src) && strings.ContainsRune(" \t\r\n", rune(src[pos])) {
+		pos++
+	}
+	if pos >= len(src) || (src[pos] != '\'' && src[pos] != '"') {
+		return "", false
+	}
+	quote := src[pos]
+	var b strings.Builder
+	for i := pos + 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			if i+1 < len(src) && (src[i+1] == quote || src[i+1] == '\\') {
+				i++
+			}
+			b.WriteByte(src[i])
+		case quote:
+			return b.String(), true
+		default:
+			b.WriteByte(src[i])
+		}
+	}
+	return "", false
+}
+
+// matchParen returns the index of the parenthesis closing the one at open, skipping strings, or -1
+func matchParen(src string, open int) int {
+	depth := 0
+	for i := open; i < len(src); i++ {
+		switch src[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		case '\'', '"':
+			i = skipString(src, i)
+		}
+	}
+	return -1
+}
+
+// skipString returns the index of the quote closing the string literal starting at i
+func skipString(src string, i int) int {
+	quote := src[i]
+	for i++; i < len(src) && src[i] != quote; i++ {
+		if src[i] == '\\' {
+			i++
+		}
+	}
+	return i
+}
+
+// stripComments replaces the comments of a PHP source with spaces, keeping offsets and line breaks
+func stripComments(src string) string {
+	b := []byte(src)
+	for i := 0; i < len(b); i++ {
+		switch {
+		case b[i] == '\'' || b[i] == '"':
+			i = skipString(src, i)
+		case b[i] == '#' && (i+1 >= len(b) || b[i+1] != '['), b[i] == '/' && i+1 < len(b) && b[i+1] == '/':
+			for ; i < len(b) && b[i] != '\n'; i++ {
+				b[i] = ' '
+			}
+		case b[i] == '/' && i+1 < len(b) && b[i+1] == '*':
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				end = len(src) - i - 2
+			}
+			for j := i; j < i+end+4 && j < len(b); j++ {
+				if b[j] != '\n' {
+					b[j] = ' '
+				}
+			}
+			i += end + 3
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,80 @@
+package discovery
+
+import (
+	"reflect"
+	"testing"
+)
+
+const pestTest = `<?php
+
+use App\Billing\Invoice;
+
+uses(Tests\TestCase::class)->group('billing');
+
+covers(Invoice::class);
+
+it('totals lines', function (int $amount) {
+    expect(total([$amount]))->toBe($amount); // it('is not a test')
+})->with('amounts')->group('slow');
+
+test("sends \"reminders\"", function () {
+    // test('neither is this')
+})->depends('it totals lines');
+
+describe('PDF', function () {
+    it('renders', fn () => true);
+
+    describe('fonts', function () {
+        test('embeds', function () {
+            $this->test('helper');
+        });
+    })->group('external');
+});
+`
+
+func TestPest_Parse(t *testing.T) {
+	if !(Pest{}).Owns(pestTest) {
+		t.Fatal("expected Pest to own a Pest file")
+	}
+	if (Pest{}).Owns(attributedTest) {
+		t.Fatal("expected Pest not to own a PHPUnit class")
+	}
+
+	file := Pest{}.Parse(pestTest)
+	if !reflect.DeepEqual(file.Groups, []string{"billing"}) || !reflect.DeepEqual(file.Covers, []string{"Invoice"}) {
+		t.Errorf("unexpected file metadata: groups %v, covers %v", file.Groups, file.Covers)
+	}
+	want := []TestCase{
+		{Name: "it totals lines", Groups: []string{"slow"}, DataProviders: []string{"amounts"}},
+		{Name: `sends "reminders"`, Depends: []string{"it totals lines"}},
+		{Name: "`PDF` → it renders"},
+		{Name: "`PDF` → `fonts` → embeds", Groups: []string{"external"}},
+	}
+	if !reflect.DeepEqual(file.Cases, want) {
+		t.Errorf("Cases = %+v\nwant %+v", file.Cases, want)
+	}
+}
+
+func TestParser_FindTestCases_Pest(t *testing.T) {
+	dir := t.TempDir()
+	path := writePHP(t, dir, "InvoiceTest.php", pestTest)
+	parser := NewParser()
+
+	cases, err := parser.FindTestCases(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cases) != 4 || cases[0] != "it totals lines" {
+		t.Errorf("unexpected test cases %v", cases)
+	}
+	if got := parser.FilterByGroups([]string{path}, nil, []string{"billing"}); len(got) != 0 {
+		t.Errorf("expected the file-level group to exclude the file, got %v", got)
+	}
+	got, err := parser.FindTestCasesInGroups(path, []string{"external"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"`PDF` → `fonts` → embeds"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindTestCasesInGroups() = %v, want %v", got, want)
+	}
+}
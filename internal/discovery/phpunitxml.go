@@ -0,0 +1,231 @@
+package discovery
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"ptp/internal/debug"
+)
+
+// PHPUnitConfigNames are the PHPUnit configuration files PHPUnit loads by default, in order of precedence
+var PHPUnitConfigNames = []string{"phpunit.xml", "phpunit.xml.dist", "phpunit.dist.xml"}
+
+// PHPUnitConfig is the part of a PHPUnit configuration file that decides which tests run
+type PHPUnitConfig struct {
+	Path   string
+	Suites []TestSuite
+	Groups Groups
+}
+
+// TestSuite is a <testsuite> of the PHPUnit configuration, with paths resolved against the config file
+type TestSuite struct {
+	Name        string
+	Directories []SuiteDirectory
+	Files       []string
+	Excludes    []string
+}
+
+// SuiteDirectory is a <directory> of a test suite; Path may contain glob patterns
+type SuiteDirectory struct {
+	Path   string
+	Prefix string
+	Suffix string
+}
+
+// Groups are the <groups> included and excluded by default
+type Groups struct {
+	Include []string
+	Exclude []string
+}
+
+type phpunitXML struct {
+	TestSuites []struct {
+		Name        string `xml:"name,attr"`
+		Directories []struct {
+			Path   string `xml:",chardata"`
+			Prefix string `xml:"prefix,attr"`
+			Suffix string `xml:"suffix,attr"`
+		} `xml:"directory"`
+		Files    []string `xml:"file"`
+		Excludes []string `xml:"exclude"`
+	} `xml:"testsuites>testsuite"`
+	Groups struct {
+		Include []string `xml:"include>group"`
+		Exclude []string `xml:"exclude>group"`
+	} `xml:"groups"`
+}
+
+// FindPHPUnitConfig returns the PHPUnit configuration file PHPUnit would load in dir, or "" if there is none
+func FindPHPUnitConfig(dir string) string {
+	for _, name := range PHPUnitConfigNames {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// LoadPHPUnitConfig parses the test suites and groups of a PHPUnit configuration file
+func LoadPHPUnitConfig(path string) (*PHPUnitConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read phpunit config: %w", err)
+	}
+	var doc phpunitXML
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filepath.Base(path), err)
+	}
+
+	dir := filepath.Dir(path)
+	resolve := func(p string) string {
+		p = strings.TrimSpace(p)
+		if filepath.IsAbs(p) {
+			return filepath.Clean(p)
+		}
+		return filepath.Join(dir, filepath.FromSlash(p))
+	}
+
+	cfg := &PHPUnitConfig{
+		Path:   path,
+		Groups: Groups{Include: trimAll(doc.Groups.Include), Exclude: trimAll(doc.Groups.Exclude)},
+	}
+	for _, s := range doc.TestSuites {
+		suite := TestSuite{Name: s.Name}
+		for _, d := range s.Directories {
+			if strings.TrimSpace(d.Path) == "" {
+				continue
+			}
+			suffix := d.Suffix
+			if suffix == "" {
+				suffix = "Test.php"
+			}
+			suite.Directories = append(suite.Directories, SuiteDirectory{Path: resolve(d.Path), Prefix: d.Prefix, Suffix: suffix})
+		}
+		for _, f := range s.Files {
+			if strings.TrimSpace(f) != "" {
+				suite.Files = append(suite.Files, resolve(f))
+			}
+		}
+		for _, e := range s.Excludes {
+			if strings.TrimSpace(e) != "" {
+				suite.Excludes = append(suite.Excludes, resolve(e))
+			}
+		}
+		cfg.Suites = append(cfg.Suites, suite)
+	}
+	debug.Logf("discovery: %s defines %d test suites", path, len(cfg.Suites))
+	return cfg, nil
+}
+
+// SelectSuites returns the named test suites (all of them when names is empty).
+// Names are matched case-insensitively.
+func (c *PHPUnitConfig) SelectSuites(names []string) ([]TestSuite, error) {
+	if len(names) == 0 {
+		return c.Suites, nil
+	}
+	var selected []TestSuite
+	for _, name := range names {
+		found := false
+		for _, s := range c.Suites {
+			if strings.EqualFold(s.Name, name) {
+				selected = append(selected, s)
+				found = true
+			}
+		}
+		if !found {
+			available := make([]string, 0, len(c.Suites))
+			for _, s := range c.Suites {
+				available = append(available, s.Name)
+			}
+			return nil, fmt.Errorf("test suite %q not found in %s (available: %s)", name, filepath.Base(c.Path), strings.Join(available, ", "))
+		}
+	}
+	return selected, nil
+}
+
+// Files returns the test files of the suites, sorted and without duplicates
+func (c *PHPUnitConfig) Files(suites []TestSuite) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+
+	for _, suite := range suites {
+		for _, d := range suite.Directories {
+			roots := []string{d.Path}
+			if strings.ContainsAny(d.Path, "*?[") {
+				matches, err := filepath.Glob(d.Path)
+				if err != nil {
+					return nil, fmt.Errorf("test suite %s: invalid directory pattern %q: %w", suite.Name, d.Path, err)
+				}
+				roots = matches
+			}
+			for _, root := range roots {
+				err := filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+					if err != nil {
+						if os.IsNotExist(err) {
+							debug.Logf("discovery: test suite %s: directory %s does not exist", suite.Name, root)
+							return filepath.SkipDir
+						}
+						return err
+					}
+					if excluded(path, suite.Excludes) {
+						if entry.IsDir() {
+							return filepath.SkipDir
+						}
+						return nil
+					}
+					name := entry.Name()
+					if !entry.IsDir() && strings.HasPrefix(name, d.Prefix) && strings.HasSuffix(name, d.Suffix) {
+						add(path)
+					}
+					return nil
+				})
+				if err != nil {
+					return nil, fmt.Errorf("test suite %s: %w", suite.Name, err)
+				}
+			}
+		}
+		for _, f := range suite.Files {
+			if excluded(f, suite.Excludes) {
+				continue
+			}
+			if _, err := os.Stat(f); err != nil {
+				debug.Logf("discovery: test suite %s: file %s does not exist", suite.Name, f)
+				continue
+			}
+			add(f)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// excluded reports whether path is one of the excluded paths or inside one of them
+func excluded(path string, excludes []string) bool {
+	for _, e := range excludes {
+		if path == e || strings.HasPrefix(path, e+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func trimAll(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
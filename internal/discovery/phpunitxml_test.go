@@ -0,0 +1,110 @@
+package discovery
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testPHPUnitXML = `<?xml version="1.0" encoding="UTF-8"?>
+<phpunit bootstrap="vendor/autoload.php">
+    <testsuites>
+        <testsuite name="Unit">
+            <directory suffix="Test.php">./tests/Unit</directory>
+            <exclude>./tests/Unit/Legacy</exclude>
+        </testsuite>
+        <testsuite name="Spec">
+            <directory suffix="Spec.php">./spec</directory>
+            <directory>./modules/*/tests</directory>
+            <file>./tests/Smoke.php</file>
+        </testsuite>
+    </testsuites>
+    <groups>
+        <exclude>
+            <group>slow</group>
+        </exclude>
+    </groups>
+</phpunit>
+`
+
+func TestPHPUnitConfig(t *testing.T) {
+	dir := t.TempDir()
+	configPath := writePHP(t, dir, "phpunit.xml.dist", testPHPUnitXML)
+	for _, name := range []string{
+		"tests/Unit/UserTest.php",
+		"tests/Unit/Helper.php",
+		"tests/Unit/Legacy/OldTest.php",
+		"tests/Smoke.php",
+		"spec/UserSpec.php",
+		"spec/UserTest.php",
+		"modules/billing/tests/InvoiceTest.php",
+		"modules/billing/src/Invoice.php",
+	} {
+		writePHP(t, dir, name, "<?php")
+	}
+
+	if found := FindPHPUnitConfig(dir); found != configPath {
+		t.Fatalf("FindPHPUnitConfig() = %q, want %q", found, configPath)
+	}
+	cfg, err := LoadPHPUnitConfig(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.Groups.Exclude, []string{"slow"}) {
+		t.Errorf("unexpected groups %+v", cfg.Groups)
+	}
+
+	files := func(names ...string) []string {
+		t.Helper()
+		suites, err := cfg.SelectSuites(names)
+		if err != nil {
+			t.Fatalf("SelectSuites(%v) failed: %v", names, err)
+		}
+		out, err := cfg.Files(suites)
+		if err != nil {
+			t.Fatalf("Files failed: %v", err)
+		}
+		for i, f := range out {
+			rel, _ := filepath.Rel(dir, f)
+			out[i] = filepath.ToSlash(rel)
+		}
+		return out
+	}
+
+	if got, want := files("unit"), []string{"tests/Unit/UserTest.php"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Unit suite = %v, want %v", got, want)
+	}
+	want := []string{"modules/billing/tests/InvoiceTest.php", "spec/UserSpec.php", "tests/Smoke.php", "tests/Unit/UserTest.php"}
+	if got := files(); !reflect.DeepEqual(got, want) {
+		t.Errorf("all suites = %v, want %v", got, want)
+	}
+	if _, err := cfg.SelectSuites([]string{"Browser"}); err == nil {
+		t.Error("expected an error for an unknown suite")
+	}
+}
+
+func TestScanner_UsePHPUnitConfig(t *testing.T) {
+	dir := t.TempDir()
+	configPath := writePHP(t, dir, "phpunit.xml", testPHPUnitXML)
+	writePHP(t, dir, "tests/Unit/UserTest.php", "<?php")
+	writePHP(t, dir, "spec/UserSpec.php", "<?php")
+	cfg, err := LoadPHPUnitConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scanner := NewScanner(nil)
+	scanner.UsePHPUnitConfig(cfg, nil)
+	got, err := scanner.Scan(filepath.Join(dir, "spec"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{filepath.Join(dir, "spec", "UserSpec.php")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Scan() = %v, want %v (files outside the test path are dropped)", got, want)
+	}
+
+	scanner.UsePHPUnitConfig(cfg, []string{"Nope"})
+	if _, err := scanner.Scan(dir); err == nil {
+		t.Error("expected an error for an unknown suite")
+	}
+}
@@ -12,6 +12,10 @@ import (
 // Scanner scans for test files in a directory
 type Scanner struct {
 	skipDirs map[string]bool
+
+	// phpunit, when set, replaces the directory walk with the test suites of the PHPUnit config
+	phpunit *PHPUnitConfig
+	suites  []string
 }
 
 // NewScanner creates a new Scanner with the given directories to skip
@@ -23,11 +27,21 @@ func NewScanner(skipDirs []string) *Scanner {
 	return &Scanner{skipDirs: skipMap}
 }
 
+// UsePHPUnitConfig makes Scan return the files of the given test suites (all suites when none are
+// named) instead of walking for *Test.php. A nil config restores the directory walk.
+func (s *Scanner) UsePHPUnitConfig(cfg *PHPUnitConfig, suites []string) {
+	s.phpunit = cfg
+	s.suites = suites
+}
+
 // Scan finds all test files in the given root directory
 func (s *Scanner) Scan(root string) ([]string, error) {
 	var testfiles []string
 
 	root = filepath.Clean(root)
+	if s.phpunit != nil {
+		return s.scanSuites(root)
+	}
 	debug.Logf("scanner: scanning root %q", root)
 	info, err := os.Stat(root)
 	if err != nil {
@@ -74,3 +88,32 @@ func (s *Scanner) Scan(root string) ([]string, error) {
 	}
 	return testfiles, err
 }
+
+// scanSuites returns the files of the selected PHPUnit test suites that are inside root
+func (s *Scanner) scanSuites(root string) ([]string, error) {
+	suites, err := s.phpunit.SelectSuites(s.suites)
+	if err != nil {
+		return nil, err
+	}
+	files, err := s.phpunit.Files(suites)
+	if err != nil {
+		return nil, err
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	var testfiles []string
+	for _, f := range files {
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			return nil, err
+		}
+		if abs == absRoot || strings.HasPrefix(abs, absRoot+string(filepath.Separator)) {
+			testfiles = append(testfiles, f)
+		}
+	}
+	debug.Logf("scanner: %d of %d files of test suites %v are under %q", len(testfiles), len(files), s.suites, root)
+	return testfiles, nil
+}
@@ -0,0 +1,26 @@
+package domain
+
+// CoverageSummary counts the covered lines and branches of a merged coverage report
+type CoverageSummary struct {
+	Lines           int
+	CoveredLines    int
+	Branches        int // 0 when the coverage driver recorded no branches
+	CoveredBranches int
+}
+
+// LinePercent returns the share of covered lines in percent
+func (s CoverageSummary) LinePercent() float64 {
+	return percent(s.CoveredLines, s.Lines)
+}
+
+// BranchPercent returns the share of covered branches in percent
+func (s CoverageSummary) BranchPercent() float64 {
+	return percent(s.CoveredBranches, s.Branches)
+}
+
+func percent(covered, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(covered) / float64(total) * 100
+}
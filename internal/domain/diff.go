@@ -0,0 +1,43 @@
+package domain
+
+// RunDiff is the comparison of two runs (base = older, head = newer)
+type RunDiff struct {
+	Base RunInfo `json:"base"`
+	Head RunInfo `json:"head"`
+
+	NewlyFailing   []FailureChange  `json:"newly_failing"`
+	Fixed          []FailureChange  `json:"fixed"`
+	StillFailing   []FailureChange  `json:"still_failing"`
+	ChangedMessage []FailureChange  `json:"changed_message"`
+	Slower         []DurationChange `json:"slower"`
+
+	// NotRun counts the base failures whose file did not run (or was interrupted) in head
+	NotRun int `json:"not_run,omitempty"`
+	// SlowerThreshold is the relative slowdown, in percent, from which a file counts as slower
+	SlowerThreshold float64 `json:"slower_threshold_percent"`
+}
+
+// FailureChange is a test case whose outcome is compared between two runs
+type FailureChange struct {
+	FilePath    string `json:"file_path"`
+	TestName    string `json:"test_name"`
+	BaseMessage string `json:"base_message,omitempty"`
+	HeadMessage string `json:"head_message,omitempty"`
+}
+
+// DurationChange is a test file whose duration regressed between two runs
+type DurationChange struct {
+	FilePath    string  `json:"file_path"`
+	BaseSeconds float64 `json:"base_seconds"`
+	HeadSeconds float64 `json:"head_seconds"`
+	// AvgSeconds is the historical running average of the file, when known
+	AvgSeconds float64 `json:"avg_seconds,omitempty"`
+}
+
+// Percent returns the slowdown of the file in percent
+func (d DurationChange) Percent() float64 {
+	if d.BaseSeconds <= 0 {
+		return 0
+	}
+	return (d.HeadSeconds - d.BaseSeconds) / d.BaseSeconds * 100
+}
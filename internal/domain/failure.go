@@ -2,13 +2,44 @@ package domain
 
 // TestFailure represents a failed test case
 type TestFailure struct {
-	TestName     string   `json:"test_name"`
-	FilePath     string   `json:"file_path"`
-	ErrorDetails string   `json:"error_details"`
-	StackTrace   []string `json:"stack_trace"`
-	File         string   `json:"file"`
-	Line         int      `json:"line"`
-	Message      string   `json:"message"`
-	Resolved     bool     `json:"resolved,omitempty"` // Track if test case is marked as resolved
+	TestName     string        `json:"test_name"`
+	Class        string        `json:"class,omitempty"` // Test class, when the result log names it
+	FilePath     string        `json:"file_path"`
+	ErrorDetails string        `json:"error_details"`
+	StackTrace   []string      `json:"stack_trace"`
+	File         string        `json:"file"`
+	Line         int           `json:"line"`
+	Message      string        `json:"message"`
+	Resolved     bool          `json:"resolved,omitempty"` // Track if test case is marked as resolved
+	Flaky        bool          `json:"flaky,omitempty"`    // Failed, then passed on a retry
+	Attempts     []TestAttempt `json:"attempts,omitempty"` // Every execution of the case when it was retried
 }
 
+// TestAttempt is one execution of a retried test case
+type TestAttempt struct {
+	Status          string  `json:"status"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Message         string  `json:"message,omitempty"`
+}
+
+// WithoutFlaky returns the failures that are real failures, dropping flaky ones
+func WithoutFlaky(failures []TestFailure) []TestFailure {
+	out := make([]TestFailure, 0, len(failures))
+	for _, f := range failures {
+		if !f.Flaky {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// CountFlaky returns the number of flaky entries in failures
+func CountFlaky(failures []TestFailure) int {
+	n := 0
+	for _, f := range failures {
+		if f.Flaky {
+			n++
+		}
+	}
+	return n
+}
@@ -4,23 +4,107 @@ import "time"
 
 // TestResult represents the result of executing a test file
 type TestResult struct {
-	TestPath string    // Path to the test file that was executed
-	Success  bool      // Whether the test passed
-	Output   string    // Raw output from PHPUnit
-	Error    error     // Error if execution failed
-	Duration time.Duration // Time taken to execute
+	TestPath    string           // Path to the test file that was executed
+	Success     bool             // Whether the test passed
+	Output      string           // Raw output from PHPUnit
+	Error       error            // Error if execution failed
+	Duration    time.Duration    // Time taken to execute
+	WorkerID    int              // Worker that executed the test
+	Log         []byte           // JUnit XML log written by PHPUnit (empty if PHPUnit died before writing it)
+	Cases       []TestCaseResult // Per-case results parsed from the JUnit log
+	Interrupted bool             // The run was cancelled (Ctrl+C) before the file finished
+	TimedOut    bool             // PHPUnit was killed after exceeding Timeout
+	Timeout     time.Duration    // Time limit that applied to this invocation (0 = none)
+	Retries     []TestResult     // Reruns of the failed cases (see --retries), in order
+	Spans       []WorkerSpan     // When and on which worker the file ran (one span per chunk of a split file)
+}
+
+// WorkerSpan is one PHPUnit invocation on a worker, timed in seconds since the execution started
+type WorkerSpan struct {
+	WorkerID     int     `json:"worker_id"`
+	Path         string  `json:"path"`
+	Chunk        int     `json:"chunk,omitempty"`
+	Chunks       int     `json:"chunks,omitempty"`
+	StartSeconds float64 `json:"start_seconds"`
+	EndSeconds   float64 `json:"end_seconds"`
+	Status       string  `json:"status"` // passed, failed, timed_out or interrupted (see SpanStatus)
+}
+
+// Worker span statuses besides CasePassed and CaseFailed
+const (
+	SpanTimedOut    = "timed_out"
+	SpanInterrupted = "interrupted"
+)
+
+// SpanStatus is the status of a span of a file with the given outcome; a timed-out file counts as
+// timed out rather than failed, so hangs stand out in timelines
+func SpanStatus(success, interrupted, timedOut bool) string {
+	switch {
+	case interrupted:
+		return SpanInterrupted
+	case timedOut:
+		return SpanTimedOut
+	case !success:
+		return CaseFailed
+	}
+	return CasePassed
+}
+
+// Test case statuses
+const (
+	CasePassed  = "passed"
+	CaseFailed  = "failed"
+	CaseError   = "error"
+	CaseSkipped = "skipped" // skipped or incomplete
+	CaseFlaky   = "flaky"   // failed, then passed on a retry
+)
+
+// TestCaseResult is the outcome of a single test case (method or data set)
+type TestCaseResult struct {
+	Name            string  `json:"name"`
+	Class           string  `json:"class,omitempty"`
+	File            string  `json:"file,omitempty"`
+	Line            int     `json:"line,omitempty"`
+	Status          string  `json:"status"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Message         string  `json:"message,omitempty"`
+}
+
+// WorkerLoad is the predicted and actual busy time of a single worker in a run
+type WorkerLoad struct {
+	WorkerID    int
+	Files       int
+	Predicted   time.Duration
+	Actual      time.Duration
+	Utilization float64       // share of the execution's wall time the worker was busy (0-1)
+	IdleTail    time.Duration // time between the worker's last file and the end of the execution
+}
+
+// RunInfo identifies a run in the run history
+type RunInfo struct {
+	RunID     string   `json:"run_id,omitempty"`
+	GitCommit string   `json:"git_commit,omitempty"`
+	GitBranch string   `json:"git_branch,omitempty"`
+	Shard     string   `json:"shard,omitempty"` // "i/n" when the run was one shard of a CI split (see --shard)
+	Args      []string `json:"args,omitempty"`  // command-line arguments of the run
 }
 
 // TestResultsMeta contains metadata about a test run
 type TestResultsMeta struct {
-	TotalTestFiles  int     `json:"total_test_files"`
-	FailedTestFiles int     `json:"failed_test_files"`
-	PassedTestFiles int     `json:"passed_test_files"`
-	FailedTestCases int     `json:"failed_test_cases"`
-	Duration        string  `json:"duration"`
-	DurationSeconds float64 `json:"duration_seconds"`
-	Workers         int     `json:"workers"`
-	Timestamp       string  `json:"timestamp"`
+	RunInfo
+	TotalTestFiles   int     `json:"total_test_files"`
+	FailedTestFiles  int     `json:"failed_test_files"`
+	PassedTestFiles  int     `json:"passed_test_files"`
+	FailedTestCases  int     `json:"failed_test_cases"`
+	TotalTestCases   int     `json:"total_test_cases,omitempty"`
+	SkippedTestCases int     `json:"skipped_test_cases,omitempty"`
+	InterruptedFiles int     `json:"interrupted_test_files,omitempty"`
+	TimedOutFiles    int     `json:"timed_out_test_files,omitempty"`
+	FlakyTestCases   int     `json:"flaky_test_cases,omitempty"`
+	Duration         string  `json:"duration"`
+	DurationSeconds  float64 `json:"duration_seconds"`
+	Workers          int     `json:"workers"`
+	Timestamp        string  `json:"timestamp"`
 }
 
 // TestTiming tracks the running average execution time for a test file.
@@ -29,10 +113,61 @@ type TestTiming struct {
 	Avg   float64 `json:"avg"`
 }
 
+// TestFileResult is the stored outcome of one test file in a run
+type TestFileResult struct {
+	Path            string           `json:"path"`
+	Success         bool             `json:"success"`
+	DurationSeconds float64          `json:"duration_seconds"`
+	WorkerID        int              `json:"worker_id,omitempty"`
+	Interrupted     bool             `json:"interrupted,omitempty"`
+	TimedOut        bool             `json:"timed_out,omitempty"`
+	Cases           []TestCaseResult `json:"cases,omitempty"`
+}
+
 // TestResultsOutput is the complete output structure for test results
 type TestResultsOutput struct {
 	Meta    TestResultsMeta        `json:"meta"`
 	Details []TestFailure          `json:"details"`
+	Files   []TestFileResult       `json:"files,omitempty"`
 	Timings map[string]*TestTiming `json:"timings,omitempty"`
+	// FlakyHistory counts, per test case ("path::test"), the runs in which it was flaky
+	FlakyHistory map[string]*FlakyStats `json:"flaky_history,omitempty"`
+	// Timeline is when each worker ran which file during the execution, by worker and start
+	Timeline []WorkerSpan `json:"timeline,omitempty"`
+}
+
+// FlakyStats tracks how often a test case turned out flaky across runs
+type FlakyStats struct {
+	FilePath  string `json:"file_path"`
+	TestName  string `json:"test_name"`
+	Count     int    `json:"count"`
+	FirstSeen string `json:"first_seen"`
+	LastSeen  string `json:"last_seen"`
 }
 
+// Summarize sets the file and test case counts of the metadata from the files and failures
+func (o *TestResultsOutput) Summarize() {
+	m := &o.Meta
+	m.TotalTestFiles, m.PassedTestFiles, m.FailedTestFiles = len(o.Files), 0, 0
+	m.InterruptedFiles, m.TimedOutFiles, m.TotalTestCases, m.SkippedTestCases = 0, 0, 0, 0
+	for _, f := range o.Files {
+		if f.TimedOut {
+			m.TimedOutFiles++
+		}
+		if f.Interrupted {
+			m.InterruptedFiles++
+		} else if f.Success {
+			m.PassedTestFiles++
+		} else {
+			m.FailedTestFiles++
+		}
+		m.TotalTestCases += len(f.Cases)
+		for _, c := range f.Cases {
+			if c.Status == CaseSkipped {
+				m.SkippedTestCases++
+			}
+		}
+	}
+	m.FlakyTestCases = CountFlaky(o.Details)
+	m.FailedTestCases = len(o.Details) - m.FlakyTestCases
+}
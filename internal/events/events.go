@@ -0,0 +1,201 @@
+// Package events writes the progress of `ptp run --format=ndjson` as a stream of JSON events, one
+// per line, for editor plugins and dashboards.
+//
+// Every event has "version" (SchemaVersion), "event" (its type) and "time" (RFC 3339 with
+// nanoseconds). The types and their other fields are:
+//
+//	run_started     tests (test files to run), test_cases, workers, shard, args
+//	worker_started  worker, remote (the agent slot of a remote worker, absent for local ones)
+//	file_started    worker, file, chunk and chunks (for a chunk of a split file)
+//	file_finished   worker, file, result: path, success, duration_seconds, worker_id,
+//	                interrupted, timed_out, timeout_seconds, error and cases (name, class, file,
+//	                line, status, duration_seconds, message)
+//	case_failed     worker, file, failure: test_name, file_path, message, error_details,
+//	                stack_trace, file, line (as in test-results.json)
+//	run_finished    status (passed, failed, interrupted, no_tests or error), results (the path of
+//	                the stored results) and meta (the "meta" object of test-results.json); error
+//	                (the message) when the status is error
+//
+// case_failed follows the file_finished of the failed file, before retries: the final failures,
+// without the flaky ones, are counted in run_finished. Every stream starts with run_started and
+// ends with run_finished: a run that stops before its tests are known (nothing to run, or an error)
+// has a run_started without counts and a run_finished without results and meta. Fields are only
+// added within a version; a change that removes or redefines fields increments SchemaVersion.
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"ptp/internal/domain"
+	"ptp/internal/execution"
+	"ptp/internal/parser"
+)
+
+// SchemaVersion is the version of the event schema, in the "version" field of every event
+const SchemaVersion = 1
+
+// Event types
+const (
+	RunStarted    = "run_started"
+	WorkerStarted = "worker_started"
+	FileStarted   = "file_started"
+	FileFinished  = "file_finished"
+	CaseFailed    = "case_failed"
+	RunFinished   = "run_finished"
+)
+
+// Run statuses of run_finished
+const (
+	StatusPassed      = "passed"
+	StatusFailed      = "failed"
+	StatusInterrupted = "interrupted"
+	StatusNoTests     = "no_tests" // nothing matched the selection, so no test ran
+	StatusError       = "error"    // the run failed before it had results
+)
+
+// Event is a line of the stream; only the fields of its type are set
+type Event struct {
+	Version   int                     `json:"version"`
+	Type      string                  `json:"event"`
+	Time      string                  `json:"time"`
+	Tests     int                     `json:"tests,omitempty"`
+	TestCases int                     `json:"test_cases,omitempty"`
+	Workers   int                     `json:"workers,omitempty"`
+	Shard     string                  `json:"shard,omitempty"`
+	Args      []string                `json:"args,omitempty"`
+	Worker    int                     `json:"worker,omitempty"`
+	Remote    string                  `json:"remote,omitempty"`
+	File      string                  `json:"file,omitempty"`
+	Chunk     int                     `json:"chunk,omitempty"`
+	Chunks    int                     `json:"chunks,omitempty"`
+	Result    *FileResult             `json:"result,omitempty"`
+	Failure   *domain.TestFailure     `json:"failure,omitempty"`
+	Status    string                  `json:"status,omitempty"`
+	Results   string                  `json:"results,omitempty"`
+	Meta      *domain.TestResultsMeta `json:"meta,omitempty"`
+	Error     string                  `json:"error,omitempty"`
+}
+
+// FileResult is a domain.TestResult without the raw output and JUnit log
+type FileResult struct {
+	Path            string                  `json:"path"`
+	Success         bool                    `json:"success"`
+	DurationSeconds float64                 `json:"duration_seconds"`
+	WorkerID        int                     `json:"worker_id"`
+	Interrupted     bool                    `json:"interrupted,omitempty"`
+	TimedOut        bool                    `json:"timed_out,omitempty"`
+	TimeoutSeconds  float64                 `json:"timeout_seconds,omitempty"`
+	Error           string                  `json:"error,omitempty"`
+	Cases           []domain.TestCaseResult `json:"cases,omitempty"`
+}
+
+func newFileResult(r domain.TestResult) *FileResult {
+	res := &FileResult{
+		Path:            r.TestPath,
+		Success:         r.Success,
+		DurationSeconds: r.Duration.Seconds(),
+		WorkerID:        r.WorkerID,
+		Interrupted:     r.Interrupted,
+		TimedOut:        r.TimedOut,
+		TimeoutSeconds:  r.Timeout.Seconds(),
+		Cases:           r.Cases,
+	}
+	if r.Error != nil {
+		res.Error = r.Error.Error()
+	}
+	return res
+}
+
+// Writer writes the events of a run. It is the progress display of the worker pool (see
+// execution.Progress and execution.WorkerObserver), so it is safe for concurrent use.
+type Writer struct {
+	mu       sync.Mutex
+	enc      *json.Encoder
+	parser   parser.Parser
+	now      func() time.Time
+	started  bool
+	finished bool
+}
+
+// NewWriter creates a Writer writing to w; failures of failed files are parsed with resultParser
+func NewWriter(w io.Writer, resultParser parser.Parser) *Writer {
+	return &Writer{enc: json.NewEncoder(w), parser: resultParser, now: time.Now}
+}
+
+func (w *Writer) write(e Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	e.Version = SchemaVersion
+	e.Time = w.now().Format(time.RFC3339Nano)
+	switch e.Type {
+	case RunStarted:
+		w.started = true
+	case RunFinished:
+		w.finished = true
+	}
+	// A consumer that went away must not stop the run
+	_ = w.enc.Encode(e)
+}
+
+// RunStarted writes run_started
+func (w *Writer) RunStarted(tests, testCases, workers int, shard string, args []string) {
+	w.write(Event{Type: RunStarted, Tests: tests, TestCases: testCases, Workers: workers, Shard: shard, Args: args})
+}
+
+// RunFinished writes run_finished with the stored results of the run
+func (w *Writer) RunFinished(status, resultsPath string, meta domain.TestResultsMeta) {
+	w.write(Event{Type: RunFinished, Status: status, Results: resultsPath, Meta: &meta})
+}
+
+// RunEnded closes the stream of a run that stopped without RunFinished: it writes run_started when
+// the run stopped before it, and run_finished with status and the message of err (may be nil).
+// Streams that already finished are left alone.
+func (w *Writer) RunEnded(status string, err error) {
+	w.mu.Lock()
+	started, finished := w.started, w.finished
+	w.mu.Unlock()
+	if finished {
+		return
+	}
+	if !started {
+		w.write(Event{Type: RunStarted})
+	}
+	e := Event{Type: RunFinished, Status: status}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	w.write(e)
+}
+
+// WorkerStarted writes worker_started
+func (w *Writer) WorkerStarted(workerID int, remote string) {
+	w.write(Event{Type: WorkerStarted, Worker: workerID, Remote: remote})
+}
+
+// JobStarted writes file_started
+func (w *Writer) JobStarted(workerID int, job execution.Job) {
+	w.write(Event{Type: FileStarted, Worker: workerID, File: job.Path, Chunk: job.Chunk, Chunks: job.Chunks})
+}
+
+// JobFinished is part of execution.WorkerObserver; file_finished is written once per file by FileFinished
+func (w *Writer) JobFinished(workerID int) {}
+
+// FileFinished writes file_finished and a case_failed per failure of a failed file
+func (w *Writer) FileFinished(result domain.TestResult) {
+	w.write(Event{Type: FileFinished, Worker: result.WorkerID, File: result.TestPath, Result: newFileResult(result)})
+	if result.Success || result.Interrupted || w.parser == nil {
+		return
+	}
+	for _, f := range w.parser.ParseFailure(result) {
+		w.write(Event{Type: CaseFailed, Worker: result.WorkerID, File: result.TestPath, Failure: &f})
+	}
+}
+
+// Update is part of execution.Progress; the counts can be summed from file_finished
+func (w *Writer) Update(filesCompleted, passedCases, failedCases int) {}
+
+// Finish is part of execution.Progress; the run ends with run_finished
+func (w *Writer) Finish() {}
@@ -0,0 +1,94 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"ptp/internal/domain"
+	"ptp/internal/execution"
+)
+
+type stubParser struct {
+	failures []domain.TestFailure
+}
+
+func (p stubParser) ParseFailure(domain.TestResult) []domain.TestFailure { return p.failures }
+
+func (p stubParser) ParseCases(domain.TestResult) []domain.TestCaseResult { return nil }
+
+func (p stubParser) ParseTestCounts(domain.TestResult) (passed, failed int) { return 0, 0 }
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, stubParser{failures: []domain.TestFailure{{TestName: "testCreate", FilePath: "tests/UserTest.php", Message: "boom"}}})
+	w.now = func() time.Time { return time.Date(2026, 1, 14, 9, 32, 0, 0, time.UTC) }
+
+	w.RunStarted(2, 5, 2, "1/2", []string{"run"})
+	w.WorkerStarted(1, "")
+	w.JobStarted(1, execution.Job{Path: "tests/UserTest.php"})
+	w.FileFinished(domain.TestResult{TestPath: "tests/UserTest.php", WorkerID: 1, Duration: 1500 * time.Millisecond, Output: "raw", Error: errors.New("exit status 1")})
+	w.FileFinished(domain.TestResult{TestPath: "tests/OrderTest.php", WorkerID: 2, Success: true})
+	w.RunFinished(StatusFailed, "storage/test-results.json", domain.TestResultsMeta{FailedTestFiles: 1})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		`{"version":1,"event":"run_started","time":"2026-01-14T09:32:00Z","tests":2,"test_cases":5,"workers":2,"shard":"1/2","args":["run"]}`,
+		`{"version":1,"event":"worker_started","time":"2026-01-14T09:32:00Z","worker":1}`,
+		`{"version":1,"event":"file_started","time":"2026-01-14T09:32:00Z","worker":1,"file":"tests/UserTest.php"}`,
+		`{"version":1,"event":"file_finished","time":"2026-01-14T09:32:00Z","worker":1,"file":"tests/UserTest.php","result":{"path":"tests/UserTest.php","success":false,"duration_seconds":1.5,"worker_id":1,"error":"exit status 1"}}`,
+		`"event":"case_failed"`,
+		`"event":"file_finished","time":"2026-01-14T09:32:00Z","worker":2`,
+		`"event":"run_finished","time":"2026-01-14T09:32:00Z","status":"failed","results":"storage/test-results.json"`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d events, got %d:\n%s", len(want), len(lines), buf.String())
+	}
+	for i, line := range lines {
+		if !strings.Contains(line, want[i]) {
+			t.Errorf("event %d: expected %s, got %s", i, want[i], line)
+		}
+		var e Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Errorf("event %d is not valid JSON: %v", i, err)
+		}
+	}
+
+	var failed Event
+	if err := json.Unmarshal([]byte(lines[4]), &failed); err != nil {
+		t.Fatal(err)
+	}
+	if failed.Failure == nil || failed.Failure.TestName != "testCreate" || failed.File != "tests/UserTest.php" {
+		t.Errorf("unexpected case_failed event: %s", lines[4])
+	}
+}
+
+func TestWriter_RunEnded(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, nil)
+	w.now = func() time.Time { return time.Date(2026, 1, 14, 9, 32, 0, 0, time.UTC) }
+
+	w.RunEnded(StatusError, errors.New("--changed: unknown revision"))
+	w.RunEnded(StatusError, errors.New("ignored"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		`{"version":1,"event":"run_started","time":"2026-01-14T09:32:00Z"}`,
+		`{"version":1,"event":"run_finished","time":"2026-01-14T09:32:00Z","status":"error","error":"--changed: unknown revision"}`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected events:\n%s\nwant\n%s", buf.String(), strings.Join(want, "\n"))
+	}
+
+	buf.Reset()
+	w = NewWriter(&buf, nil)
+	w.RunStarted(1, 1, 1, "", nil)
+	w.RunFinished(StatusFailed, "storage/test-results.json", domain.TestResultsMeta{})
+	w.RunEnded(StatusError, errors.New("1 test case(s) failed"))
+	if n := strings.Count(buf.String(), "\n"); n != 2 {
+		t.Errorf("expected RunEnded to leave a finished stream alone, got %d events:\n%s", n, buf.String())
+	}
+}
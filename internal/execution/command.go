@@ -0,0 +1,84 @@
+package execution
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"ptp/internal/config"
+	"ptp/internal/debug"
+	"ptp/internal/domain"
+	"ptp/internal/proc"
+)
+
+// logDirName is the directory, next to the output JSON file, holding the JUnit logs of the runner
+// command: unlike the temp directory, it is inside the project and so visible to a container.
+const logDirName = "ptp-junit"
+
+// CommandRunner executes tests through the configured runner command template, e.g. in a
+// container with `docker compose exec`. Paths are translated with the configured path mappings:
+// the file and logs are passed as container paths, and container paths in the output and logs
+// are turned back into host paths.
+type CommandRunner struct {
+	config *config.Config
+}
+
+// NewCommandRunner creates a new CommandRunner
+func NewCommandRunner(cfg *config.Config) *CommandRunner {
+	return &CommandRunner{config: cfg}
+}
+
+// Run executes the runner command for a single test file
+func (r *CommandRunner) Run(ctx context.Context, testPath string, workerID int) domain.TestResult {
+	return r.run(ctx, testPath, "", workerID)
+}
+
+// RunFiltered executes the runner command for a single test file with --filter
+func (r *CommandRunner) RunFiltered(ctx context.Context, testPath string, filter string, workerID int) domain.TestResult {
+	return r.run(ctx, testPath, filter, workerID)
+}
+
+// SupportsCaseFilter is false for Pest, as for the local runner
+func (r *CommandRunner) SupportsCaseFilter() bool {
+	return supportsCaseFilter(r.config)
+}
+
+func (r *CommandRunner) run(ctx context.Context, testPath string, filter string, workerID int) domain.TestResult {
+	logPath, err := newLogFile(filepath.Join(filepath.Dir(r.config.GetOutputPath()), logDirName), workerID)
+	if err == nil {
+		defer os.Remove(logPath)
+	} else {
+		debug.Logf("runner[w%d]: could not create junit log file: %v", workerID, err)
+	}
+	words := r.command(testPath, filter, workerID, logPath, newCoverageFragment(r.config, workerID))
+
+	result := execute(ctx, r.config, testPath, workerID, logPath, func(runCtx context.Context) *exec.Cmd {
+		debug.Logf("runner[w%d]: exec %v (dir=%s)", workerID, words, r.config.ProjectPath)
+		return proc.Command(runCtx, words[0], words[1:]...)
+	})
+	result.Output = r.config.ToHost(result.Output)
+	if len(result.Log) > 0 {
+		result.Log = []byte(r.config.ToHost(string(result.Log)))
+	}
+	return result
+}
+
+// command expands the template for a run (see config.ExpandCommand)
+func (r *CommandRunner) command(testPath, filter string, workerID int, logPath, coverageFragment string) []string {
+	cfg := r.config
+	file := cfg.ToContainer(testPath)
+	configPath := cfg.GetPHPUnitConfigPath()
+	if configPath != "" {
+		configPath = cfg.ToContainer(configPath)
+	}
+	args := testArgs(cfg, configPath, filter)
+	if logPath != "" {
+		args = append(args, "--log-junit", cfg.ToContainer(logPath))
+	}
+	if coverageFragment != "" {
+		args = append(args, cfg.CoverageOption(), cfg.ToContainer(coverageFragment))
+	}
+
+	return cfg.ExpandCommand(cfg.RunnerCommand, workerID, file, args)
+}
@@ -0,0 +1,80 @@
+package execution
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"ptp/internal/config"
+)
+
+func TestCommandRunner_Command(t *testing.T) {
+	cfg := config.New()
+	cfg.ProjectPath = t.TempDir()
+	cfg.RunnerCommand = []string{"docker", "compose", "exec", "-T", "-e", "DB_DATABASE={{db}}", "app", "vendor/bin/phpunit", "{{file}}", "{{args}}"}
+	cfg.PathMappings = []config.PathMapping{{Host: ".", Container: "/app"}}
+	cfg.Flags.Group = "slow"
+	r := NewCommandRunner(cfg)
+
+	log := filepath.Join(cfg.ProjectPath, "storage", "ptp-junit", "w2.xml")
+	got := strings.Join(r.command("tests/UserTest.php", "testCreate", 2, log, ""), " ")
+	want := "docker compose exec -T -e DB_DATABASE=" + cfg.GetDatabaseName(2) + " app vendor/bin/phpunit /app/tests/UserTest.php" +
+		" --filter testCreate --group slow --log-junit /app/storage/ptp-junit/w2.xml"
+	if got != want {
+		t.Errorf("command() =\n%s\nwant\n%s", got, want)
+	}
+
+	cfg.RunnerCommand = []string{"ssh", "box", "phpunit"}
+	got = strings.Join(r.command("tests/UserTest.php", "", 1, "", ""), " ")
+	if want := "ssh box phpunit /app/tests/UserTest.php --group slow"; got != want {
+		t.Errorf("expected the file and arguments to be appended, got %q", got)
+	}
+
+	cfg.CoverageDir = filepath.Join(cfg.ProjectPath, "storage", "ptp-coverage")
+	cfg.CoverageFragment = config.CoverageFragmentClover
+	got = strings.Join(r.command("tests/UserTest.php", "", 1, "", filepath.Join(cfg.CoverageDir, "w1-1.xml")), " ")
+	if want := "ssh box phpunit /app/tests/UserTest.php --group slow --coverage-clover /app/storage/ptp-coverage/w1-1.xml"; got != want {
+		t.Errorf("expected the coverage fragment as a container path, got %q", got)
+	}
+}
+
+func TestCommandRunner_TranslatesPaths(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the runner command")
+	}
+	dir := t.TempDir()
+	// The fake container sees the project under /app: it writes the JUnit log through the real
+	// directory and reports container paths, as PHPUnit in a container would
+	script := `#!/bin/sh
+file=$1
+log=$(echo "$3" | sed "s#^/app#` + dir + `#")
+echo "<testsuites><testsuite file=\"$file\"><testcase name=\"testCreate\" file=\"$file\" line=\"7\"/></testsuite></testsuites>" > "$log"
+echo "FAILURES! at $file:7"
+exit 1
+`
+	if err := os.WriteFile(filepath.Join(dir, "container"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.New()
+	cfg.ProjectPath = dir
+	cfg.RunnerCommand = []string{filepath.Join(dir, "container")}
+	cfg.PathMappings = []config.PathMapping{{Host: ".", Container: "/app"}}
+
+	result := NewRunner(cfg).Run(context.Background(), "tests/UserTest.php", 1)
+	if result.Success || result.TestPath != "tests/UserTest.php" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	hostFile := filepath.ToSlash(dir) + "/tests/UserTest.php"
+	if !strings.Contains(result.Output, "at "+hostFile+":7") {
+		t.Errorf("expected host paths in the output, got %q", result.Output)
+	}
+	if !strings.Contains(string(result.Log), `file="`+hostFile+`"`) || strings.Contains(string(result.Log), "/app/") {
+		t.Errorf("expected host paths in the log, got %q", result.Log)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dir, "storage", logDirName)); len(entries) != 0 {
+		t.Errorf("expected the log to be removed, found %d file(s)", len(entries))
+	}
+}
@@ -0,0 +1,132 @@
+package execution
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"ptp/internal/domain"
+)
+
+// DefaultCaseEstimate is the assumed duration of a single test case when there is no history at all
+const DefaultCaseEstimate = 200 * time.Millisecond
+
+// CaseCounter lists the test cases of a file (implemented by discovery.Parser)
+type CaseCounter interface {
+	FindTestCases(filePath string) ([]string, error)
+}
+
+// Estimator predicts how long a test file takes. Files with history use their running
+// average from storage; unseen files are estimated from their test-case count times the
+// average per-case duration learned from the files that do have history.
+type Estimator struct {
+	timings map[string]*domain.TestTiming
+	counter CaseCounter
+
+	perCaseOnce sync.Once
+	perCase     time.Duration
+
+	mu    sync.Mutex
+	cache map[string]time.Duration
+}
+
+// NewEstimator creates an Estimator from historical timings (may be nil) and a case counter (may be nil)
+func NewEstimator(timings map[string]*domain.TestTiming, counter CaseCounter) *Estimator {
+	return &Estimator{
+		timings: timings,
+		counter: counter,
+		cache:   make(map[string]time.Duration),
+	}
+}
+
+// Estimate returns the predicted duration of a test file
+func (e *Estimator) Estimate(test string) time.Duration {
+	if e == nil {
+		return 0
+	}
+	if t, ok := e.timings[test]; ok && t != nil && t.Count > 0 {
+		return secondsToDuration(t.Avg)
+	}
+
+	e.mu.Lock()
+	d, ok := e.cache[test]
+	e.mu.Unlock()
+	if ok {
+		return d
+	}
+
+	// Parse the file without holding the lock, so concurrent callers (the dashboard
+	// ETA) never wait on disk reads of other files
+	cases := e.countCases(test)
+	if cases == 0 {
+		cases = 1
+	}
+	d = time.Duration(cases) * e.averagePerCase()
+
+	e.mu.Lock()
+	e.cache[test] = d
+	e.mu.Unlock()
+	return d
+}
+
+// EstimateJob returns the predicted duration of a job (a chunk's own estimate, or its file's)
+func (e *Estimator) EstimateJob(job Job) time.Duration {
+	if job.Estimate > 0 {
+		return job.Estimate
+	}
+	return e.Estimate(job.Path)
+}
+
+// HasHistory reports whether the estimate for test comes from recorded timings
+func (e *Estimator) HasHistory(test string) bool {
+	if e == nil {
+		return false
+	}
+	t, ok := e.timings[test]
+	return ok && t != nil && t.Count > 0
+}
+
+// averagePerCase returns the average per-case duration learned from the files with
+// history, computed on first use. Files that no longer exist are skipped.
+func (e *Estimator) averagePerCase() time.Duration {
+	e.perCaseOnce.Do(e.learnPerCase)
+	return e.perCase
+}
+
+func (e *Estimator) learnPerCase() {
+	e.perCase = DefaultCaseEstimate
+	var totalSeconds float64
+	var totalCases int
+	for path, t := range e.timings {
+		if t == nil || t.Count == 0 {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		cases := e.countCases(path)
+		if cases == 0 {
+			continue
+		}
+		totalSeconds += t.Avg
+		totalCases += cases
+	}
+	if totalCases > 0 && totalSeconds > 0 {
+		e.perCase = secondsToDuration(totalSeconds / float64(totalCases))
+	}
+}
+
+func (e *Estimator) countCases(test string) int {
+	if e.counter == nil {
+		return 0
+	}
+	cases, err := e.counter.FindTestCases(test)
+	if err != nil {
+		return 0
+	}
+	return len(cases)
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
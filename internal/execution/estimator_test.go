@@ -0,0 +1,75 @@
+package execution
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ptp/internal/domain"
+)
+
+type fakeCounter map[string]int
+
+func (c fakeCounter) FindTestCases(filePath string) ([]string, error) {
+	n, ok := c[filePath]
+	if !ok {
+		return nil, fmt.Errorf("no such file: %s", filePath)
+	}
+	return make([]string, n), nil
+}
+
+func TestEstimator_Estimate(t *testing.T) {
+	known := filepath.Join(t.TempDir(), "KnownTest.php")
+	if err := os.WriteFile(known, []byte("<?php"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	timings := map[string]*domain.TestTiming{
+		known: {Count: 3, Avg: 2},
+	}
+
+	t.Run("uses history when available", func(t *testing.T) {
+		e := NewEstimator(timings, fakeCounter{known: 4})
+		if d := e.Estimate(known); d != 2*time.Second {
+			t.Errorf("expected 2s, got %s", d)
+		}
+		if !e.HasHistory(known) || e.HasHistory("unseen") {
+			t.Error("unexpected HasHistory result")
+		}
+	})
+
+	t.Run("estimates unseen files by case count", func(t *testing.T) {
+		// 2s over 4 cases => 500ms per case
+		e := NewEstimator(timings, fakeCounter{known: 4, "unseen": 6})
+		if d := e.Estimate("unseen"); d != 3*time.Second {
+			t.Errorf("expected 3s, got %s", d)
+		}
+	})
+
+	t.Run("skips history of deleted files", func(t *testing.T) {
+		deleted := filepath.Join(t.TempDir(), "DeletedTest.php")
+		gone := map[string]*domain.TestTiming{deleted: {Count: 1, Avg: 60}}
+		e := NewEstimator(gone, fakeCounter{deleted: 1, "unseen": 2})
+		if d := e.Estimate("unseen"); d != 2*DefaultCaseEstimate {
+			t.Errorf("expected %s, got %s", 2*DefaultCaseEstimate, d)
+		}
+	})
+
+	t.Run("falls back to default per-case estimate", func(t *testing.T) {
+		e := NewEstimator(nil, fakeCounter{"unseen": 5})
+		if d := e.Estimate("unseen"); d != 5*DefaultCaseEstimate {
+			t.Errorf("expected %s, got %s", 5*DefaultCaseEstimate, d)
+		}
+		if d := e.Estimate("missing"); d != DefaultCaseEstimate {
+			t.Errorf("expected %s for unreadable file, got %s", DefaultCaseEstimate, d)
+		}
+	})
+
+	t.Run("nil estimator", func(t *testing.T) {
+		var e *Estimator
+		if d := e.Estimate("anything"); d != 0 {
+			t.Errorf("expected 0, got %s", d)
+		}
+	})
+}
@@ -1,12 +1,13 @@
 package execution
 
 import (
+	"context"
 	"ptp/internal/domain"
 	"time"
 )
 
 // Executor executes tests and returns results
 type Executor interface {
-	Execute(tests []string) ([]domain.TestResult, time.Duration, error)
+	Execute(ctx context.Context, tests []string) ([]domain.TestResult, time.Duration, error)
 }
 
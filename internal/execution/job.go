@@ -0,0 +1,33 @@
+package execution
+
+import (
+	"fmt"
+	"time"
+)
+
+// Job is a unit of work for a worker: a whole test file, or one chunk of its test cases
+// selected with a PHPUnit --filter when the file is split.
+type Job struct {
+	Path     string
+	Filter   string        // PHPUnit --filter pattern ("" runs the whole file)
+	Chunk    int           // 1-based chunk index, 0 when the file is not split
+	Chunks   int           // number of chunks the file was split into
+	Estimate time.Duration // predicted duration of the chunk (0 = estimate the whole file)
+}
+
+// String returns a readable job name (the path, plus the chunk for split files)
+func (j Job) String() string {
+	if j.Chunks == 0 {
+		return j.Path
+	}
+	return fmt.Sprintf("%s [%d/%d]", j.Path, j.Chunk, j.Chunks)
+}
+
+// fileJobs wraps whole test files as jobs
+func fileJobs(tests []string) []Job {
+	jobs := make([]Job, len(tests))
+	for i, t := range tests {
+		jobs[i] = Job{Path: t}
+	}
+	return jobs
+}
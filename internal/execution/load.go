@@ -0,0 +1,67 @@
+package execution
+
+import (
+	"container/heap"
+	"time"
+
+	"ptp/internal/domain"
+)
+
+// WorkerLoads compares the predicted per-worker load of a plan with the actual load
+// recorded in results. For a shared queue the prediction simulates workers pulling the
+// next test as soon as they become free. With the wall time of the execution, the loads also
+// tell how busy each worker was and how long it sat idle at the end (from the results' spans).
+func WorkerLoads(plan [][]Job, workerCount int, results []domain.TestResult, estimator *Estimator, wall time.Duration) []domain.WorkerLoad {
+	if workerCount <= 0 {
+		workerCount = 1
+	}
+	loads := make([]domain.WorkerLoad, workerCount)
+	for i := range loads {
+		loads[i].WorkerID = i + 1
+	}
+
+	if len(plan) == 1 && workerCount > 1 {
+		h := make(loadHeap, workerCount)
+		for i := range h {
+			h[i] = &workerSlot{index: i}
+		}
+		heap.Init(&h)
+		for _, job := range plan[0] {
+			slot := h[0]
+			slot.load += estimator.EstimateJob(job)
+			loads[slot.index].Predicted = slot.load
+			heap.Fix(&h, 0)
+		}
+	} else {
+		for i, bucket := range plan {
+			if i >= workerCount {
+				break
+			}
+			for _, job := range bucket {
+				loads[i].Predicted += estimator.EstimateJob(job)
+			}
+		}
+	}
+
+	lastEnd := make([]time.Duration, workerCount)
+	for _, r := range results {
+		if r.WorkerID < 1 || r.WorkerID > workerCount {
+			continue
+		}
+		loads[r.WorkerID-1].Files++
+		loads[r.WorkerID-1].Actual += r.Duration
+		for _, s := range r.Spans {
+			if s.WorkerID >= 1 && s.WorkerID <= workerCount {
+				end := time.Duration(s.EndSeconds * float64(time.Second))
+				lastEnd[s.WorkerID-1] = max(lastEnd[s.WorkerID-1], end)
+			}
+		}
+	}
+	if wall > 0 {
+		for i := range loads {
+			loads[i].Utilization = min(loads[i].Actual.Seconds()/wall.Seconds(), 1)
+			loads[i].IdleTail = max(wall-lastEnd[i], 0)
+		}
+	}
+	return loads
+}
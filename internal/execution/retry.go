@@ -0,0 +1,190 @@
+package execution
+
+import (
+	"context"
+	"sync"
+
+	"ptp/internal/debug"
+	"ptp/internal/domain"
+)
+
+// Retry reruns the failed test cases of failed results up to retries times, stopping for a case as
+// soon as it passes. Cases that fail and then pass are marked flaky, and a result whose failing
+// cases all passed on a retry becomes successful. Every rerun is kept in TestResult.Retries so the
+// failures can be classified afterwards (see MarkFlaky). Interrupted and timed-out files are not retried.
+func (wp *WorkerPool) Retry(ctx context.Context, results []domain.TestResult, retries int) []domain.TestResult {
+	if retries <= 0 {
+		return results
+	}
+	var pending []int
+	for i, r := range results {
+		if !r.Success && !r.Interrupted && !r.TimedOut {
+			pending = append(pending, i)
+		}
+	}
+	if len(pending) == 0 {
+		return results
+	}
+
+	workerCount := min(max(wp.config.Processors, 1), len(pending))
+	debug.Logf("retry: retrying %d failed test file(s) up to %d time(s) on %d worker(s)", len(pending), retries, workerCount)
+
+	queue := make(chan int)
+	go func() {
+		defer close(queue)
+		for _, i := range pending {
+			select {
+			case <-ctx.Done():
+				return
+			case queue <- i:
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 1; w <= workerCount; w++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+			for i := range queue {
+				results[i] = wp.retryResult(ctx, results[i], retries, workerID)
+			}
+		}(w)
+	}
+	wg.Wait()
+	return results
+}
+
+// retryResult reruns the failing cases of one file. With per-case results the rerun is filtered to
+// the cases still failing; without them (PHPUnit died before writing its log) the whole file reruns.
+func (wp *WorkerPool) retryResult(ctx context.Context, result domain.TestResult, retries int, workerID int) domain.TestResult {
+	failing := failingCases(result.Cases)
+	caseLevel := len(failing) > 0 && wp.runner.SupportsCaseFilter()
+
+	for attempt := 1; attempt <= retries && ctx.Err() == nil; attempt++ {
+		var rerun domain.TestResult
+		if caseLevel {
+			rerun = wp.runner.RunFiltered(ctx, result.TestPath, includeFilter(failing), workerID)
+		} else {
+			rerun = wp.runner.Run(ctx, result.TestPath, workerID)
+		}
+		if wp.parser != nil {
+			rerun.Cases = wp.parser.ParseCases(rerun)
+		}
+		if rerun.Interrupted {
+			break
+		}
+		rerun.Log = nil // the parsed cases are all that is needed from a retry
+		result.Retries = append(result.Retries, rerun)
+
+		if !caseLevel {
+			if rerun.Success {
+				result.Success = true
+				failing = nil
+				break
+			}
+			continue
+		}
+
+		passed := make(map[string]bool)
+		for _, c := range rerun.Cases {
+			if c.Status == domain.CasePassed {
+				passed[c.Name] = true
+			}
+		}
+		var still []string
+		for _, name := range failing {
+			if !passed[name] {
+				still = append(still, name)
+			}
+		}
+		failing = still
+		if len(failing) == 0 {
+			break
+		}
+	}
+
+	// A case is flaky once it passed on a retry, or once the whole file did (Pest cannot filter cases)
+	if len(result.Cases) > 0 && len(result.Retries) > 0 {
+		stillFailing := make(map[string]bool, len(failing))
+		for _, name := range failing {
+			stillFailing[name] = true
+		}
+		cases := make([]domain.TestCaseResult, len(result.Cases))
+		copy(cases, result.Cases)
+		for i, c := range cases {
+			if isFailedCase(c) && !stillFailing[c.Name] {
+				cases[i].Status = domain.CaseFlaky
+			}
+		}
+		result.Cases = cases
+	}
+	if caseLevel {
+		result.Success = len(failing) == 0 && len(result.Retries) > 0
+	}
+	debug.Logf("retry[w%d]: %s after %d retries: success=%v", workerID, result.TestPath, len(result.Retries), result.Success)
+	return result
+}
+
+// MarkFlaky classifies the failures parsed from a retried result: a failure whose case passed on a
+// retry is flagged flaky, and every failure gets the status of each attempt of its case.
+func MarkFlaky(result domain.TestResult, failures []domain.TestFailure) []domain.TestFailure {
+	if len(result.Retries) == 0 {
+		return failures
+	}
+	out := make([]domain.TestFailure, len(failures))
+	for i, f := range failures {
+		original, hasCase := findCase(result.Cases, f.TestName)
+		if hasCase {
+			f.Flaky = original.Status == domain.CaseFlaky
+			status := original.Status
+			if f.Flaky {
+				status = domain.CaseFailed
+			}
+			f.Attempts = []domain.TestAttempt{{Status: status, DurationSeconds: original.DurationSeconds, Message: original.Message}}
+		} else {
+			// No per-case data: the whole file was retried, so the file outcome decides
+			f.Flaky = result.Success
+			f.Attempts = []domain.TestAttempt{{Status: domain.CaseFailed, DurationSeconds: result.Duration.Seconds()}}
+		}
+		for _, rerun := range result.Retries {
+			if !hasCase {
+				status := domain.CaseFailed
+				if rerun.Success {
+					status = domain.CasePassed
+				}
+				f.Attempts = append(f.Attempts, domain.TestAttempt{Status: status, DurationSeconds: rerun.Duration.Seconds()})
+				continue
+			}
+			if c, ok := findCase(rerun.Cases, f.TestName); ok {
+				f.Attempts = append(f.Attempts, domain.TestAttempt{Status: c.Status, DurationSeconds: c.DurationSeconds, Message: c.Message})
+			}
+		}
+		out[i] = f
+	}
+	return out
+}
+
+// failingCases returns the names of the failed or errored cases
+func failingCases(cases []domain.TestCaseResult) []string {
+	var names []string
+	for _, c := range cases {
+		if isFailedCase(c) {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+func isFailedCase(c domain.TestCaseResult) bool {
+	return c.Status == domain.CaseFailed || c.Status == domain.CaseError
+}
+
+func findCase(cases []domain.TestCaseResult, name string) (domain.TestCaseResult, bool) {
+	for _, c := range cases {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return domain.TestCaseResult{}, false
+}
@@ -0,0 +1,148 @@
+package execution
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"ptp/internal/domain"
+)
+
+func TestFailingCases(t *testing.T) {
+	cases := []domain.TestCaseResult{
+		{Name: "testA", Status: domain.CasePassed},
+		{Name: "testB", Status: domain.CaseFailed},
+		{Name: "testC", Status: domain.CaseSkipped},
+		{Name: "testD", Status: domain.CaseError},
+		{Name: "testE", Status: domain.CaseFlaky},
+	}
+	got := failingCases(cases)
+	if want := []string{"testB", "testD"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("failingCases() = %v, want %v", got, want)
+	}
+}
+
+func TestMarkFlaky(t *testing.T) {
+	failures := []domain.TestFailure{
+		{FilePath: "tests/UserTest.php", TestName: "testFlaky"},
+		{FilePath: "tests/UserTest.php", TestName: "testBroken"},
+	}
+
+	t.Run("no retries leaves failures untouched", func(t *testing.T) {
+		got := MarkFlaky(domain.TestResult{}, failures)
+		if !reflect.DeepEqual(got, failures) {
+			t.Errorf("MarkFlaky() = %+v, want %+v", got, failures)
+		}
+	})
+
+	t.Run("case-level retries", func(t *testing.T) {
+		result := domain.TestResult{
+			Cases: []domain.TestCaseResult{
+				{Name: "testFlaky", Status: domain.CaseFlaky, DurationSeconds: 1, Message: "boom"},
+				{Name: "testBroken", Status: domain.CaseFailed, DurationSeconds: 2, Message: "broken"},
+			},
+			Retries: []domain.TestResult{
+				{Cases: []domain.TestCaseResult{
+					{Name: "testFlaky", Status: domain.CaseFailed, DurationSeconds: 1, Message: "boom again"},
+					{Name: "testBroken", Status: domain.CaseFailed, DurationSeconds: 2, Message: "broken"},
+				}},
+				{Cases: []domain.TestCaseResult{
+					{Name: "testFlaky", Status: domain.CasePassed, DurationSeconds: 1},
+					{Name: "testBroken", Status: domain.CaseFailed, DurationSeconds: 2, Message: "broken"},
+				}},
+			},
+		}
+		got := MarkFlaky(result, failures)
+
+		if !got[0].Flaky || got[1].Flaky {
+			t.Fatalf("expected only testFlaky to be flaky, got %v/%v", got[0].Flaky, got[1].Flaky)
+		}
+		wantStatuses := []string{domain.CaseFailed, domain.CaseFailed, domain.CasePassed}
+		if statuses := attemptStatuses(got[0]); !reflect.DeepEqual(statuses, wantStatuses) {
+			t.Errorf("testFlaky attempts = %v, want %v", statuses, wantStatuses)
+		}
+		if got[0].Attempts[1].Message != "boom again" {
+			t.Errorf("expected the retry message to be kept, got %q", got[0].Attempts[1].Message)
+		}
+		if len(got[1].Attempts) != 3 {
+			t.Errorf("expected 3 attempts for testBroken, got %d", len(got[1].Attempts))
+		}
+		if failures[0].Flaky {
+			t.Error("MarkFlaky modified its input")
+		}
+	})
+
+	t.Run("whole-file retry", func(t *testing.T) {
+		result := domain.TestResult{
+			Success:  true,
+			Duration: 3 * time.Second,
+			Retries: []domain.TestResult{
+				{Success: false, Duration: time.Second},
+				{Success: true, Duration: 2 * time.Second},
+			},
+		}
+		got := MarkFlaky(result, failures[:1])
+		if !got[0].Flaky {
+			t.Error("expected the failure to be flaky when the file passed on a retry")
+		}
+		wantStatuses := []string{domain.CaseFailed, domain.CaseFailed, domain.CasePassed}
+		if statuses := attemptStatuses(got[0]); !reflect.DeepEqual(statuses, wantStatuses) {
+			t.Errorf("attempts = %v, want %v", statuses, wantStatuses)
+		}
+	})
+
+	t.Run("whole-file retry with cases", func(t *testing.T) {
+		// Pest writes per-case results but cannot filter cases, so the whole file reruns
+		pest := &wholeFileRunner{rerun: domain.TestResult{
+			TestPath: "tests/UserTest.php",
+			Success:  true,
+			Duration: time.Second,
+			Cases:    []domain.TestCaseResult{{Name: "testFlaky", Status: domain.CasePassed, DurationSeconds: 1}},
+		}}
+		pool := &WorkerPool{runner: pest}
+		result := pool.retryResult(context.Background(), domain.TestResult{
+			TestPath: "tests/UserTest.php",
+			Duration: time.Second,
+			Cases:    []domain.TestCaseResult{{Name: "testFlaky", Status: domain.CaseFailed, DurationSeconds: 1, Message: "boom"}},
+		}, 2, 1)
+		if !result.Success || pest.runs != 1 || pest.filtered != 0 {
+			t.Fatalf("expected one whole-file rerun that passed, got success=%v runs=%d filtered=%d", result.Success, pest.runs, pest.filtered)
+		}
+
+		got := MarkFlaky(result, failures[:1])
+		if !got[0].Flaky {
+			t.Error("expected the failure to be flaky when the file passed on a whole-file retry")
+		}
+		wantStatuses := []string{domain.CaseFailed, domain.CasePassed}
+		if statuses := attemptStatuses(got[0]); !reflect.DeepEqual(statuses, wantStatuses) {
+			t.Errorf("attempts = %v, want %v", statuses, wantStatuses)
+		}
+	})
+}
+
+// wholeFileRunner is a runner without case filters (as for Pest) whose reruns return rerun
+type wholeFileRunner struct {
+	rerun          domain.TestResult
+	runs, filtered int
+}
+
+func (r *wholeFileRunner) Run(ctx context.Context, testPath string, workerID int) domain.TestResult {
+	r.runs++
+	return r.rerun
+}
+
+func (r *wholeFileRunner) RunFiltered(ctx context.Context, testPath string, filter string, workerID int) domain.TestResult {
+	r.filtered++
+	return r.rerun
+}
+
+func (r *wholeFileRunner) SupportsCaseFilter() bool { return false }
+
+func attemptStatuses(f domain.TestFailure) []string {
+	statuses := make([]string, len(f.Attempts))
+	for i, a := range f.Attempts {
+		statuses[i] = a.Status
+	}
+	return statuses
+}
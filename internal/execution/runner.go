@@ -5,65 +5,191 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strings"
 	"time"
 
 	"ptp/internal/config"
 	"ptp/internal/debug"
 	"ptp/internal/domain"
+	"ptp/internal/proc"
 )
 
-// Runner executes a single PHPUnit test
-type Runner struct {
+// Runner executes single test files
+type Runner interface {
+	// Run executes a test file. Cancelling ctx kills the test process and its children and marks
+	// the result as interrupted.
+	Run(ctx context.Context, testPath string, workerID int) domain.TestResult
+	// RunFiltered runs a test file with --filter to run one test case (e.g. method name).
+	RunFiltered(ctx context.Context, testPath string, filter string, workerID int) domain.TestResult
+	// SupportsCaseFilter reports whether single test cases can be selected with the "::method"
+	// --filter patterns used for splitting and retries.
+	SupportsCaseFilter() bool
+}
+
+// NewRunner returns the runner configured for the project: the runner command template when one
+// is configured, the local test runner binary otherwise
+func NewRunner(cfg *config.Config) Runner {
+	if cfg.HasRunnerCommand() {
+		return NewCommandRunner(cfg)
+	}
+	return NewLocalRunner(cfg)
+}
+
+// LocalRunner executes PHPUnit (or Pest) from the project on this machine
+type LocalRunner struct {
 	config *config.Config
 }
 
-// NewRunner creates a new Runner
-func NewRunner(cfg *config.Config) *Runner {
-	return &Runner{config: cfg}
+// NewLocalRunner creates a new LocalRunner
+func NewLocalRunner(cfg *config.Config) *LocalRunner {
+	return &LocalRunner{config: cfg}
 }
 
 // Run executes PHPUnit for a single test file
-func (r *Runner) Run(testPath string, workerID int) domain.TestResult {
-	return r.run(testPath, "", workerID)
+func (r *LocalRunner) Run(ctx context.Context, testPath string, workerID int) domain.TestResult {
+	return r.run(ctx, testPath, "", workerID)
+}
+
+// RunFiltered runs PHPUnit for a single test file with --filter
+func (r *LocalRunner) RunFiltered(ctx context.Context, testPath string, filter string, workerID int) domain.TestResult {
+	return r.run(ctx, testPath, filter, workerID)
+}
+
+// SupportsCaseFilter is false for Pest: it names its test methods after the descriptions, so
+// Pest files always run whole.
+func (r *LocalRunner) SupportsCaseFilter() bool {
+	return supportsCaseFilter(r.config)
 }
 
-// RunFiltered runs PHPUnit for a single test file with --filter to run one test case (e.g. method name).
-func (r *Runner) RunFiltered(testPath string, filter string, workerID int) domain.TestResult {
-	return r.run(testPath, filter, workerID)
+func (r *LocalRunner) run(ctx context.Context, testPath string, filter string, workerID int) domain.TestResult {
+	runnerPath := r.config.GetTestRunnerPath()
+	args := append([]string{testPath}, testArgs(r.config, r.config.GetPHPUnitConfigPath(), filter)...)
+
+	// Each invocation gets its own JUnit log so results don't depend on PHPUnit's text output
+	logPath, err := newLogFile("", workerID)
+	if err == nil {
+		defer os.Remove(logPath)
+		args = append(args, "--log-junit", logPath)
+	} else {
+		debug.Logf("runner[w%d]: could not create junit log file: %v", workerID, err)
+	}
+	if fragment := newCoverageFragment(r.config, workerID); fragment != "" {
+		args = append(args, r.config.CoverageOption(), fragment)
+	}
+
+	return execute(ctx, r.config, testPath, workerID, logPath, func(runCtx context.Context) *exec.Cmd {
+		debug.Logf("runner[w%d]: exec %s %v (dir=%s, db=%s)", workerID, runnerPath, args, r.config.ProjectPath, r.config.GetDatabaseName(workerID))
+		return proc.Command(runCtx, runnerPath, args...)
+	})
 }
 
-func (r *Runner) run(testPath string, filter string, workerID int) domain.TestResult {
-	phpunitPath := r.config.GetPHPUnitPath()
-	ctx := context.Background()
-	args := []string{testPath}
+func supportsCaseFilter(cfg *config.Config) bool {
+	return cfg.GetFramework() != config.FrameworkPest
+}
+
+// testArgs returns the test runner arguments after the test file, except the JUnit log
+func testArgs(cfg *config.Config, configPath, filter string) []string {
+	var args []string
+	if configPath != "" {
+		args = append(args, "--configuration", configPath)
+	}
 	if filter != "" {
 		args = append(args, "--filter", filter)
 	}
-	cmd := exec.CommandContext(ctx, phpunitPath, args...)
+	include, exclude := cfg.GetGroups()
+	if len(include) > 0 {
+		args = append(args, "--group", strings.Join(include, ","))
+	}
+	if len(exclude) > 0 {
+		args = append(args, "--exclude-group", strings.Join(exclude, ","))
+	}
+	return args
+}
+
+// newCoverageFragment creates the file a PHPUnit invocation writes its coverage to, in the
+// coverage directory of the run, or returns "" when coverage is off. The fragments are merged
+// after the run (see coverage.Collector).
+func newCoverageFragment(cfg *config.Config, workerID int) string {
+	if cfg.CoverageDir == "" {
+		return ""
+	}
+	ext := ".xml"
+	if cfg.CoverageFragment == config.CoverageFragmentPHP {
+		ext = ".cov"
+	}
+	f, err := os.CreateTemp(cfg.CoverageDir, fmt.Sprintf("w%d-*%s", workerID, ext))
+	if err != nil {
+		debug.Logf("runner[w%d]: could not create coverage fragment: %v", workerID, err)
+		return ""
+	}
+	f.Close()
+	return f.Name()
+}
+
+// newLogFile creates an empty JUnit log file for a worker in dir ("" for the temp directory)
+func newLogFile(dir string, workerID int) (string, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", err
+		}
+	}
+	logFile, err := os.CreateTemp(dir, fmt.Sprintf("ptp-junit-w%d-*.xml", workerID))
+	if err != nil {
+		return "", err
+	}
+	logFile.Close()
+	return logFile.Name(), nil
+}
 
-	cmd.Env = os.Environ()
-	cmd.Env = append(cmd.Env, fmt.Sprintf("DB_DATABASE=%s", r.config.GetDatabaseName(0)))
-	cmd.Env = append(cmd.Env, fmt.Sprintf("TEST_TOKEN=%d", workerID))
+// execute runs the command for a test file in the worker's environment under the file's timeout
+// and collects the result, with the JUnit log read from logPath ("" for none)
+func execute(ctx context.Context, cfg *config.Config, testPath string, workerID int, logPath string, command func(runCtx context.Context) *exec.Cmd) domain.TestResult {
+	runCtx := ctx
+	timeout := cfg.TimeoutFor(testPath)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 
-	cmd.Dir = r.config.ProjectPath
+	cmd := command(runCtx)
 
-	debug.Logf("runner[w%d]: exec %s %v (dir=%s, db=%s)", workerID, phpunitPath, args, r.config.ProjectPath, r.config.GetDatabaseName(workerID))
+	cmd.Env = cfg.WorkerEnviron(workerID)
+
+	cmd.Dir = cfg.ProjectPath
 
 	st := time.Now()
 	output, err := cmd.CombinedOutput()
 	dur := time.Since(st)
 
-	if err != nil {
+	// A file that completed before the cancellation reached it keeps its real outcome
+	interrupted := err != nil && ctx.Err() != nil
+	timedOut := err != nil && !interrupted && runCtx.Err() == context.DeadlineExceeded
+	if interrupted {
+		debug.Logf("runner[w%d]: INTERRUPTED %s after %.2fs", workerID, testPath, dur.Seconds())
+	} else if timedOut {
+		debug.Logf("runner[w%d]: TIMEOUT %s after %s", workerID, testPath, timeout)
+	} else if err != nil {
 		debug.Logf("runner[w%d]: FAILED %s in %.2fs: %v", workerID, testPath, dur.Seconds(), err)
 	} else {
 		debug.Logf("runner[w%d]: PASSED %s in %.2fs", workerID, testPath, dur.Seconds())
 	}
 
+	var log []byte
+	if logPath != "" {
+		log, _ = os.ReadFile(logPath)
+	}
+
 	return domain.TestResult{
-		TestPath: testPath,
-		Success:  err == nil,
-		Output:   string(output),
-		Error:    err,
-		Duration: dur,
+		TestPath:    testPath,
+		Success:     err == nil,
+		Output:      string(output),
+		Error:       err,
+		Duration:    dur,
+		WorkerID:    workerID,
+		Log:         log,
+		Interrupted: interrupted,
+		TimedOut:    timedOut,
+		Timeout:     timeout,
 	}
 }
@@ -1,8 +1,37 @@
 package execution
 
-// Scheduler distributes tests across workers
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Scheduler names accepted by --scheduler
+const (
+	SchedulerQueue      = "queue"
+	SchedulerLPT        = "lpt"
+	SchedulerRoundRobin = "round-robin"
+)
+
+// Scheduler distributes jobs across workers.
+// A plan with a single bucket is a shared queue that all workers pull from;
+// otherwise bucket i is the fixed list of jobs for worker i+1.
 type Scheduler interface {
-	Schedule(tests []string, workerCount int) [][]string
+	Schedule(jobs []Job, workerCount int) [][]Job
+}
+
+// NewScheduler returns the scheduler registered under name
+func NewScheduler(name string, estimator *Estimator) (Scheduler, error) {
+	switch name {
+	case "", SchedulerQueue:
+		return NewQueueScheduler(estimator), nil
+	case SchedulerLPT:
+		return NewLPTScheduler(estimator), nil
+	case SchedulerRoundRobin:
+		return NewRoundRobinScheduler(), nil
+	}
+	return nil, fmt.Errorf("unknown scheduler %q (expected %s, %s or %s)", name, SchedulerQueue, SchedulerLPT, SchedulerRoundRobin)
 }
 
 // RoundRobinScheduler distributes tests evenly across workers
@@ -13,22 +42,124 @@ func NewRoundRobinScheduler() *RoundRobinScheduler {
 	return &RoundRobinScheduler{}
 }
 
-// Schedule distributes tests evenly across workers using round-robin
-func (s *RoundRobinScheduler) Schedule(tests []string, workerCount int) [][]string {
+// Schedule distributes jobs evenly across workers using round-robin
+func (s *RoundRobinScheduler) Schedule(jobs []Job, workerCount int) [][]Job {
 	if workerCount <= 0 {
 		workerCount = 1
 	}
 
-	distribution := make([][]string, workerCount)
+	distribution := make([][]Job, workerCount)
 	for i := range distribution {
-		distribution[i] = make([]string, 0)
+		distribution[i] = make([]Job, 0)
 	}
 
-	for i, test := range tests {
+	for i, job := range jobs {
 		workerIndex := i % workerCount
-		distribution[workerIndex] = append(distribution[workerIndex], test)
+		distribution[workerIndex] = append(distribution[workerIndex], job)
+	}
+
+	return distribution
+}
+
+// QueueScheduler puts all tests in one shared queue, slowest first, so the longest
+// files are picked up early and don't become a tail bottleneck.
+type QueueScheduler struct {
+	estimator *Estimator
+}
+
+// NewQueueScheduler creates a new QueueScheduler
+func NewQueueScheduler(estimator *Estimator) *QueueScheduler {
+	return &QueueScheduler{estimator: estimator}
+}
+
+// Schedule returns a single shared queue ordered by estimated duration (descending)
+func (s *QueueScheduler) Schedule(jobs []Job, workerCount int) [][]Job {
+	return [][]Job{sortByEstimate(jobs, s.estimator)}
+}
+
+// LPTScheduler assigns tests up front using the longest-processing-time rule:
+// tests are taken slowest first and each goes to the worker with the least estimated load.
+type LPTScheduler struct {
+	estimator *Estimator
+}
+
+// NewLPTScheduler creates a new LPTScheduler
+func NewLPTScheduler(estimator *Estimator) *LPTScheduler {
+	return &LPTScheduler{estimator: estimator}
+}
+
+// Schedule bin-packs jobs into workerCount buckets with balanced estimated load
+func (s *LPTScheduler) Schedule(jobs []Job, workerCount int) [][]Job {
+	if workerCount <= 0 {
+		workerCount = 1
+	}
+
+	distribution := make([][]Job, workerCount)
+	h := make(loadHeap, workerCount)
+	for i := range h {
+		distribution[i] = make([]Job, 0)
+		h[i] = &workerSlot{index: i}
+	}
+	heap.Init(&h)
+
+	for _, job := range sortByEstimate(jobs, s.estimator) {
+		slot := h[0]
+		distribution[slot.index] = append(distribution[slot.index], job)
+		slot.load += s.estimator.EstimateJob(job)
+		heap.Fix(&h, 0)
 	}
 
 	return distribution
 }
 
+// sortByEstimate returns a copy of jobs ordered by estimated duration, slowest first.
+// Ties keep discovery order so plans are deterministic.
+func sortByEstimate(jobs []Job, estimator *Estimator) []Job {
+	sorted := make([]Job, len(jobs))
+	copy(sorted, jobs)
+	if estimator == nil {
+		return sorted
+	}
+	estimates := make([]time.Duration, len(sorted))
+	for i, j := range sorted {
+		estimates[i] = estimator.EstimateJob(j)
+	}
+	idx := make([]int, len(sorted))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool {
+		return estimates[idx[a]] > estimates[idx[b]]
+	})
+	out := make([]Job, len(sorted))
+	for i, k := range idx {
+		out[i] = sorted[k]
+	}
+	return out
+}
+
+// workerSlot is a worker's accumulated load while building a plan
+type workerSlot struct {
+	index int
+	load  time.Duration
+}
+
+// loadHeap is a min-heap of worker slots by load (lowest index first on ties)
+type loadHeap []*workerSlot
+
+func (h loadHeap) Len() int { return len(h) }
+func (h loadHeap) Less(i, j int) bool {
+	if h[i].load == h[j].load {
+		return h[i].index < h[j].index
+	}
+	return h[i].load < h[j].load
+}
+func (h loadHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *loadHeap) Push(x interface{}) { *h = append(*h, x.(*workerSlot)) }
+func (h *loadHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
@@ -0,0 +1,117 @@
+package execution
+
+import (
+	"testing"
+	"time"
+
+	"ptp/internal/domain"
+)
+
+func timingsFor(avgs map[string]float64) map[string]*domain.TestTiming {
+	timings := make(map[string]*domain.TestTiming)
+	for path, avg := range avgs {
+		timings[path] = &domain.TestTiming{Count: 1, Avg: avg}
+	}
+	return timings
+}
+
+func TestNewScheduler(t *testing.T) {
+	for _, name := range []string{"", SchedulerQueue, SchedulerLPT, SchedulerRoundRobin} {
+		if _, err := NewScheduler(name, nil); err != nil {
+			t.Errorf("unexpected error for %q: %v", name, err)
+		}
+	}
+	if _, err := NewScheduler("random", nil); err == nil {
+		t.Error("expected error for unknown scheduler")
+	}
+}
+
+func TestRoundRobinScheduler_Schedule(t *testing.T) {
+	plan := NewRoundRobinScheduler().Schedule(fileJobs([]string{"a", "b", "c", "d", "e"}), 2)
+	if len(plan) != 2 || len(plan[0]) != 3 || len(plan[1]) != 2 {
+		t.Errorf("unexpected distribution: %v", plan)
+	}
+}
+
+func TestQueueScheduler_Schedule(t *testing.T) {
+	estimator := NewEstimator(timingsFor(map[string]float64{"fast": 1, "slow": 10, "medium": 5}), nil)
+	plan := NewQueueScheduler(estimator).Schedule(fileJobs([]string{"fast", "slow", "medium"}), 4)
+
+	if len(plan) != 1 {
+		t.Fatalf("expected a single shared queue, got %d buckets", len(plan))
+	}
+	expected := []string{"slow", "medium", "fast"}
+	for i, test := range expected {
+		if plan[0][i].Path != test {
+			t.Errorf("position %d: expected %s, got %s", i, test, plan[0][i].Path)
+		}
+	}
+}
+
+func TestLPTScheduler_Schedule(t *testing.T) {
+	estimator := NewEstimator(timingsFor(map[string]float64{
+		"a": 8, "b": 7, "c": 6, "d": 5, "e": 4,
+	}), nil)
+	plan := NewLPTScheduler(estimator).Schedule(fileJobs([]string{"e", "d", "c", "b", "a"}), 2)
+
+	if len(plan) != 2 {
+		t.Fatalf("expected 2 buckets, got %d", len(plan))
+	}
+
+	var loads [2]time.Duration
+	total := 0
+	for i, bucket := range plan {
+		for _, job := range bucket {
+			loads[i] += estimator.EstimateJob(job)
+			total++
+		}
+	}
+	if total != 5 {
+		t.Errorf("expected all 5 tests to be scheduled, got %d", total)
+	}
+	// LPT: a->w1, b->w2, c->w2, d->w1, e->w1 => 17s vs 13s
+	if loads[0] != 17*time.Second || loads[1] != 13*time.Second {
+		t.Errorf("unexpected loads: %v", loads)
+	}
+}
+
+func TestWorkerLoads(t *testing.T) {
+	estimator := NewEstimator(timingsFor(map[string]float64{"a": 4, "b": 3, "c": 2}), nil)
+	results := []domain.TestResult{
+		{TestPath: "a", WorkerID: 1, Duration: 5 * time.Second},
+		{TestPath: "b", WorkerID: 2, Duration: 3 * time.Second},
+		{TestPath: "c", WorkerID: 2, Duration: 2 * time.Second},
+	}
+
+	t.Run("shared queue simulates pulling", func(t *testing.T) {
+		loads := WorkerLoads([][]Job{fileJobs([]string{"a", "b", "c"})}, 2, results, estimator, 0)
+		if loads[0].Predicted != 4*time.Second || loads[1].Predicted != 5*time.Second {
+			t.Errorf("unexpected predicted loads: %+v", loads)
+		}
+		if loads[0].Actual != 5*time.Second || loads[1].Actual != 5*time.Second || loads[1].Files != 2 {
+			t.Errorf("unexpected actual loads: %+v", loads)
+		}
+	})
+
+	t.Run("per-worker plan", func(t *testing.T) {
+		loads := WorkerLoads([][]Job{fileJobs([]string{"a"}), fileJobs([]string{"b", "c"})}, 2, results, estimator, 0)
+		if loads[0].Predicted != 4*time.Second || loads[1].Predicted != 5*time.Second {
+			t.Errorf("unexpected predicted loads: %+v", loads)
+		}
+	})
+
+	t.Run("utilization and idle tail", func(t *testing.T) {
+		timed := []domain.TestResult{
+			{TestPath: "a", WorkerID: 1, Duration: 5 * time.Second, Spans: []domain.WorkerSpan{{WorkerID: 1, StartSeconds: 0, EndSeconds: 5}}},
+			{TestPath: "b", WorkerID: 2, Duration: 3 * time.Second, Spans: []domain.WorkerSpan{{WorkerID: 2, StartSeconds: 0, EndSeconds: 3}}},
+			{TestPath: "c", WorkerID: 2, Duration: 1 * time.Second, Spans: []domain.WorkerSpan{{WorkerID: 2, StartSeconds: 3, EndSeconds: 4}}},
+		}
+		loads := WorkerLoads([][]Job{fileJobs([]string{"a", "b", "c"})}, 2, timed, estimator, 5*time.Second)
+		if loads[0].Utilization != 1 || loads[1].Utilization != 0.8 {
+			t.Errorf("unexpected utilization: %+v", loads)
+		}
+		if loads[0].IdleTail != 0 || loads[1].IdleTail != time.Second {
+			t.Errorf("unexpected idle tails: %+v", loads)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package execution
+
+import (
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Shard is one of Total partitions of the test files (1-based Index), so CI machines can
+// split a suite between them with --shard=i/n
+type Shard struct {
+	Index int
+	Total int
+}
+
+// ParseShard parses an "i/n" shard specification
+func ParseShard(spec string) (Shard, error) {
+	i, n, ok := strings.Cut(strings.TrimSpace(spec), "/")
+	index, err1 := strconv.Atoi(strings.TrimSpace(i))
+	total, err2 := strconv.Atoi(strings.TrimSpace(n))
+	if !ok || err1 != nil || err2 != nil {
+		return Shard{}, fmt.Errorf("invalid shard %q (expected i/n, e.g. 2/4)", spec)
+	}
+	if total < 1 || index < 1 || index > total {
+		return Shard{}, fmt.Errorf("invalid shard %q (expected 1 <= i <= n)", spec)
+	}
+	return Shard{Index: index, Total: total}, nil
+}
+
+// String returns the shard as "i/n"
+func (s Shard) String() string {
+	return fmt.Sprintf("%d/%d", s.Index, s.Total)
+}
+
+// Select returns the tests of this shard. Every machine computes the same partition from the same
+// test list and timings: with recorded timings the files are balanced by the LPT rule over their
+// estimates, otherwise each file goes to the shard picked by a hash of its path, which keeps files
+// on their shard as others are added or removed.
+func (s Shard) Select(tests []string, estimator *Estimator) []string {
+	if s.Total <= 1 {
+		return tests
+	}
+	sorted := append([]string(nil), tests...)
+	sort.Strings(sorted)
+
+	var selected []string
+	if !anyHistory(sorted, estimator) {
+		for _, test := range sorted {
+			if shardByHash(test, s.Total) == s.Index-1 {
+				selected = append(selected, test)
+			}
+		}
+		return selected
+	}
+
+	estimates := make(map[string]time.Duration, len(sorted))
+	for _, test := range sorted {
+		estimates[test] = estimator.Estimate(test)
+	}
+	// Stable: equal estimates keep path order, so ties are broken the same way everywhere
+	sort.SliceStable(sorted, func(i, j int) bool { return estimates[sorted[i]] > estimates[sorted[j]] })
+	loads := make([]time.Duration, s.Total)
+	for _, test := range sorted {
+		shard := 0
+		for i := 1; i < len(loads); i++ {
+			if loads[i] < loads[shard] {
+				shard = i
+			}
+		}
+		loads[shard] += estimates[test]
+		if shard == s.Index-1 {
+			selected = append(selected, test)
+		}
+	}
+	sort.Strings(selected)
+	return selected
+}
+
+func anyHistory(tests []string, estimator *Estimator) bool {
+	for _, test := range tests {
+		if estimator.HasHistory(test) {
+			return true
+		}
+	}
+	return false
+}
+
+// shardByHash returns the 0-based shard of a test file from its slash-separated path
+func shardByHash(test string, total int) int {
+	h := fnv.New32a()
+	h.Write([]byte(filepath.ToSlash(filepath.Clean(test))))
+	return int(h.Sum32() % uint32(total))
+}
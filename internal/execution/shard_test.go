@@ -0,0 +1,73 @@
+package execution
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestParseShard(t *testing.T) {
+	shard, err := ParseShard("2/4")
+	if err != nil || shard != (Shard{Index: 2, Total: 4}) {
+		t.Errorf("ParseShard(2/4) = %v, %v", shard, err)
+	}
+	for _, spec := range []string{"", "2", "0/4", "5/4", "a/b", "1/0"} {
+		if _, err := ParseShard(spec); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+}
+
+func TestShard_Select_Partitions(t *testing.T) {
+	var tests []string
+	for i := 0; i < 40; i++ {
+		tests = append(tests, fmt.Sprintf("tests/Unit/Case%dTest.php", i))
+	}
+	for _, estimator := range []*Estimator{nil, NewEstimator(timingsFor(map[string]float64{tests[0]: 3, tests[1]: 1}), nil)} {
+		var all []string
+		for i := 1; i <= 3; i++ {
+			selected := Shard{Index: i, Total: 3}.Select(tests, estimator)
+			if len(selected) == 0 {
+				t.Errorf("shard %d/3 is empty", i)
+			}
+			all = append(all, selected...)
+		}
+		sort.Strings(all)
+		want := append([]string(nil), tests...)
+		sort.Strings(want)
+		if !reflect.DeepEqual(all, want) {
+			t.Errorf("shards do not partition the tests exactly once: got %d files", len(all))
+		}
+	}
+}
+
+func TestShard_Select_BalancesByTimings(t *testing.T) {
+	estimator := NewEstimator(timingsFor(map[string]float64{
+		"a": 8, "b": 7, "c": 6, "d": 5, "e": 4,
+	}), nil)
+	// Input order must not matter
+	first := Shard{Index: 1, Total: 2}.Select([]string{"e", "d", "c", "b", "a"}, estimator)
+	second := Shard{Index: 2, Total: 2}.Select([]string{"a", "b", "c", "d", "e"}, estimator)
+	if want := []string{"a", "d", "e"}; !reflect.DeepEqual(first, want) {
+		t.Errorf("shard 1/2 = %v, want %v", first, want)
+	}
+	if want := []string{"b", "c"}; !reflect.DeepEqual(second, want) {
+		t.Errorf("shard 2/2 = %v, want %v", second, want)
+	}
+}
+
+func TestShard_Select_HashIsStable(t *testing.T) {
+	shard := Shard{Index: 2, Total: 4}
+	before := shard.Select([]string{"tests/ATest.php", "tests/BTest.php", "tests/CTest.php", "tests/DTest.php"}, nil)
+	after := shard.Select([]string{"tests/ATest.php", "tests/BTest.php", "tests/CTest.php", "tests/DTest.php", "tests/NewTest.php"}, nil)
+	for _, test := range before {
+		found := false
+		for _, a := range after {
+			found = found || a == test
+		}
+		if !found {
+			t.Errorf("%s moved to another shard when a file was added", test)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package execution
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"ptp/internal/debug"
+	"ptp/internal/domain"
+	"ptp/internal/parser"
+)
+
+// MinSplitThreshold is the smallest automatic split threshold; below it the extra
+// PHPUnit bootstrap per chunk costs more than the parallelism gains.
+const MinSplitThreshold = 30 * time.Second
+
+// Splitter breaks genuinely heavy test files into chunks of test cases, run with
+// --filter, so a single large file doesn't pin one worker while the others idle.
+// Only files with recorded timings are split.
+type Splitter struct {
+	estimator *Estimator
+	counter   CaseCounter
+	threshold time.Duration
+}
+
+// NewSplitter creates a Splitter. Files whose historical duration reaches threshold are split;
+// a zero threshold means half of the ideal per-worker load (but at least MinSplitThreshold).
+func NewSplitter(estimator *Estimator, counter CaseCounter, threshold time.Duration) *Splitter {
+	return &Splitter{
+		estimator: estimator,
+		counter:   counter,
+		threshold: threshold,
+	}
+}
+
+// Split returns the jobs for tests: whole files, except heavy files which become several chunks
+func (s *Splitter) Split(tests []string, workerCount int) []Job {
+	if workerCount <= 1 || s.counter == nil {
+		return fileJobs(tests)
+	}
+
+	threshold := s.threshold
+	if threshold <= 0 {
+		var total time.Duration
+		for _, t := range tests {
+			total += s.estimator.Estimate(t)
+		}
+		threshold = max(total/time.Duration(2*workerCount), MinSplitThreshold)
+	}
+
+	jobs := make([]Job, 0, len(tests))
+	for _, t := range tests {
+		estimate := s.estimator.Estimate(t)
+		if !s.estimator.HasHistory(t) || estimate < threshold {
+			jobs = append(jobs, Job{Path: t})
+			continue
+		}
+		cases, err := s.counter.FindTestCases(t)
+		if err != nil || len(cases) < 2 {
+			jobs = append(jobs, Job{Path: t})
+			continue
+		}
+
+		chunks := int((estimate + threshold - 1) / threshold)
+		chunks = max(2, min(chunks, len(cases), workerCount))
+		debug.Logf("split: %s (%.1fs, %d cases) into %d chunks", t, estimate.Seconds(), len(cases), chunks)
+		jobs = append(jobs, chunkJobs(t, cases, chunks, estimate)...)
+	}
+	return jobs
+}
+
+// chunkJobs splits the cases of a file into contiguous chunks. The last chunk selects every case
+// *not* named in the earlier ones, so cases the discovery parser missed (e.g. inherited test
+// methods) still run exactly once.
+func chunkJobs(path string, cases []string, chunks int, estimate time.Duration) []Job {
+	jobs := make([]Job, 0, chunks)
+	size := (len(cases) + chunks - 1) / chunks
+	for i := 0; i < chunks; i++ {
+		start := i * size
+		if start >= len(cases) {
+			break
+		}
+		end := min(start+size, len(cases))
+		names := cases[start:end]
+		filter := includeFilter(names)
+		if end == len(cases) {
+			filter = excludeFilter(cases[:start])
+		}
+		jobs = append(jobs, Job{
+			Path:     path,
+			Filter:   filter,
+			Chunk:    i + 1,
+			Estimate: estimate * time.Duration(len(names)) / time.Duration(len(cases)),
+		})
+	}
+	for i := range jobs {
+		jobs[i].Chunks = len(jobs)
+	}
+	return jobs
+}
+
+// includeFilter matches the given methods, with or without a data set suffix
+func includeFilter(names []string) string {
+	return fmt.Sprintf("/::(?:%s)(?:\\s|$)/", quoteNames(names))
+}
+
+// excludeFilter matches every method except the given ones
+func excludeFilter(names []string) string {
+	return fmt.Sprintf("/::(?!(?:%s)(?:\\s|$))/", quoteNames(names))
+}
+
+func quoteNames(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = strings.ReplaceAll(regexp.QuoteMeta(n), "/", "\\/")
+	}
+	return strings.Join(quoted, "|")
+}
+
+// mergeResults folds the chunk results of one file back into a single file result.
+// Duration is the summed busy time, so recorded timings stay comparable to unsplit runs.
+func mergeResults(parts []domain.TestResult) domain.TestResult {
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	merged := domain.TestResult{
+		TestPath: parts[0].TestPath,
+		Success:  true,
+		WorkerID: parts[0].WorkerID,
+	}
+	var outputs []string
+	var logs [][]byte
+	for _, p := range parts {
+		merged.Success = merged.Success && p.Success
+		merged.Interrupted = merged.Interrupted || p.Interrupted
+		merged.TimedOut = merged.TimedOut || p.TimedOut
+		merged.Timeout = max(merged.Timeout, p.Timeout)
+		if merged.Error == nil && p.Error != nil {
+			merged.Error = p.Error
+		}
+		merged.Duration += p.Duration
+		merged.Cases = append(merged.Cases, p.Cases...)
+		merged.Spans = append(merged.Spans, p.Spans...)
+		outputs = append(outputs, p.Output)
+		logs = append(logs, p.Log)
+	}
+	merged.Output = strings.Join(outputs, "\n")
+	merged.Log = parser.MergeJUnitLogs(logs)
+	return merged
+}
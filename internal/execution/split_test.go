@@ -0,0 +1,119 @@
+package execution
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"ptp/internal/domain"
+)
+
+type caseList map[string][]string
+
+func (c caseList) FindTestCases(filePath string) ([]string, error) {
+	return c[filePath], nil
+}
+
+func TestSplitter_Split(t *testing.T) {
+	estimator := NewEstimator(timingsFor(map[string]float64{"heavy": 120, "light": 5}), nil)
+	cases := caseList{
+		"heavy": {"testA", "testB", "testC", "testD", "testE"},
+		"light": {"testA", "testB"},
+		"new":   {"testA", "testB", "testC"},
+	}
+
+	t.Run("only heavy files with history are split", func(t *testing.T) {
+		jobs := NewSplitter(estimator, cases, 50*time.Second).Split([]string{"heavy", "light", "new"}, 4)
+
+		var heavy []Job
+		for _, job := range jobs {
+			if job.Path == "heavy" {
+				heavy = append(heavy, job)
+			} else if job.Filter != "" {
+				t.Errorf("expected %s to run whole, got filter %s", job.Path, job.Filter)
+			}
+		}
+		// ceil(120/50) = 3 chunks
+		if len(heavy) != 3 {
+			t.Fatalf("expected 3 chunks, got %d: %v", len(heavy), heavy)
+		}
+		var total time.Duration
+		for i, job := range heavy {
+			if job.Chunk != i+1 || job.Chunks != 3 {
+				t.Errorf("unexpected chunk numbering: %s", job)
+			}
+			total += job.Estimate
+		}
+		if total != 120*time.Second {
+			t.Errorf("expected chunk estimates to add up to 120s, got %s", total)
+		}
+		if want := `/::(?!(?:testA|testB|testC|testD)(?:\s|$))/`; heavy[2].Filter != want {
+			t.Errorf("expected last chunk to exclude earlier cases\nwant %s\ngot  %s", want, heavy[2].Filter)
+		}
+	})
+
+	t.Run("chunks are capped by worker count", func(t *testing.T) {
+		jobs := NewSplitter(estimator, cases, 10*time.Second).Split([]string{"heavy"}, 2)
+		if len(jobs) != 2 {
+			t.Errorf("expected 2 chunks, got %d", len(jobs))
+		}
+	})
+
+	t.Run("automatic threshold", func(t *testing.T) {
+		// total 125s over 2 workers => 31.25s threshold, only heavy qualifies
+		jobs := NewSplitter(estimator, cases, 0).Split([]string{"heavy", "light"}, 2)
+		if len(jobs) != 3 {
+			t.Errorf("expected heavy in 2 chunks plus light, got %v", jobs)
+		}
+	})
+
+	t.Run("single worker never splits", func(t *testing.T) {
+		jobs := NewSplitter(estimator, cases, time.Second).Split([]string{"heavy"}, 1)
+		if len(jobs) != 1 || jobs[0].Filter != "" {
+			t.Errorf("expected the whole file, got %v", jobs)
+		}
+	})
+}
+
+func TestIncludeFilter(t *testing.T) {
+	filter := includeFilter([]string{"testA", "test_b"})
+	re := regexp.MustCompile(strings.Trim(filter, "/"))
+
+	for name, want := range map[string]bool{
+		`Tests\UserTest::testA`:                   true,
+		`Tests\UserTest::test_b with data set #0`: true,
+		`Tests\UserTest::testAB`:                  false,
+		`Tests\UserTest::testC`:                   false,
+	} {
+		if got := re.MatchString(name); got != want {
+			t.Errorf("%s: expected match=%v", name, want)
+		}
+	}
+}
+
+func TestMergeResults(t *testing.T) {
+	parts := []domain.TestResult{
+		{TestPath: "a", Success: true, Duration: 2 * time.Second, Output: "one", WorkerID: 1,
+			Cases: []domain.TestCaseResult{{Name: "testA", Status: domain.CasePassed}}},
+		{TestPath: "a", Success: false, Duration: 3 * time.Second, Output: "two", WorkerID: 2,
+			Error: errors.New("exit status 1"),
+			Cases: []domain.TestCaseResult{{Name: "testB", Status: domain.CaseFailed}}},
+	}
+	merged := mergeResults(parts)
+
+	if merged.Success || merged.Error == nil {
+		t.Error("expected merged result to fail when a chunk fails")
+	}
+	if merged.Duration != 5*time.Second {
+		t.Errorf("expected summed duration, got %s", merged.Duration)
+	}
+	if len(merged.Cases) != 2 || merged.Output != "one\ntwo" {
+		t.Errorf("unexpected merged cases/output: %+v", merged)
+	}
+	if merged.Log != nil {
+		t.Errorf("expected no log when chunks have none, got %s", fmt.Sprint(merged.Log))
+	}
+}
@@ -6,191 +6,336 @@ import (
 	"time"
 
 	"ptp/internal/config"
+	"ptp/internal/debug"
 	"ptp/internal/domain"
 	"ptp/internal/parser"
-	"ptp/internal/ui"
 )
 
 // WorkerPool manages a pool of workers for parallel test execution
 type WorkerPool struct {
-	config   *config.Config
-	runner   *Runner
-	scheduler Scheduler
-	progress *ui.ProgressBar
-	parser   *parser.PHPUnitParser
+	config     *config.Config
+	runner     Runner
+	scheduler  Scheduler
+	splitter   *Splitter
+	progress   Progress
+	parser     parser.Parser
+	remotes    []RemoteSlot
+	lastPlan   [][]Job
+	lastChunks []domain.TestResult
+	lastWall   time.Duration
+}
+
+// Progress displays the progress of an execution (the progress bar or the dashboard)
+type Progress interface {
+	// Update is called after each completed test file with the test case counts so far
+	Update(filesCompleted, passedCases, failedCases int)
+	Finish()
+}
+
+// WorkerObserver is implemented by progress displays that follow what each worker is doing
+type WorkerObserver interface {
+	// WorkerStarted is called once per worker of an execution; remote names the slot of a remote
+	// worker and is empty for local ones
+	WorkerStarted(workerID int, remote string)
+	JobStarted(workerID int, job Job)
+	JobFinished(workerID int)
+	// FileFinished is called with the result of each completed test file (split files once merged)
+	FileFinished(result domain.TestResult)
+}
+
+// RemoteSlot runs jobs on one slot of a remote agent (see `ptp serve-worker`). An error means the
+// job did not run there (the agent went away or refused it), so the pool hands it to another slot.
+type RemoteSlot interface {
+	Run(ctx context.Context, job Job, workerID int) (domain.TestResult, error)
+	String() string
 }
 
 // NewWorkerPool creates a new WorkerPool
-func NewWorkerPool(cfg *config.Config, runner *Runner, scheduler Scheduler, phpUnitParser *parser.PHPUnitParser) *WorkerPool {
+func NewWorkerPool(cfg *config.Config, runner Runner, scheduler Scheduler, resultParser parser.Parser) *WorkerPool {
 	return &WorkerPool{
 		config:    cfg,
 		runner:    runner,
 		scheduler: scheduler,
-		parser:    phpUnitParser,
+		parser:    resultParser,
 	}
 }
 
-// SetProgress sets the progress bar for the worker pool
-func (wp *WorkerPool) SetProgress(progress *ui.ProgressBar) {
+// SetProgress sets the progress display for the worker pool (nil for none). A progress that
+// implements WorkerObserver is also told about the jobs of each worker.
+func (wp *WorkerPool) SetProgress(progress Progress) {
 	wp.progress = progress
 }
 
+// SetScheduler replaces the scheduler used to distribute tests across workers
+func (wp *WorkerPool) SetScheduler(scheduler Scheduler) {
+	wp.scheduler = scheduler
+}
+
+// SetSplitter enables splitting heavy files into chunks of test cases (nil disables it)
+func (wp *WorkerPool) SetSplitter(splitter *Splitter) {
+	wp.splitter = splitter
+}
+
+// SetRemoteSlots adds remote slots to the pool; they are the workers after the local ones
+func (wp *WorkerPool) SetRemoteSlots(slots []RemoteSlot) {
+	wp.remotes = slots
+}
+
+// WorkerCount returns the number of workers of an execution: the local processors plus the remote slots
+func (wp *WorkerPool) WorkerCount() int {
+	return max(wp.config.Processors, 1) + len(wp.remotes)
+}
+
+// LastPlan returns the distribution produced by the scheduler for the last execution
+func (wp *WorkerPool) LastPlan() [][]Job {
+	return wp.lastPlan
+}
+
+// WorkerLoads returns the predicted vs actual load per worker for the last execution.
+// Chunks of split files are counted on the worker that ran them.
+func (wp *WorkerPool) WorkerLoads(estimator *Estimator) []domain.WorkerLoad {
+	return WorkerLoads(wp.lastPlan, wp.WorkerCount(), wp.lastChunks, estimator, wp.lastWall)
+}
+
 // Execute executes tests in parallel using worker pool (no fail-fast).
-func (wp *WorkerPool) Execute(tests []string) ([]domain.TestResult, time.Duration, error) {
-	return wp.ExecuteWithOptions(tests, false)
+func (wp *WorkerPool) Execute(ctx context.Context, tests []string) ([]domain.TestResult, time.Duration, error) {
+	return wp.ExecuteWithOptions(ctx, tests, false)
 }
 
 // ExecuteWithOptions executes tests with optional fail-fast (stop on first failure).
-func (wp *WorkerPool) ExecuteWithOptions(tests []string, failFast bool) ([]domain.TestResult, time.Duration, error) {
+// Tests are distributed by the pool's scheduler: either one shared queue or a fixed list per worker.
+// When a splitter is set, heavy files run as several chunks whose results are merged back per file.
+// Remote slots pull from the same queues as the local workers; a job lost with a remote slot is
+// queued again for the remaining workers once the queues are drained.
+// Cancelling ctx stops handing out tests and kills the running ones; the results collected so far
+// (with in-flight files marked as interrupted) are still returned.
+// Every result records in Spans when and on which worker it ran, relative to the start of the execution.
+func (wp *WorkerPool) ExecuteWithOptions(ctx context.Context, tests []string, failFast bool) ([]domain.TestResult, time.Duration, error) {
 	if len(tests) == 0 {
 		return nil, 0, nil
 	}
-	if !failFast {
-		return wp.executeAll(tests)
-	}
-	return wp.executeFailFast(tests)
-}
 
-// executeAll runs all tests (original behavior).
-func (wp *WorkerPool) executeAll(tests []string) ([]domain.TestResult, time.Duration, error) {
-	testQueue := make(chan string, len(tests))
-	results := make(chan domain.TestResult, len(tests))
-	for _, test := range tests {
-		testQueue <- test
+	// Fail-fast only stops the queues; tests already running are allowed to finish
+	queueCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workerCount := wp.WorkerCount()
+	local := workerCount - len(wp.remotes)
+	scheduler := wp.scheduler
+	if scheduler == nil {
+		scheduler = NewQueueScheduler(nil)
 	}
-	close(testQueue)
+	jobs := fileJobs(tests)
+	if wp.splitter != nil {
+		jobs = wp.splitter.Split(tests, workerCount)
+	}
+	remaining := make(map[string]int, len(tests))
+	for _, job := range jobs {
+		remaining[job.Path]++
+	}
+	plan := scheduler.Schedule(jobs, workerCount)
+	wp.lastPlan = plan
+	wp.lastChunks = nil
+	debug.Logf("pool: scheduled %d jobs for %d tests into %d queue(s) for %d workers", len(jobs), len(tests), len(plan), workerCount)
+	queues := feedQueues(queueCtx, plan, workerCount)
 
 	var mu sync.Mutex
+	var allResults []domain.TestResult
+	parts := make(map[string][]domain.TestResult)
 	var completedFiles int
 	var passedCases, failedCases int
+	var seenFailure bool
 	startTime := time.Now()
-	workerCount := wp.config.Processors
-	if workerCount <= 0 {
-		workerCount = 1
-	}
-
-	var wg sync.WaitGroup
-	for i := 1; i <= workerCount; i++ {
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-			for testPath := range testQueue {
-				result := wp.runner.Run(testPath, workerID)
-				results <- result
+
+	observer, _ := wp.progress.(WorkerObserver)
+	lost := make(map[int]bool)
+	var requeued []Job
+	work := func(workerID int, queue <-chan Job, shared bool) {
+		for job := range queue {
+			if observer != nil {
+				observer.JobStarted(workerID, job)
+			}
+			started := time.Since(startTime)
+			result, ok := wp.runJob(ctx, job, workerID, local)
+			span := newSpan(job, workerID, started, time.Since(startTime), result)
+			if observer != nil {
+				observer.JobFinished(workerID)
+			}
+			if !ok {
 				mu.Lock()
-				completedFiles++
-				if wp.parser != nil {
-					p, f := wp.parser.ParseTestCounts(result)
-					passedCases += p
-					failedCases += f
-				} else {
-					if result.Success {
-						passedCases++
-					} else {
-						failedCases++
+				lost[workerID] = true
+				requeued = append(requeued, job)
+				if !shared {
+					// Nobody else reads this worker's own queue
+					for job := range queue {
+						requeued = append(requeued, job)
 					}
 				}
-				if wp.progress != nil {
-					wp.progress.Update(completedFiles, passedCases, failedCases)
-				}
 				mu.Unlock()
+				return
+			}
+			if wp.parser != nil {
+				result.Cases = wp.parser.ParseCases(result)
+			}
+			result.Spans = []domain.WorkerSpan{span}
+			mu.Lock()
+			if failFast && seenFailure {
+				mu.Unlock()
+				continue
+			}
+			wp.lastChunks = append(wp.lastChunks, result)
+			parts[job.Path] = append(parts[job.Path], result)
+			remaining[job.Path]--
+			if remaining[job.Path] > 0 && !(failFast && !result.Success) {
+				mu.Unlock()
+				continue
+			}
+			result = mergeResults(parts[job.Path])
+			delete(parts, job.Path)
+			allResults = append(allResults, result)
+			completedFiles++
+			if wp.parser != nil {
+				p, f := wp.parser.ParseTestCounts(result)
+				passedCases += p
+				failedCases += f
+			} else {
+				if result.Success {
+					passedCases++
+				} else {
+					failedCases++
+				}
+			}
+			if wp.progress != nil {
+				wp.progress.Update(completedFiles, passedCases, failedCases)
+			}
+			if observer != nil {
+				observer.FileFinished(result)
+			}
+			if failFast && !result.Success {
+				seenFailure = true
+				cancel()
 			}
-		}(i)
+			mu.Unlock()
+		}
+	}
+
+	if observer != nil {
+		for i := 1; i <= workerCount; i++ {
+			remote := ""
+			if i > local {
+				remote = wp.remotes[i-local-1].String()
+			}
+			observer.WorkerStarted(i, remote)
+		}
 	}
-	go func() {
+
+	// Local workers are never lost, so every round drains its queue
+	shared := len(plan) == 1
+	for {
+		var wg sync.WaitGroup
+		for i := 1; i <= workerCount; i++ {
+			if lost[i] {
+				continue
+			}
+			wg.Add(1)
+			go func(workerID int, queue <-chan Job) {
+				defer wg.Done()
+				work(workerID, queue, shared)
+			}(i, queues[i-1])
+		}
 		wg.Wait()
-		close(results)
-	}()
+		if len(requeued) == 0 || queueCtx.Err() != nil {
+			break
+		}
+		debug.Logf("pool: re-queueing %d job(s) of lost remote slots", len(requeued))
+		queues = feedQueues(queueCtx, [][]Job{requeued}, workerCount)
+		requeued = nil
+		shared = true
+	}
 
-	var allResults []domain.TestResult
-	for result := range results {
-		allResults = append(allResults, result)
+	// Split files whose remaining chunks never ran because of a cancellation are reported as
+	// interrupted with what did complete; after a fail-fast stop they are dropped like unstarted files.
+	if ctx.Err() != nil {
+		for path, p := range parts {
+			result := mergeResults(p)
+			result.Success = false
+			result.Interrupted = true
+			debug.Logf("pool: %s interrupted after %d chunk(s)", path, len(p))
+			allResults = append(allResults, result)
+		}
+		debug.Logf("pool: cancelled after %d of %d tests", completedFiles, len(tests))
 	}
+
 	if wp.progress != nil {
 		wp.progress.Finish()
 	}
-	return allResults, time.Since(startTime), nil
+	wp.lastWall = time.Since(startTime)
+	return allResults, wp.lastWall, nil
 }
 
-// executeFailFast runs tests and stops after the first failure.
-func (wp *WorkerPool) executeFailFast(tests []string) ([]domain.TestResult, time.Duration, error) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	testQueue := make(chan string, 1)
-	results := make(chan domain.TestResult, len(tests))
+// newSpan records that a job ran on a worker from started to ended (since the start of the execution)
+func newSpan(job Job, workerID int, started, ended time.Duration, result domain.TestResult) domain.WorkerSpan {
+	return domain.WorkerSpan{
+		WorkerID:     workerID,
+		Path:         job.Path,
+		Chunk:        job.Chunk,
+		Chunks:       job.Chunks,
+		StartSeconds: started.Seconds(),
+		EndSeconds:   ended.Seconds(),
+		Status:       domain.SpanStatus(result.Success, result.Interrupted, result.TimedOut),
+	}
+}
 
-	go func() {
-		defer close(testQueue)
-		for _, test := range tests {
-			select {
-			case <-ctx.Done():
-				return
-			case testQueue <- test:
-			}
+// runJob runs a job on the local runner or, for the workers after the local ones, on a remote slot.
+// It returns false when the remote slot was lost without running the job.
+func (wp *WorkerPool) runJob(ctx context.Context, job Job, workerID, local int) (domain.TestResult, bool) {
+	if workerID > local {
+		slot := wp.remotes[workerID-local-1]
+		result, err := slot.Run(ctx, job, workerID)
+		if err != nil {
+			debug.Logf("pool: remote slot %s lost running %s: %v", slot, job, err)
+			return result, false
 		}
-	}()
+		return result, true
+	}
+	if job.Filter != "" {
+		return wp.runner.RunFiltered(ctx, job.Path, job.Filter, workerID), true
+	}
+	return wp.runner.Run(ctx, job.Path, workerID), true
+}
 
-	var mu sync.Mutex
-	var completedFiles int
-	var passedCases, failedCases int
-	var seenFailure bool
-	startTime := time.Now()
-	workerCount := wp.config.Processors
-	if workerCount <= 0 {
-		workerCount = 1
-	}
-
-	var wg sync.WaitGroup
-	for i := 1; i <= workerCount; i++ {
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-			for testPath := range testQueue {
-				result := wp.runner.Run(testPath, workerID)
-				mu.Lock()
-				done := seenFailure
-				mu.Unlock()
-				if done {
-					continue
-				}
-				results <- result
-				mu.Lock()
-				completedFiles++
-				if wp.parser != nil {
-					p, f := wp.parser.ParseTestCounts(result)
-					passedCases += p
-					failedCases += f
-				} else {
-					if result.Success {
-						passedCases++
-					} else {
-						failedCases++
-					}
+// feedQueues returns one channel per worker fed from the plan until ctx is cancelled.
+// A single-bucket plan yields the same shared channel for every worker.
+func feedQueues(ctx context.Context, plan [][]Job, workerCount int) []<-chan Job {
+	feed := func(bucket []Job) <-chan Job {
+		ch := make(chan Job)
+		go func() {
+			defer close(ch)
+			for _, job := range bucket {
+				select {
+				case <-ctx.Done():
+					return
+				case ch <- job:
 				}
-				if wp.progress != nil {
-					wp.progress.Update(completedFiles, passedCases, failedCases)
-				}
-				if !result.Success {
-					seenFailure = true
-					cancel()
-				}
-				mu.Unlock()
 			}
-		}(i)
+		}()
+		return ch
 	}
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
 
-	var allResults []domain.TestResult
-	for result := range results {
-		allResults = append(allResults, result)
+	queues := make([]<-chan Job, workerCount)
+	if len(plan) == 1 {
+		shared := feed(plan[0])
+		for i := range queues {
+			queues[i] = shared
+		}
+		return queues
 	}
-	if wp.progress != nil {
-		wp.progress.Finish()
+	for i := range queues {
+		var bucket []Job
+		if i < len(plan) {
+			bucket = plan[i]
+		}
+		queues[i] = feed(bucket)
 	}
-	return allResults, time.Since(startTime), nil
+	return queues
 }
-
@@ -0,0 +1,108 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"ptp/internal/config"
+	"ptp/internal/domain"
+)
+
+// fakeSlot is a remote slot that runs every job successfully, or is lost on its first job
+type fakeSlot struct {
+	name string
+	lost bool
+
+	mu  sync.Mutex
+	ran []string
+}
+
+func (s *fakeSlot) Run(ctx context.Context, job Job, workerID int) (domain.TestResult, error) {
+	if s.lost {
+		return domain.TestResult{}, errors.New("connection reset")
+	}
+	s.mu.Lock()
+	s.ran = append(s.ran, job.Path)
+	s.mu.Unlock()
+	return domain.TestResult{TestPath: job.Path, Success: true, WorkerID: workerID}, nil
+}
+
+func (s *fakeSlot) String() string { return s.name }
+
+func TestWorkerPool_RemoteSlots(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the phpunit binary")
+	}
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "phpunit")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\nexit 0\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.New()
+	cfg.ProjectPath = dir
+	cfg.PHPUnitBinary = binary
+	cfg.Processors = 1
+
+	tests := []string{"tests/ATest.php", "tests/BTest.php", "tests/CTest.php", "tests/DTest.php", "tests/ETest.php", "tests/FTest.php"}
+	for _, scheduler := range []Scheduler{NewQueueScheduler(nil), NewRoundRobinScheduler()} {
+		remote := &fakeSlot{name: "build-1:7420#1"}
+		gone := &fakeSlot{name: "build-2:7420#1", lost: true}
+		pool := NewWorkerPool(cfg, NewRunner(cfg), scheduler, nil)
+		pool.SetRemoteSlots([]RemoteSlot{remote, gone})
+		if pool.WorkerCount() != 3 {
+			t.Fatalf("expected 3 workers, got %d", pool.WorkerCount())
+		}
+
+		results, _, err := pool.Execute(context.Background(), tests)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var paths []string
+		for _, r := range results {
+			if !r.Success || r.WorkerID == 3 {
+				t.Errorf("unexpected result %+v", r)
+			}
+			if len(r.Spans) != 1 || r.Spans[0].WorkerID != r.WorkerID || r.Spans[0].EndSeconds < r.Spans[0].StartSeconds {
+				t.Errorf("unexpected spans %+v", r.Spans)
+			}
+			paths = append(paths, r.TestPath)
+		}
+		sort.Strings(paths)
+		if len(paths) != len(tests) {
+			t.Errorf("%T: expected every file to run once despite the lost slot, got %v", scheduler, paths)
+		}
+		for i := range paths {
+			if i < len(tests) && paths[i] != tests[i] {
+				t.Errorf("%T: expected %v, got %v", scheduler, tests, paths)
+				break
+			}
+		}
+		if len(remote.ran) == 0 {
+			t.Errorf("%T: expected the remote slot to run jobs", scheduler)
+		}
+	}
+}
+
+func TestNewSpan_Status(t *testing.T) {
+	job := Job{Path: "tests/HangTest.php"}
+	for _, tc := range []struct {
+		result domain.TestResult
+		want   string
+	}{
+		{domain.TestResult{Success: true}, domain.CasePassed},
+		{domain.TestResult{}, domain.CaseFailed},
+		{domain.TestResult{TimedOut: true}, domain.SpanTimedOut},
+		{domain.TestResult{Interrupted: true}, domain.SpanInterrupted},
+	} {
+		if got := newSpan(job, 1, 0, time.Second, tc.result).Status; got != tc.want {
+			t.Errorf("newSpan(%+v).Status = %q, want %q", tc.result, got, tc.want)
+		}
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -17,6 +16,7 @@ import (
 	"ptp/internal/config"
 	"ptp/internal/debug"
 	"ptp/internal/domain"
+	"ptp/internal/proc"
 )
 
 // LaravelMigrator implements Migrator for Laravel migrations
@@ -33,8 +33,11 @@ func NewLaravelMigrator(cfg *config.Config, dbManager *DatabaseManager) *Laravel
 	}
 }
 
-// Run executes migrations in parallel for all workers
-func (lm *LaravelMigrator) Run(workerCount int, fresh bool) error {
+// Run executes migrations in parallel for all workers. Cancelling ctx kills the running artisan processes.
+func (lm *LaravelMigrator) Run(ctx context.Context, workerCount int, fresh bool) error {
+	if lm.config.HasRunnerCommand() && len(lm.config.RunnerMigrateCommand) == 0 {
+		return fmt.Errorf("tests run through runner.command, but runner.migrate is not set: set it to run artisan where the tests run, or skip migrations (--skip-migrate or migration: skip)")
+	}
 	quiet := debug.IsEnabled()
 
 	if !quiet {
@@ -110,7 +113,7 @@ func (lm *LaravelMigrator) Run(workerCount int, fresh bool) error {
 		wg.Add(1)
 		go func(id int) {
 			defer wg.Done()
-			result := lm.runMigrationForWorker(id, bar, &completedCount, &progressMu, fresh)
+			result := lm.runMigrationForWorker(ctx, id, bar, &completedCount, &progressMu, fresh)
 			results <- result
 		}(workerID)
 	}
@@ -131,6 +134,10 @@ func (lm *LaravelMigrator) Run(workerCount int, fresh bool) error {
 
 	duration := time.Since(startTime)
 
+	if ctx.Err() != nil {
+		return fmt.Errorf("migrations interrupted: %w", ctx.Err())
+	}
+
 	if !quiet {
 		fmt.Print("\n")
 		if len(failedMigrations) == 0 {
@@ -175,8 +182,22 @@ func (lm *LaravelMigrator) findMigrationFiles() ([]string, error) {
 	return migrationFiles, err
 }
 
+// migrateCommand returns the artisan migrate (or migrate:fresh) command of a worker: php on the host,
+// or the runner.migrate template with artisan as a container path when tests run through runner.command
+func (lm *LaravelMigrator) migrateCommand(artisanPath string, workerID int, fresh bool) []string {
+	migrateCmd := "migrate"
+	if fresh {
+		migrateCmd = "migrate:fresh"
+	}
+	args := []string{migrateCmd, "--env=testing", "--force"}
+	if lm.config.HasRunnerCommand() {
+		return lm.config.ExpandCommand(lm.config.RunnerMigrateCommand, workerID, lm.config.ToContainer(artisanPath), args)
+	}
+	return append([]string{"php", artisanPath}, args...)
+}
+
 // runMigrationForWorker executes migrate or migrate:fresh with streaming output and progress tracking
-func (lm *LaravelMigrator) runMigrationForWorker(workerID int, bar *progressbar.ProgressBar, completedCount *int, progressMu *sync.Mutex, fresh bool) domain.MigrationResult {
+func (lm *LaravelMigrator) runMigrationForWorker(ctx context.Context, workerID int, bar *progressbar.ProgressBar, completedCount *int, progressMu *sync.Mutex, fresh bool) domain.MigrationResult {
 	projectAbsPath, err := filepath.Abs(lm.config.ProjectPath)
 	if err != nil {
 		debug.Logf("migration[w%d]: failed to resolve project path: %v", workerID, err)
@@ -188,20 +209,12 @@ func (lm *LaravelMigrator) runMigrationForWorker(workerID int, bar *progressbar.
 		}
 	}
 
-	artisanPath := filepath.Join(projectAbsPath, "artisan")
-	ctx := context.Background()
-
-	migrateCmd := "migrate"
-	if fresh {
-		migrateCmd = "migrate:fresh"
-	}
-
-	debug.Logf("migration[w%d]: exec php %s %s --env=testing --force (db=%s)", workerID, artisanPath, migrateCmd, lm.config.GetDatabaseName(workerID))
-	cmd := exec.CommandContext(ctx, "php", artisanPath, migrateCmd, "--env=testing", "--force")
+	words := lm.migrateCommand(filepath.Join(projectAbsPath, "artisan"), workerID, fresh)
+	debug.Logf("migration[w%d]: exec %v (db=%s)", workerID, words, lm.config.GetDatabaseName(workerID))
+	cmd := proc.Command(ctx, words[0], words[1:]...)
 
 	// Set environment variables
-	cmd.Env = os.Environ()
-	cmd.Env = append(cmd.Env, fmt.Sprintf("DB_DATABASE=%s", lm.config.GetDatabaseName(workerID)))
+	cmd.Env = lm.config.WorkerEnviron(workerID)
 
 	// Set working directory
 	cmd.Dir = projectAbsPath
@@ -1,7 +1,9 @@
 package migration
 
+import "context"
+
 // Migrator runs database migrations
 type Migrator interface {
-	Run(workerCount int, fresh bool) error
+	Run(ctx context.Context, workerCount int, fresh bool) error
 }
 
@@ -0,0 +1,281 @@
+package parser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"ptp/internal/domain"
+)
+
+// junitTestSuites is the root of a PHPUnit --log-junit document
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name   string           `xml:"name,attr"`
+	File   string           `xml:"file,attr"`
+	Suites []junitTestSuite `xml:"testsuite"`
+	Cases  []junitTestCase  `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string         `xml:"name,attr"`
+	Class     string         `xml:"class,attr"`
+	ClassName string         `xml:"classname,attr"`
+	File      string         `xml:"file,attr"`
+	Line      int            `xml:"line,attr"`
+	Time      float64        `xml:"time,attr"`
+	Failures  []junitProblem `xml:"failure"`
+	Errors    []junitProblem `xml:"error"`
+	Skipped   *junitProblem  `xml:"skipped"`
+}
+
+type junitProblem struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// stackLinePattern matches a stack trace line: /path/to/File.php:123
+var stackLinePattern = regexp.MustCompile(`^\s*(.+\.php):(\d+)\s*$`)
+
+// JUnitParser builds failures and per-case results from the JUnit XML log PHPUnit
+// writes with --log-junit. It is independent of PHPUnit's human-readable output format.
+type JUnitParser struct{}
+
+// NewJUnitParser creates a new JUnitParser
+func NewJUnitParser() *JUnitParser {
+	return &JUnitParser{}
+}
+
+// ParseCases returns the per-case results of a test file, or an error if the log is missing or invalid
+func (p *JUnitParser) ParseCases(result domain.TestResult) ([]domain.TestCaseResult, error) {
+	cases, err := p.parse(result.Log)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]domain.TestCaseResult, 0, len(cases))
+	for _, c := range cases {
+		tc := domain.TestCaseResult{
+			Name:            c.Name,
+			Class:           c.class(),
+			File:            c.File,
+			Line:            c.Line,
+			Status:          domain.CasePassed,
+			DurationSeconds: c.Time,
+		}
+		switch {
+		case len(c.Errors) > 0:
+			tc.Status = domain.CaseError
+			tc.Message, _ = splitProblem(c.Errors[0].Text, c)
+		case len(c.Failures) > 0:
+			tc.Status = domain.CaseFailed
+			tc.Message, _ = splitProblem(c.Failures[0].Text, c)
+		case c.Skipped != nil:
+			tc.Status = domain.CaseSkipped
+			tc.Message = strings.TrimSpace(c.Skipped.Text)
+		}
+		out = append(out, tc)
+	}
+	return out, nil
+}
+
+// ParseFailure builds one TestFailure per failed or errored case in the log
+func (p *JUnitParser) ParseFailure(result domain.TestResult) ([]domain.TestFailure, error) {
+	cases, err := p.parse(result.Log)
+	if err != nil {
+		return nil, err
+	}
+	var failures []domain.TestFailure
+	for _, c := range cases {
+		problems := append(append([]junitProblem{}, c.Failures...), c.Errors...)
+		for _, problem := range problems {
+			failures = append(failures, p.buildFailure(result, c, problem))
+		}
+	}
+	return failures, nil
+}
+
+// ParseTestCounts returns (passed, failed) case counts from the log. Skipped cases count as passed,
+// matching PHPUnit's own "Tests: N, Failures: F" summary.
+func (p *JUnitParser) ParseTestCounts(result domain.TestResult) (passed, failed int, err error) {
+	cases, err := p.parse(result.Log)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, c := range cases {
+		if len(c.Failures) > 0 || len(c.Errors) > 0 {
+			failed++
+		} else {
+			passed++
+		}
+	}
+	return passed, failed, nil
+}
+
+func (p *JUnitParser) parse(data []byte) ([]junitTestCase, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("no junit log")
+	}
+	var doc junitTestSuites
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse junit log: %w", err)
+	}
+	var cases []junitTestCase
+	var walk func(suites []junitTestSuite)
+	walk = func(suites []junitTestSuite) {
+		for _, s := range suites {
+			cases = append(cases, s.Cases...)
+			walk(s.Suites)
+		}
+	}
+	walk(doc.Suites)
+	return cases, nil
+}
+
+func (p *JUnitParser) buildFailure(result domain.TestResult, c junitTestCase, problem junitProblem) domain.TestFailure {
+	message, stack := splitProblem(problem.Text, c)
+	message, details := extractJSONBlock(message)
+
+	filePath := result.TestPath
+	if filePath == "" || (c.File != "" && !sameFile(c.File, filePath)) {
+		filePath = c.File
+	}
+
+	failure := domain.TestFailure{
+		TestName:     c.Name,
+		Class:        c.class(),
+		FilePath:     filePath,
+		ErrorDetails: details,
+		StackTrace:   stack,
+		Message:      message,
+	}
+	if message == "" && problem.Type != "" {
+		failure.Message = problem.Type
+	}
+
+	// Location: first frame outside vendor/, preferring the test file itself
+	for _, line := range stack {
+		m := stackLinePattern.FindStringSubmatch(line)
+		if m == nil || strings.Contains(m[1], "/vendor/") {
+			continue
+		}
+		if failure.File == "" || sameFile(m[1], c.File) {
+			failure.File = m[1]
+			fmt.Sscanf(m[2], "%d", &failure.Line)
+			if sameFile(m[1], c.File) {
+				break
+			}
+		}
+	}
+	if failure.File == "" && c.File != "" {
+		failure.File = c.File
+		failure.Line = c.Line
+	}
+	return failure
+}
+
+// class returns the test class name (PHPUnit 9 uses "class", PHPUnit 10+ "classname")
+func (c junitTestCase) class() string {
+	if c.Class != "" {
+		return c.Class
+	}
+	return c.ClassName
+}
+
+// splitProblem splits a failure/error body into the message and the trailing stack trace lines.
+// The leading "Class::method" header PHPUnit repeats in the body is dropped.
+func splitProblem(text string, c junitTestCase) (message string, stack []string) {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	if len(lines) > 0 && c.class() != "" && strings.HasPrefix(strings.TrimSpace(lines[0]), c.class()+"::") {
+		lines = lines[1:]
+	}
+
+	end := len(lines)
+	for end > 0 {
+		trimmed := strings.TrimSpace(lines[end-1])
+		if trimmed == "" {
+			end--
+			continue
+		}
+		if !stackLinePattern.MatchString(trimmed) {
+			break
+		}
+		end--
+	}
+	for _, line := range lines[end:] {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			stack = append(stack, trimmed)
+		}
+	}
+	if stack == nil {
+		stack = []string{}
+	}
+	return strings.TrimSpace(strings.Join(lines[:end], "\n")), stack
+}
+
+// extractJSONBlock moves a multi-line JSON block (e.g. a dumped response) out of the message,
+// the same way the text parser fills ErrorDetails.
+func extractJSONBlock(message string) (rest string, details string) {
+	lines := strings.Split(message, "\n")
+	start := -1
+	depth := 0
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if start < 0 {
+			if trimmed == "{" {
+				start = i
+				depth = 1
+			}
+			continue
+		}
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+		if depth == 0 {
+			details = strings.Join(lines[start:i+1], "\n")
+			remaining := append(append([]string{}, lines[:start]...), lines[i+1:]...)
+			return strings.TrimSpace(strings.Join(remaining, "\n")), details
+		}
+	}
+	return message, ""
+}
+
+// sameFile reports whether two paths point to the same file, tolerating relative vs absolute forms
+func sameFile(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	a, b = filepath.ToSlash(filepath.Clean(a)), filepath.ToSlash(filepath.Clean(b))
+	if a == b {
+		return true
+	}
+	return strings.HasSuffix(a, "/"+b) || strings.HasSuffix(b, "/"+a)
+}
+
+// MergeJUnitLogs combines several JUnit logs into one document by concatenating their
+// top-level suites. Returns nil if any log is missing, so callers fall back to text parsing.
+func MergeJUnitLogs(logs [][]byte) []byte {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString("<testsuites>\n")
+	for _, log := range logs {
+		s := string(log)
+		start := strings.Index(s, "<testsuites")
+		end := strings.LastIndex(s, "</testsuites>")
+		if start < 0 || end < 0 {
+			return nil
+		}
+		if gt := strings.Index(s[start:], ">"); gt >= 0 {
+			start += gt + 1
+		}
+		if start > end {
+			continue
+		}
+		b.WriteString(s[start:end])
+	}
+	b.WriteString("</testsuites>\n")
+	return []byte(b.String())
+}
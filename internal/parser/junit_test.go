@@ -0,0 +1,133 @@
+package parser
+
+import (
+	"testing"
+
+	"ptp/internal/domain"
+)
+
+const junitLog = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="Unit" tests="4">
+    <testsuite name="Tests\Unit\UserTest" file="/app/tests/Unit/UserTest.php" tests="4">
+      <testcase name="testCreate" class="Tests\Unit\UserTest" classname="Tests.Unit.UserTest" file="/app/tests/Unit/UserTest.php" line="10" assertions="1" time="0.012"/>
+      <testcase name="testSkipped" class="Tests\Unit\UserTest" classname="Tests.Unit.UserTest" file="/app/tests/Unit/UserTest.php" line="15" assertions="0" time="0.001">
+        <skipped/>
+      </testcase>
+      <testcase name="testUpdate with data set #1" class="Tests\Unit\UserTest" classname="Tests.Unit.UserTest" file="/app/tests/Unit/UserTest.php" line="20" assertions="1" time="0.250">
+        <failure type="PHPUnit\Framework\ExpectationFailedException">Tests\Unit\UserTest::testUpdate with data set #1
+Expected response status code [200] but received 500.
+{
+    "message": "Server Error"
+}
+
+/app/vendor/laravel/framework/src/Illuminate/Testing/TestResponse.php:187
+/app/tests/Unit/UserTest.php:24
+</failure>
+      </testcase>
+      <testcase name="testDelete" classname="Tests.Unit.UserTest" file="/app/tests/Unit/UserTest.php" line="30" time="0.100">
+        <error type="Error">Error: Call to undefined method delete()
+
+/app/tests/Unit/UserTest.php:31
+</error>
+      </testcase>
+    </testsuite>
+  </testsuite>
+</testsuites>`
+
+func TestJUnitParser_ParseFailure(t *testing.T) {
+	p := NewJUnitParser()
+	result := domain.TestResult{TestPath: "tests/Unit/UserTest.php", Log: []byte(junitLog)}
+
+	failures, err := p.ParseFailure(result)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(failures) != 2 {
+		t.Fatalf("expected 2 failures, got %d", len(failures))
+	}
+
+	f := failures[0]
+	if f.TestName != "testUpdate with data set #1" {
+		t.Errorf("unexpected test name %q", f.TestName)
+	}
+	if f.FilePath != "tests/Unit/UserTest.php" {
+		t.Errorf("expected file path from the executed test, got %q", f.FilePath)
+	}
+	if f.Message != "Expected response status code [200] but received 500." {
+		t.Errorf("unexpected message %q", f.Message)
+	}
+	if f.ErrorDetails == "" {
+		t.Error("expected JSON block to be moved into ErrorDetails")
+	}
+	if len(f.StackTrace) != 2 {
+		t.Errorf("expected 2 stack lines, got %v", f.StackTrace)
+	}
+	if f.File != "/app/tests/Unit/UserTest.php" || f.Line != 24 {
+		t.Errorf("expected location in the test file, got %s:%d", f.File, f.Line)
+	}
+
+	if failures[1].Message != "Error: Call to undefined method delete()" || failures[1].Line != 31 {
+		t.Errorf("unexpected error failure: %+v", failures[1])
+	}
+}
+
+func TestJUnitParser_ParseCases(t *testing.T) {
+	p := NewJUnitParser()
+	cases, err := p.ParseCases(domain.TestResult{Log: []byte(junitLog)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{domain.CasePassed, domain.CaseSkipped, domain.CaseFailed, domain.CaseError}
+	if len(cases) != len(expected) {
+		t.Fatalf("expected %d cases, got %d", len(expected), len(cases))
+	}
+	for i, status := range expected {
+		if cases[i].Status != status {
+			t.Errorf("case %d: expected %s, got %s", i, status, cases[i].Status)
+		}
+	}
+	if cases[2].DurationSeconds != 0.25 || cases[2].Class != `Tests\Unit\UserTest` {
+		t.Errorf("unexpected case details: %+v", cases[2])
+	}
+	if cases[3].Class != "Tests.Unit.UserTest" {
+		t.Errorf("expected classname fallback, got %q", cases[3].Class)
+	}
+}
+
+func TestPHPUnitParser_FallsBackToText(t *testing.T) {
+	p := NewPHPUnitParser()
+
+	t.Run("missing log", func(t *testing.T) {
+		passed, failed := p.ParseTestCounts(domain.TestResult{Output: "Tests: 5, Assertions: 9, Failures: 2."})
+		if passed != 3 || failed != 2 {
+			t.Errorf("expected 3/2 from text output, got %d/%d", passed, failed)
+		}
+		if cases := p.ParseCases(domain.TestResult{}); cases != nil {
+			t.Errorf("expected no cases without a log, got %v", cases)
+		}
+	})
+
+	t.Run("log present", func(t *testing.T) {
+		passed, failed := p.ParseTestCounts(domain.TestResult{Log: []byte(junitLog), Output: "garbage"})
+		if passed != 2 || failed != 2 {
+			t.Errorf("expected 2/2 from junit log, got %d/%d", passed, failed)
+		}
+	})
+}
+
+func TestMergeJUnitLogs(t *testing.T) {
+	merged := MergeJUnitLogs([][]byte{[]byte(junitLog), []byte(junitLog)})
+	cases, err := NewJUnitParser().ParseCases(domain.TestResult{TestPath: "tests/Unit/UserTest.php", Log: merged})
+	if err != nil {
+		t.Fatalf("unexpected error parsing merged log: %v", err)
+	}
+	if len(cases) != 8 {
+		t.Errorf("expected cases of both logs, got %d", len(cases))
+	}
+
+	if MergeJUnitLogs([][]byte{[]byte(junitLog), nil}) != nil {
+		t.Error("expected nil when a chunk has no log")
+	}
+}
@@ -1,9 +1,21 @@
 package parser
 
-import "ptp/internal/domain"
+import (
+	"ptp/internal/config"
+	"ptp/internal/domain"
+)
 
-// Parser parses test results and extracts failures
+// Parser parses the results of a test framework run and extracts failures
 type Parser interface {
+	ParseCases(result domain.TestResult) []domain.TestCaseResult
+	ParseTestCounts(result domain.TestResult) (passed, failed int)
 	ParseFailure(result domain.TestResult) []domain.TestFailure
 }
 
+// New returns the parser for the results of a test framework (config.FrameworkPHPUnit or config.FrameworkPest)
+func New(framework string) Parser {
+	if framework == config.FrameworkPest {
+		return NewPestParser()
+	}
+	return NewPHPUnitParser()
+}
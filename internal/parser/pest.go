@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"ptp/internal/domain"
+)
+
+var (
+	ansiPattern       = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+	pestFailedPattern = regexp.MustCompile(`^\s*FAILED\s+(\S+)\s+>\s+(.+?)(?:\s{2,}\S+)?\s*$`)
+	pestAtPattern     = regexp.MustCompile(`^\s*at\s+(\S+\.php):(\d+)\s*$`)
+	pestFramePattern  = regexp.MustCompile(`^\s*\d+\s+(\S+\.php:\d+)`)
+	pestTestsPattern  = regexp.MustCompile(`(?m)^\s*Tests:\s+(.+)$`)
+	pestCountPattern  = regexp.MustCompile(`(\d+)\s+(failed|passed)`)
+)
+
+// PestParser parses Pest test results. Like PHPUnitParser it prefers the JUnit log Pest writes
+// with --log-junit and only scrapes Pest's output when the log is missing.
+type PestParser struct {
+	junit *JUnitParser
+}
+
+// NewPestParser creates a new PestParser
+func NewPestParser() *PestParser {
+	return &PestParser{junit: NewJUnitParser()}
+}
+
+// ParseCases returns per-case results from the JUnit log (nil when there is no usable log)
+func (p *PestParser) ParseCases(result domain.TestResult) []domain.TestCaseResult {
+	cases, err := p.junit.ParseCases(result)
+	if err != nil {
+		return nil
+	}
+	return cases
+}
+
+// ParseTestCounts extracts passed and failed test case counts, from the JUnit log or from Pest's
+// "Tests: 1 failed, 3 passed (5 assertions)" summary. Falls back to one test per file.
+func (p *PestParser) ParseTestCounts(result domain.TestResult) (passed, failed int) {
+	if passed, failed, err := p.junit.ParseTestCounts(result); err == nil && (passed > 0 || failed > 0) {
+		if result.Success || failed > 0 {
+			return passed, failed
+		}
+	}
+
+	output := ansiPattern.ReplaceAllString(result.Output, "")
+	if m := pestTestsPattern.FindStringSubmatch(output); m != nil {
+		for _, count := range pestCountPattern.FindAllStringSubmatch(m[1], -1) {
+			var n int
+			fmt.Sscanf(count[1], "%d", &n)
+			if count[2] == "failed" {
+				failed += n
+			} else {
+				passed += n
+			}
+		}
+		if passed > 0 || failed > 0 {
+			return passed, failed
+		}
+	}
+
+	if result.Success {
+		return 1, 0
+	}
+	return 0, 1
+}
+
+// ParseFailure parses test failures, from the JUnit log when it accounts for the failure and from
+// Pest's output otherwise
+func (p *PestParser) ParseFailure(result domain.TestResult) []domain.TestFailure {
+	if result.TimedOut {
+		return []domain.TestFailure{TimeoutFailure(result)}
+	}
+	if failures, err := p.junit.ParseFailure(result); err == nil && (len(failures) > 0 || result.Success) {
+		return failures
+	}
+	return p.parseTextFailure(result)
+}
+
+// parseTextFailure scrapes the "FAILED  Tests\Unit\FooTest > it does x" blocks of Pest's output
+func (p *PestParser) parseTextFailure(result domain.TestResult) []domain.TestFailure {
+	var failures []domain.TestFailure
+	lines := strings.Split(ansiPattern.ReplaceAllString(result.Output, ""), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		m := pestFailedPattern.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		failure := domain.TestFailure{
+			TestName:   m[2],
+			FilePath:   result.TestPath,
+			StackTrace: []string{},
+		}
+		var message []string
+		inSource := false
+		for i+1 < len(lines) && !pestFailedPattern.MatchString(lines[i+1]) && !pestTestsPattern.MatchString(lines[i+1]) {
+			i++
+			line := lines[i]
+			if at := pestAtPattern.FindStringSubmatch(line); at != nil && !inSource {
+				inSource = true
+				failure.File = at[1]
+				fmt.Sscanf(at[2], "%d", &failure.Line)
+				continue
+			}
+			if !inSource {
+				message = append(message, strings.TrimPrefix(line, "  "))
+				continue
+			}
+			if frame := pestFramePattern.FindStringSubmatch(line); frame != nil {
+				failure.StackTrace = append(failure.StackTrace, frame[1])
+			}
+		}
+		failure.Message, failure.ErrorDetails = extractJSONBlock(strings.TrimSpace(strings.Join(message, "\n")))
+		if failure.File == "" {
+			failure.File = result.TestPath
+		}
+		failures = append(failures, failure)
+	}
+	return failures
+}
@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"testing"
+
+	"ptp/internal/domain"
+)
+
+const pestOutput = "\n" +
+	"   FAIL  Tests\\Feature\\InvoiceTest\n" +
+	"  ✓ it totals lines                                                      0.01s  \n" +
+	"  ⨯ it sends reminders                                                   0.12s  \n" +
+	"  ⨯ `PDF` → it renders                                                   0.02s  \n" +
+	"  ───────────────────────────────────────────────────────────────────────────  \n" +
+	"   FAILED  Tests\\Feature\\InvoiceTest > it sends reminders                     \n" +
+	"  Expected response status code [200] but received 500.\n" +
+	"Failed asserting that 500 is identical to 200.\n" +
+	"\n" +
+	"  at tests/Feature/InvoiceTest.php:12\n" +
+	"      8▕ it('sends reminders', function () {\n" +
+	"  ➜  12▕     $response->assertOk();\n" +
+	"\n" +
+	"  1   tests/Feature/InvoiceTest.php:12\n" +
+	"\n" +
+	"  ───────────────────────────────────────────────────────────────────────────  \n" +
+	"   FAILED  Tests\\Feature\\InvoiceTest > `PDF` → it renders              Error   \n" +
+	"  Call to undefined function render()\n" +
+	"\n" +
+	"  at tests/Feature/InvoiceTest.php:20\n" +
+	"\n" +
+	"  1   vendor/pestphp/pest/src/Factories/TestCaseFactory.php:151\n" +
+	"  2   tests/Feature/InvoiceTest.php:20\n" +
+	"\n" +
+	"\n" +
+	"  Tests:    2 failed, 1 passed (3 assertions)\n" +
+	"  Duration: 0.18s\n"
+
+func TestPestParser_ParseFailure(t *testing.T) {
+	p := NewPestParser()
+	result := domain.TestResult{TestPath: "tests/Feature/InvoiceTest.php", Output: pestOutput}
+
+	failures := p.ParseFailure(result)
+	if len(failures) != 2 {
+		t.Fatalf("expected 2 failures, got %d: %+v", len(failures), failures)
+	}
+	f := failures[0]
+	if f.TestName != "it sends reminders" || f.FilePath != "tests/Feature/InvoiceTest.php" {
+		t.Errorf("unexpected failure %q in %q", f.TestName, f.FilePath)
+	}
+	if f.Message != "Expected response status code [200] but received 500.\nFailed asserting that 500 is identical to 200." {
+		t.Errorf("unexpected message %q", f.Message)
+	}
+	if f.File != "tests/Feature/InvoiceTest.php" || f.Line != 12 || len(f.StackTrace) != 1 {
+		t.Errorf("unexpected location %s:%d, stack %v", f.File, f.Line, f.StackTrace)
+	}
+	if f := failures[1]; f.TestName != "`PDF` → it renders" || f.Message != "Call to undefined function render()" || len(f.StackTrace) != 2 {
+		t.Errorf("unexpected second failure %+v", f)
+	}
+
+	passed, failed := p.ParseTestCounts(result)
+	if passed != 1 || failed != 2 {
+		t.Errorf("expected 1/2 from the summary, got %d/%d", passed, failed)
+	}
+}
+
+func TestPestParser_PrefersJUnitLog(t *testing.T) {
+	p := NewPestParser()
+	result := domain.TestResult{TestPath: "tests/Unit/UserTest.php", Log: []byte(junitLog), Output: pestOutput}
+	if failures := p.ParseFailure(result); len(failures) != 2 || failures[0].TestName != "testUpdate with data set #1" {
+		t.Errorf("expected the failures of the junit log, got %+v", failures)
+	}
+	if cases := p.ParseCases(result); len(cases) != 4 {
+		t.Errorf("expected 4 cases from the junit log, got %d", len(cases))
+	}
+}
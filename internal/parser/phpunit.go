@@ -8,17 +8,35 @@ import (
 	"ptp/internal/domain"
 )
 
-// PHPUnitParser parses PHPUnit test output
-type PHPUnitParser struct{}
+// PHPUnitParser parses PHPUnit test results. The JUnit log is preferred; the
+// human-readable output is only scraped when the log is missing (e.g. fatal errors).
+type PHPUnitParser struct {
+	junit *JUnitParser
+}
 
 // NewPHPUnitParser creates a new PHPUnitParser
 func NewPHPUnitParser() *PHPUnitParser {
-	return &PHPUnitParser{}
+	return &PHPUnitParser{junit: NewJUnitParser()}
+}
+
+// ParseCases returns per-case results from the JUnit log (nil when there is no usable log)
+func (p *PHPUnitParser) ParseCases(result domain.TestResult) []domain.TestCaseResult {
+	cases, err := p.junit.ParseCases(result)
+	if err != nil {
+		return nil
+	}
+	return cases
 }
 
 // ParseTestCounts extracts passed and failed test case counts from PHPUnit output.
 // Returns (passed, failed). If parsing fails, returns (1,0) for success or (0,1) for failure (file-level fallback).
 func (p *PHPUnitParser) ParseTestCounts(result domain.TestResult) (passed, failed int) {
+	if passed, failed, err := p.junit.ParseTestCounts(result); err == nil && (passed > 0 || failed > 0) {
+		if result.Success || failed > 0 {
+			return passed, failed
+		}
+	}
+
 	output := result.Output
 
 	// OK (N tests, ...) - all passed
@@ -58,8 +76,20 @@ func (p *PHPUnitParser) ParseTestCounts(result domain.TestResult) (passed, faile
 	return 0, 1
 }
 
-// ParseFailure parses test failure from PHPUnit output
+// ParseFailure parses test failures, from the JUnit log when it accounts for the
+// failure and from PHPUnit's text output otherwise.
 func (p *PHPUnitParser) ParseFailure(result domain.TestResult) []domain.TestFailure {
+	if result.TimedOut {
+		return []domain.TestFailure{TimeoutFailure(result)}
+	}
+	if failures, err := p.junit.ParseFailure(result); err == nil && (len(failures) > 0 || result.Success) {
+		return failures
+	}
+	return p.parseTextFailure(result)
+}
+
+// parseTextFailure scrapes failures from PHPUnit's human-readable output
+func (p *PHPUnitParser) parseTextFailure(result domain.TestResult) []domain.TestFailure {
 	var failures []domain.TestFailure
 	str := strings.Split(result.Output, "\n")
 
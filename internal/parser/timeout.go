@@ -0,0 +1,38 @@
+package parser
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"ptp/internal/domain"
+)
+
+// TimeoutTailLines is how many lines of captured output are kept in a timeout failure
+const TimeoutTailLines = 30
+
+// TimeoutFailure builds the failure recorded for a test file that was killed after exceeding its
+// timeout. PHPUnit never got to report anything, so the tail of its output is the only hint of
+// where it hung. The test name is the class name, so a rerun with --filter runs the whole file.
+func TimeoutFailure(result domain.TestResult) domain.TestFailure {
+	message := fmt.Sprintf("timed out after %gs", result.Timeout.Seconds())
+	if tail := outputTail(result.Output, TimeoutTailLines); tail != "" {
+		message += "\n\nLast output:\n" + tail
+	}
+	return domain.TestFailure{
+		TestName:   strings.TrimSuffix(filepath.Base(result.TestPath), ".php"),
+		FilePath:   result.TestPath,
+		StackTrace: []string{},
+		File:       result.TestPath,
+		Message:    message,
+	}
+}
+
+// outputTail returns the last n lines of output, without trailing blank lines
+func outputTail(output string, n int) string {
+	lines := strings.Split(strings.TrimRight(output, "\n\r\t "), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,38 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"ptp/internal/domain"
+)
+
+func TestPHPUnitParser_TimeoutFailure(t *testing.T) {
+	var output strings.Builder
+	for i := 1; i <= 50; i++ {
+		fmt.Fprintf(&output, "line %d\n", i)
+	}
+	result := domain.TestResult{
+		TestPath: "tests/Integration/QueueTest.php",
+		Output:   output.String(),
+		TimedOut: true,
+		Timeout:  90 * time.Second,
+	}
+
+	failures := NewPHPUnitParser().ParseFailure(result)
+	if len(failures) != 1 {
+		t.Fatalf("expected one synthetic failure, got %d", len(failures))
+	}
+	f := failures[0]
+	if f.TestName != "QueueTest" || f.FilePath != result.TestPath {
+		t.Errorf("unexpected failure identity: %+v", f)
+	}
+	if !strings.HasPrefix(f.Message, "timed out after 90s") {
+		t.Errorf("unexpected message: %q", f.Message)
+	}
+	if !strings.HasSuffix(f.Message, "line 50") || strings.Contains(f.Message, "line 20\n") {
+		t.Errorf("expected the last %d output lines, got %q", TimeoutTailLines, f.Message)
+	}
+}
@@ -0,0 +1,24 @@
+// Package proc starts the external processes PTP drives (phpunit, artisan) so that
+// cancelling their context stops the whole process tree, not just the direct child.
+package proc
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// WaitDelay bounds how long Wait keeps waiting for output pipes after a cancelled process was killed
+const WaitDelay = 5 * time.Second
+
+// Command is like exec.CommandContext, but the process runs in its own process group and
+// cancelling ctx kills the entire group (e.g. phpunit together with the php processes it forked).
+func Command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd)
+	}
+	cmd.WaitDelay = WaitDelay
+	return cmd
+}
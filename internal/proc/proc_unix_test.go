@@ -0,0 +1,32 @@
+//go:build !windows
+
+package proc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCommand_CancelKillsProcessGroup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	// The shell waits on a grandchild; killing only the shell would leave sleep holding stdout open
+	cmd := Command(ctx, "sh", "-c", "sleep 30 & wait")
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := cmd.CombinedOutput()
+		done <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected an error from the killed command")
+		}
+	case <-time.After(WaitDelay / 2):
+		t.Fatal("command was not stopped after cancel")
+	}
+}
@@ -0,0 +1,157 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ptp/internal/config"
+	"ptp/internal/debug"
+	"ptp/internal/domain"
+	"ptp/internal/execution"
+)
+
+// connectTimeout bounds the handshake with an agent; jobs themselves run without a time limit
+const connectTimeout = 5 * time.Second
+
+// Agent is the coordinator's connection to a `ptp serve-worker` agent
+type Agent struct {
+	config   *config.Config
+	addr     string
+	revision string
+	info     Info
+	client   *http.Client
+}
+
+// Connect asks the agent at addr (host or host:port) for its info and checks that it serves the
+// coordinator's revision
+func Connect(ctx context.Context, cfg *config.Config, addr, revision string) (*Agent, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, DefaultPort)
+	}
+	a := &Agent{config: cfg, addr: addr, revision: revision, client: &http.Client{}}
+
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.url(infoPath), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("connect to %s: %w", addr, err)
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return nil, fmt.Errorf("%s: %w", addr, err)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&a.info); err != nil {
+		return nil, fmt.Errorf("%s: invalid info: %w", addr, err)
+	}
+	if a.info.Revision != revision {
+		return nil, fmt.Errorf("%s serves revision %s, the project is at %s", addr, a.info.Revision, revision)
+	}
+	debug.Logf("remote: connected to %s (%d slots, %s)", addr, a.info.Slots, a.info.Framework)
+	return a, nil
+}
+
+// Addr returns the agent's host:port
+func (a *Agent) Addr() string {
+	return a.addr
+}
+
+// Slots returns one pool slot per slot of the agent
+func (a *Agent) Slots() []execution.RemoteSlot {
+	slots := make([]execution.RemoteSlot, a.info.Slots)
+	for i := range slots {
+		slots[i] = &slot{agent: a, index: i + 1}
+	}
+	return slots
+}
+
+// slot is one of an agent's slots in the coordinator's worker pool
+type slot struct {
+	agent *Agent
+	index int
+}
+
+func (s *slot) String() string {
+	return fmt.Sprintf("%s#%d", s.agent.addr, s.index)
+}
+
+// Run runs the job on the agent. A cancelled ctx yields an interrupted result, as for local runs;
+// errors mean the agent did not run the job.
+func (s *slot) Run(ctx context.Context, job execution.Job, workerID int) (domain.TestResult, error) {
+	a := s.agent
+	include, exclude := a.config.GetGroups()
+	body, err := json.Marshal(RunRequest{
+		Revision:     a.revision,
+		Path:         a.relativePath(job.Path),
+		Filter:       job.Filter,
+		Group:        strings.Join(include, ","),
+		ExcludeGroup: strings.Join(exclude, ","),
+		Timeout:      a.config.TimeoutFor(job.Path),
+	})
+	if err != nil {
+		return domain.TestResult{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url(runPath), bytes.NewReader(body))
+	if err != nil {
+		return domain.TestResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	interrupted := domain.TestResult{TestPath: job.Path, WorkerID: workerID, Interrupted: true}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return interrupted, nil
+		}
+		return domain.TestResult{}, err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return domain.TestResult{}, err
+	}
+	var res Result
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		if ctx.Err() != nil {
+			return interrupted, nil
+		}
+		return domain.TestResult{}, fmt.Errorf("invalid result: %w", err)
+	}
+	debug.Logf("remote: %s ran %s in %s (success=%v)", s, job, res.Duration, res.Success)
+	return res.testResult(job.Path, workerID), nil
+}
+
+// relativePath returns a test path relative to the project root, as the agent expects it
+func (a *Agent) relativePath(path string) string {
+	if filepath.IsAbs(path) {
+		if root, err := filepath.Abs(a.config.ProjectPath); err == nil {
+			if rel, err := filepath.Rel(root, path); err == nil {
+				path = rel
+			}
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(path))
+}
+
+func (a *Agent) url(path string) string {
+	return "http://" + a.addr + path
+}
+
+// checkResponse turns a non-200 answer into an error with the agent's message
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return fmt.Errorf("agent answered %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+}
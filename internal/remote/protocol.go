@@ -0,0 +1,104 @@
+// Package remote runs test files on other machines: `ptp serve-worker` agents execute the jobs the
+// coordinator's worker pool hands to their slots over HTTP (see --remote).
+//
+// The protocol has two endpoints. GET /v1/info describes the agent (revision and slots); POST
+// /v1/run runs one job and answers with its result once the file finished. Every run request carries
+// the coordinator's revision, and an agent whose checkout differed at the last /v1/info refuses the
+// job with 409 Conflict.
+package remote
+
+import (
+	"errors"
+	"path/filepath"
+	"time"
+
+	"ptp/internal/config"
+	"ptp/internal/domain"
+	"ptp/internal/vcs"
+)
+
+// DefaultPort is the port `ptp serve-worker` listens on and --remote addresses default to
+const DefaultPort = "7420"
+
+const (
+	infoPath = "/v1/info"
+	runPath  = "/v1/run"
+)
+
+// Revision returns the revision of the project the coordinator and its agents must agree on. The
+// results written by ptp itself are not part of it.
+func Revision(cfg *config.Config) (string, error) {
+	var exclude []string
+	root, err1 := filepath.Abs(cfg.ProjectPath)
+	output, err2 := filepath.Abs(filepath.Dir(cfg.GetOutputPath()))
+	if err1 == nil && err2 == nil {
+		if rel, err := filepath.Rel(root, output); err == nil && filepath.IsLocal(rel) {
+			exclude = append(exclude, rel)
+		}
+	}
+	return vcs.Revision(cfg.ProjectPath, exclude...)
+}
+
+// Info describes an agent
+type Info struct {
+	Revision  string `json:"revision"`
+	Slots     int    `json:"slots"`
+	Framework string `json:"framework"`
+}
+
+// RunRequest asks an agent to run a job. Path is relative to the project root, slash-separated.
+type RunRequest struct {
+	Revision     string        `json:"revision"`
+	Path         string        `json:"path"`
+	Filter       string        `json:"filter,omitempty"`
+	Group        string        `json:"group,omitempty"`
+	ExcludeGroup string        `json:"exclude_group,omitempty"`
+	Timeout      time.Duration `json:"timeout,omitempty"`
+}
+
+// Result is the outcome of a job on the wire (domain.TestResult without the coordinator's fields)
+type Result struct {
+	Success     bool          `json:"success"`
+	Output      string        `json:"output"`
+	Error       string        `json:"error,omitempty"`
+	Duration    time.Duration `json:"duration"`
+	Log         []byte        `json:"log,omitempty"`
+	Interrupted bool          `json:"interrupted,omitempty"`
+	TimedOut    bool          `json:"timed_out,omitempty"`
+	Timeout     time.Duration `json:"timeout,omitempty"`
+}
+
+func toResult(r domain.TestResult) Result {
+	res := Result{
+		Success:     r.Success,
+		Output:      r.Output,
+		Duration:    r.Duration,
+		Log:         r.Log,
+		Interrupted: r.Interrupted,
+		TimedOut:    r.TimedOut,
+		Timeout:     r.Timeout,
+	}
+	if r.Error != nil {
+		res.Error = r.Error.Error()
+	}
+	return res
+}
+
+// testResult converts a wire result back for the coordinator's file and worker
+func (r Result) testResult(testPath string, workerID int) domain.TestResult {
+	result := domain.TestResult{
+		TestPath:    testPath,
+		Success:     r.Success,
+		Output:      r.Output,
+		Duration:    r.Duration,
+		WorkerID:    workerID,
+		Log:         r.Log,
+		Interrupted: r.Interrupted,
+		TimedOut:    r.TimedOut,
+		Timeout:     r.Timeout,
+	}
+	if r.Error != "" {
+		result.Error = errors.New(r.Error)
+	}
+	return result
+}
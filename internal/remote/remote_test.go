@@ -0,0 +1,183 @@
+package remote
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ptp/internal/config"
+	"ptp/internal/domain"
+	"ptp/internal/execution"
+)
+
+// newTestServer starts an agent at revision "abc" whose jobs are run by run
+func newTestServer(t *testing.T, slots int, run func(ctx context.Context, cfg *config.Config, req RunRequest, slot int) domain.TestResult) (*Server, string) {
+	t.Helper()
+	s := NewServer(config.New(), slots)
+	s.revision = func() (string, error) { return "abc", nil }
+	s.run = run
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return s, strings.TrimPrefix(ts.URL, "http://")
+}
+
+func TestAgent_Run(t *testing.T) {
+	var got RunRequest
+	_, addr := newTestServer(t, 2, func(ctx context.Context, cfg *config.Config, req RunRequest, slot int) domain.TestResult {
+		got = req
+		if cfg.Flags.Group != "slow" {
+			t.Errorf("expected the request's groups in the job's config, got %q", cfg.Flags.Group)
+		}
+		return domain.TestResult{TestPath: req.Path, Output: "FAILURES!", Duration: time.Second, Log: []byte("<testsuites/>"), WorkerID: slot}
+	})
+
+	cfg := config.New()
+	cfg.Flags.Group = "slow"
+	agent, err := Connect(context.Background(), cfg, addr, "abc")
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	slots := agent.Slots()
+	if len(slots) != 2 || slots[1].String() != addr+"#2" {
+		t.Fatalf("unexpected slots %v", slots)
+	}
+
+	result, err := slots[0].Run(context.Background(), execution.Job{Path: "tests/UserTest.php", Filter: "testCreate"}, 5)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got.Path != "tests/UserTest.php" || got.Filter != "testCreate" || got.Revision != "abc" {
+		t.Errorf("unexpected request %+v", got)
+	}
+	if result.TestPath != "tests/UserTest.php" || result.WorkerID != 5 || result.Success || result.Output != "FAILURES!" ||
+		string(result.Log) != "<testsuites/>" || result.Duration != time.Second {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestAgent_RevisionMismatch(t *testing.T) {
+	_, addr := newTestServer(t, 1, func(ctx context.Context, cfg *config.Config, req RunRequest, slot int) domain.TestResult {
+		t.Error("a job of another revision was run")
+		return domain.TestResult{}
+	})
+	if _, err := Connect(context.Background(), config.New(), addr, "def"); err == nil {
+		t.Error("expected Connect to refuse an agent at another revision")
+	}
+
+	// The agent checks every job too, against the revision of its last handshake
+	agent := &Agent{config: config.New(), addr: addr, revision: "def", info: Info{Slots: 1}, client: &http.Client{}}
+	_, err := agent.Slots()[0].Run(context.Background(), execution.Job{Path: "tests/UserTest.php"}, 1)
+	if err == nil || !strings.Contains(err.Error(), "revision mismatch") {
+		t.Errorf("expected a revision mismatch, got %v", err)
+	}
+}
+
+func TestAgent_Disconnect(t *testing.T) {
+	started := make(chan struct{})
+	s := NewServer(config.New(), 1)
+	s.revision = func() (string, error) { return "abc", nil }
+	s.run = func(ctx context.Context, cfg *config.Config, req RunRequest, slot int) domain.TestResult {
+		close(started)
+		<-ctx.Done()
+		return domain.TestResult{Interrupted: true}
+	}
+	ts := httptest.NewServer(s.Handler())
+	agent, err := Connect(context.Background(), config.New(), strings.TrimPrefix(ts.URL, "http://"), "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		<-started
+		ts.CloseClientConnections()
+		ts.Close()
+	}()
+	if _, err := agent.Slots()[0].Run(context.Background(), execution.Job{Path: "tests/UserTest.php"}, 1); err == nil {
+		t.Error("expected an error when the agent goes away mid-job")
+	}
+}
+
+func TestAgent_Cancel(t *testing.T) {
+	_, addr := newTestServer(t, 1, func(ctx context.Context, cfg *config.Config, req RunRequest, slot int) domain.TestResult {
+		<-ctx.Done()
+		return domain.TestResult{Interrupted: true}
+	})
+	agent, err := Connect(context.Background(), config.New(), addr, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result, err := agent.Slots()[0].Run(ctx, execution.Job{Path: "tests/UserTest.php"}, 1)
+	if err != nil || !result.Interrupted {
+		t.Errorf("expected an interrupted result on cancel, got %+v, %v", result, err)
+	}
+}
+
+func TestServer_RejectsPathsOutsideProject(t *testing.T) {
+	_, addr := newTestServer(t, 1, func(ctx context.Context, cfg *config.Config, req RunRequest, slot int) domain.TestResult {
+		t.Errorf("ran %s", req.Path)
+		return domain.TestResult{}
+	})
+	agent, err := Connect(context.Background(), config.New(), addr, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"../other/Test.php", "/etc/passwd"} {
+		agent.config.ProjectPath = "/nonexistent"
+		if _, err := agent.Slots()[0].Run(context.Background(), execution.Job{Path: path}, 1); err == nil {
+			t.Errorf("expected %s to be refused", path)
+		}
+	}
+}
+
+func TestServer_RevisionPerHandshake(t *testing.T) {
+	s, addr := newTestServer(t, 1, func(ctx context.Context, cfg *config.Config, req RunRequest, slot int) domain.TestResult {
+		return domain.TestResult{TestPath: req.Path, Success: true}
+	})
+	calls := 0
+	s.revision = func() (string, error) {
+		calls++
+		return "abc", nil
+	}
+
+	agent, err := Connect(context.Background(), config.New(), addr, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if _, err := agent.Slots()[0].Run(context.Background(), execution.Job{Path: "tests/UserTest.php"}, 1); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected the revision to be computed once per handshake, got %d calls", calls)
+	}
+}
+
+func TestAgent_SendsPerPathTimeout(t *testing.T) {
+	var got RunRequest
+	_, addr := newTestServer(t, 1, func(ctx context.Context, cfg *config.Config, req RunRequest, slot int) domain.TestResult {
+		got = req
+		if cfg.TimeoutFor(req.Path) != 10*time.Minute {
+			t.Errorf("expected the coordinator's limit to apply on the agent, got %s", cfg.TimeoutFor(req.Path))
+		}
+		return domain.TestResult{TestPath: req.Path, Success: true}
+	})
+
+	cfg := config.New()
+	cfg.Timeout = time.Minute
+	cfg.Timeouts = map[string]time.Duration{"tests/Integration": 10 * time.Minute}
+	agent, err := Connect(context.Background(), cfg, addr, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := agent.Slots()[0].Run(context.Background(), execution.Job{Path: "tests/Integration/ImportTest.php"}, 1); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got.Timeout != 10*time.Minute {
+		t.Errorf("expected the per-path timeout in the request, got %s", got.Timeout)
+	}
+}
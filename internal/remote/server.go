@@ -0,0 +1,173 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"ptp/internal/config"
+	"ptp/internal/debug"
+	"ptp/internal/domain"
+	"ptp/internal/execution"
+)
+
+// Server is the agent side: it runs the jobs of coordinators with the local runner, one per slot.
+// Slot i uses worker i's database and environment, as local worker i would.
+type Server struct {
+	config   *config.Config
+	slots    chan int
+	revision func() (string, error)
+	run      func(ctx context.Context, cfg *config.Config, req RunRequest, slot int) domain.TestResult
+
+	// current is the revision computed at the last handshake; jobs are checked against it,
+	// so the (expensive) revision is not recomputed for every test file
+	mu      sync.Mutex
+	current string
+}
+
+// NewServer creates a Server with the given number of slots for the project of cfg
+func NewServer(cfg *config.Config, slots int) *Server {
+	slots = max(slots, 1)
+	s := &Server{
+		config:   cfg,
+		slots:    make(chan int, slots),
+		revision: func() (string, error) { return Revision(cfg) },
+		run:      runJob,
+	}
+	for i := 1; i <= slots; i++ {
+		s.slots <- i
+	}
+	return s
+}
+
+// Slots returns the number of jobs the server runs at the same time
+func (s *Server) Slots() int {
+	return cap(s.slots)
+}
+
+// Handler returns the HTTP handler of the protocol
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+infoPath, s.handleInfo)
+	mux.HandleFunc("POST "+runPath, s.handleRun)
+	return mux
+}
+
+// Serve serves the protocol on l until ctx is done; running jobs are killed on shutdown
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	srv := &http.Server{
+		Handler:     s.Handler(),
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+	if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serve: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
+	revision, err := s.refreshRevision()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("no project revision: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, Info{Revision: revision, Slots: s.Slots(), Framework: s.config.GetFramework()})
+}
+
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	var req RunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid run request: %v", err), http.StatusBadRequest)
+		return
+	}
+	// Only files inside the project are run
+	if req.Path == "" || !filepath.IsLocal(filepath.FromSlash(req.Path)) {
+		http.Error(w, fmt.Sprintf("invalid test path %q", req.Path), http.StatusBadRequest)
+		return
+	}
+	revision, err := s.cachedRevision()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("no project revision: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if req.Revision != revision {
+		debug.Logf("remote: refusing %s from %s: revision %s, serving %s", req.Path, r.RemoteAddr, req.Revision, revision)
+		http.Error(w, fmt.Sprintf("revision mismatch: coordinator is at %s, agent at %s", req.Revision, revision), http.StatusConflict)
+		return
+	}
+
+	var slot int
+	select {
+	case slot = <-s.slots:
+	case <-r.Context().Done():
+		return
+	}
+	defer func() { s.slots <- slot }()
+
+	// Per-request options apply to this job only
+	cfg := *s.config
+	cfg.Flags.Group, cfg.Flags.ExcludeGroup = req.Group, req.ExcludeGroup
+	if req.Timeout > 0 {
+		// The coordinator already applied its per-path overrides
+		cfg.Timeout, cfg.Timeouts = req.Timeout, nil
+	}
+	debug.Logf("remote: running %s (filter %q) for %s on slot %d", req.Path, req.Filter, r.RemoteAddr, slot)
+	result := s.run(r.Context(), &cfg, req, slot)
+	if r.Context().Err() != nil {
+		// The coordinator is gone or cancelled the run; nobody reads the answer
+		return
+	}
+	writeJSON(w, toResult(result))
+}
+
+// refreshRevision computes the project's revision and remembers it for the jobs that follow
+func (s *Server) refreshRevision() (string, error) {
+	revision, err := s.revision()
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	s.current = revision
+	s.mu.Unlock()
+	return revision, nil
+}
+
+// cachedRevision returns the revision of the last handshake, computing it when there was none
+func (s *Server) cachedRevision() (string, error) {
+	s.mu.Lock()
+	revision := s.current
+	s.mu.Unlock()
+	if revision != "" {
+		return revision, nil
+	}
+	return s.refreshRevision()
+}
+
+// runJob runs a job with the local runner
+func runJob(ctx context.Context, cfg *config.Config, req RunRequest, slot int) domain.TestResult {
+	runner := execution.NewRunner(cfg)
+	path := filepath.FromSlash(req.Path)
+	if req.Filter != "" {
+		return runner.RunFiltered(ctx, path, req.Filter, slot)
+	}
+	return runner.Run(ctx, path, slot)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		debug.Logf("remote: writing response failed: %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+package report
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ptp/internal/debug"
+	"ptp/internal/discovery"
+	"ptp/internal/domain"
+	"ptp/internal/vcs"
+)
+
+// CI report formats of `ptp run --report` and `ptp report`
+const (
+	FormatGitHub = "github"
+	FormatGitLab = "gitlab"
+)
+
+// CodeQualityFile is where `--report=gitlab` writes the Code Quality report, relative to the project
+const CodeQualityFile = "gl-code-quality-report.json"
+
+// maxSummaryFailures caps the failures detailed in the GitHub job summary, which is limited to 1 MiB
+const maxSummaryFailures = 100
+
+// Locator resolves where failures are reported in CI: paths relative to the repository root, so
+// the annotations land on the files of the pull request
+type Locator struct {
+	projectPath string
+	root        string
+}
+
+// NewLocator creates a Locator for the results of the project at projectPath. Without a git work
+// tree, paths are relative to the project.
+func NewLocator(projectPath string) *Locator {
+	if abs, err := filepath.Abs(projectPath); err == nil {
+		projectPath = abs
+	}
+	root, err := vcs.Root(projectPath)
+	if err != nil {
+		debug.Logf("report: annotating paths relative to the project: %v", err)
+		root = projectPath
+	}
+	return &Locator{projectPath: projectPath, root: root}
+}
+
+// Locate returns the file and line of a failure: where PHPUnit reported it or, when it did not,
+// the test method in the test file (line 0 when not found)
+func (l *Locator) Locate(f domain.TestFailure) (string, int) {
+	if f.File != "" && f.Line > 0 {
+		return l.relative(f.File), f.Line
+	}
+	path := f.FilePath
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(l.projectPath, path)
+	}
+	if _, err := os.Stat(path); err != nil && !strings.HasSuffix(path, ".php") {
+		path += ".php"
+	}
+	return l.relative(path), discovery.FindTestFunctionLine(path, f.TestName)
+}
+
+// relative returns path relative to the repository root, with forward slashes
+func (l *Locator) relative(path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(l.projectPath, path)
+	}
+	root := l.root
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if rel, err := filepath.Rel(root, path); err == nil && filepath.IsLocal(rel) {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(path)
+}
+
+// WriteGitHubAnnotations writes a workflow command per failure, so GitHub Actions annotates the
+// failing lines: errors for failures, warnings for flaky test cases
+func WriteGitHubAnnotations(w io.Writer, out *domain.TestResultsOutput, loc *Locator) error {
+	for _, f := range out.Details {
+		level, title := "error", f.TestName
+		if f.Flaky {
+			level, title = "warning", "Flaky: "+f.TestName
+		}
+		path, line := loc.Locate(f)
+		props := "file=" + escapeProperty(path)
+		if line > 0 {
+			props += fmt.Sprintf(",line=%d", line)
+		}
+		props += ",title=" + escapeProperty(title)
+		if _, err := fmt.Fprintf(w, "::%s %s::%s\n", level, props, escapeData(failureText(f))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escapeData escapes the message of a workflow command
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a property value of a workflow command
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// WriteGitHubSummary writes the run as Markdown for the job summary ($GITHUB_STEP_SUMMARY)
+func WriteGitHubSummary(w io.Writer, out *domain.TestResultsOutput, loc *Locator) error {
+	m := out.Meta
+	failures := domain.WithoutFlaky(out.Details)
+	var b strings.Builder
+	icon := "✅"
+	if len(failures) > 0 || m.FailedTestFiles > 0 {
+		icon = "❌"
+	}
+	title := "Test results"
+	if m.Shard != "" {
+		title += " (shard " + m.Shard + ")"
+	}
+	fmt.Fprintf(&b, "## %s %s\n\n", icon, title)
+	b.WriteString("| Test files | Failed files | Failed test cases | Flaky | Duration | Workers |\n")
+	b.WriteString("| ---: | ---: | ---: | ---: | ---: | ---: |\n")
+	fmt.Fprintf(&b, "| %d | %d | %d | %d | %s | %d |\n", m.TotalTestFiles, m.FailedTestFiles, m.FailedTestCases, m.FlakyTestCases, m.Duration, m.Workers)
+
+	if len(failures) > 0 {
+		fmt.Fprintf(&b, "\n### Failed test cases (%d)\n\n", len(failures))
+		for i, f := range failures {
+			if i == maxSummaryFailures {
+				fmt.Fprintf(&b, "_%d more failure(s) not shown, see the annotations or `ptp faills`._\n", len(failures)-i)
+				break
+			}
+			path, line := loc.Locate(f)
+			if line > 0 {
+				path = fmt.Sprintf("%s:%d", path, line)
+			}
+			fmt.Fprintf(&b, "<details><summary><code>%s</code> %s</summary>\n\n```text\n%s\n```\n\n</details>\n",
+				htmlText(path), htmlText(f.TestName), strings.ReplaceAll(failureText(f), "```", "'''"))
+		}
+	}
+	if flaky := domain.CountFlaky(out.Details); flaky > 0 {
+		fmt.Fprintf(&b, "\n### Flaky test cases (%d)\n\n", flaky)
+		for _, f := range out.Details {
+			if f.Flaky {
+				fmt.Fprintf(&b, "- `%s::%s`\n", filepath.ToSlash(f.FilePath), f.TestName)
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// AppendGitHubSummary appends the job summary to the file named by $GITHUB_STEP_SUMMARY; it does
+// nothing outside GitHub Actions
+func AppendGitHubSummary(out *domain.TestResultsOutput, loc *Locator) error {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		debug.Log("report: GITHUB_STEP_SUMMARY is not set, no job summary written")
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open job summary: %w", err)
+	}
+	if err := WriteGitHubSummary(f, out, loc); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func htmlText(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// codeQualityIssue is an entry of a GitLab Code Quality report
+type codeQualityIssue struct {
+	Description string              `json:"description"`
+	CheckName   string              `json:"check_name"`
+	Fingerprint string              `json:"fingerprint"`
+	Severity    string              `json:"severity"`
+	Location    codeQualityLocation `json:"location"`
+}
+
+type codeQualityLocation struct {
+	Path  string           `json:"path"`
+	Lines codeQualityLines `json:"lines"`
+}
+
+type codeQualityLines struct {
+	Begin int `json:"begin"`
+}
+
+// WriteCodeQuality writes the failures as a GitLab Code Quality report: failures are major issues,
+// flaky test cases minor ones. The fingerprint depends on the test case only, so GitLab can tell
+// new failures of a merge request from the ones of its target branch.
+func WriteCodeQuality(w io.Writer, out *domain.TestResultsOutput, loc *Locator) error {
+	issues := make([]codeQualityIssue, 0, len(out.Details))
+	for _, f := range out.Details {
+		path, line := loc.Locate(f)
+		checkName, severity := "ptp-failed-test", "major"
+		if f.Flaky {
+			checkName, severity = "ptp-flaky-test", "minor"
+		}
+		sum := md5.Sum([]byte(checkName + "\x00" + filepath.ToSlash(f.FilePath) + "\x00" + f.TestName))
+		issues = append(issues, codeQualityIssue{
+			Description: fmt.Sprintf("%s: %s", f.TestName, FirstLine(f.Message)),
+			CheckName:   checkName,
+			Fingerprint: hex.EncodeToString(sum[:]),
+			Severity:    severity,
+			Location:    codeQualityLocation{Path: path, Lines: codeQualityLines{Begin: max(line, 1)}},
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(issues); err != nil {
+		return fmt.Errorf("encode code quality report: %w", err)
+	}
+	return nil
+}
+
+// WriteCodeQualityFile writes the Code Quality report to path, creating parent directories as needed
+func WriteCodeQualityFile(path string, out *domain.TestResultsOutput, loc *Locator) error {
+	return writeFile(path, func(w io.Writer) error { return WriteCodeQuality(w, out, loc) })
+}
@@ -0,0 +1,112 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ptp/internal/domain"
+)
+
+// ciResults returns a run of a project (without git) with a failure reported with its location,
+// one without (found from the test method) and a flaky test case
+func ciResults(t *testing.T) (*domain.TestResultsOutput, *Locator) {
+	dir := t.TempDir()
+	src := "<?php\nclass UserTest extends TestCase\n{\n    public function test_update_user(): void\n    {\n    }\n}\n"
+	if err := os.MkdirAll(filepath.Join(dir, "tests"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tests", "UserTest.php"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := &domain.TestResultsOutput{
+		Meta: domain.TestResultsMeta{TotalTestFiles: 3, FailedTestFiles: 2, FailedTestCases: 2, FlakyTestCases: 1, Duration: "4s", Workers: 2},
+		Details: []domain.TestFailure{
+			{TestName: "testCreate", FilePath: "tests/OrderTest.php", File: filepath.Join(dir, "tests", "OrderTest.php"), Line: 12, Message: "Failed asserting that 1, 2 are equal.\n100% off"},
+			{TestName: "testUpdateUser with data set #1", FilePath: "tests/UserTest", Message: "Boom"},
+			{TestName: "testRace", FilePath: "tests/RaceTest.php", File: filepath.Join(dir, "tests", "RaceTest.php"), Line: 3, Message: "Race lost", Flaky: true},
+		},
+	}
+	return out, NewLocator(dir)
+}
+
+func TestWriteGitHubAnnotations(t *testing.T) {
+	out, loc := ciResults(t)
+	var buf bytes.Buffer
+	if err := WriteGitHubAnnotations(&buf, out, loc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		"::error file=tests/OrderTest.php,line=12,title=testCreate::Failed asserting that 1, 2 are equal.%0A100%25 off",
+		"::error file=tests/UserTest.php,line=4,title=testUpdateUser with data set #1::Boom",
+		"::warning file=tests/RaceTest.php,line=3,title=Flaky%3A testRace::Race lost",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d annotations, got %q", len(want), lines)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("annotation %d =\n%s\nwant\n%s", i, lines[i], want[i])
+		}
+	}
+}
+
+func TestWriteGitHubSummary(t *testing.T) {
+	out, loc := ciResults(t)
+	var buf bytes.Buffer
+	if err := WriteGitHubSummary(&buf, out, loc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := buf.String()
+	for _, want := range []string{
+		"## ❌ Test results",
+		"| 3 | 2 | 2 | 1 | 4s | 2 |",
+		"### Failed test cases (2)",
+		"<code>tests/UserTest.php:4</code> testUpdateUser with data set #1",
+		"### Flaky test cases (1)\n\n- `tests/RaceTest.php::testRace`",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("expected the summary to contain %q, got:\n%s", want, s)
+		}
+	}
+}
+
+func TestWriteCodeQuality(t *testing.T) {
+	out, loc := ciResults(t)
+	var buf bytes.Buffer
+	if err := WriteCodeQuality(&buf, out, loc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var issues []codeQualityIssue
+	if err := json.Unmarshal(buf.Bytes(), &issues); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(issues) != 3 {
+		t.Fatalf("expected 3 issues, got %d", len(issues))
+	}
+	if got := issues[0]; got.Severity != "major" || got.Location.Path != "tests/OrderTest.php" || got.Location.Lines.Begin != 12 ||
+		got.Description != "testCreate: Failed asserting that 1, 2 are equal." {
+		t.Errorf("unexpected issue %+v", got)
+	}
+	if got := issues[2]; got.Severity != "minor" || got.CheckName != "ptp-flaky-test" {
+		t.Errorf("expected the flaky test case as a minor issue, got %+v", got)
+	}
+
+	// Fingerprints identify the test case, not where or how it failed
+	out.Details[0].Line, out.Details[0].Message = 14, "other"
+	var again bytes.Buffer
+	if err := WriteCodeQuality(&again, out, loc); err != nil {
+		t.Fatal(err)
+	}
+	var moved []codeQualityIssue
+	if err := json.Unmarshal(again.Bytes(), &moved); err != nil {
+		t.Fatal(err)
+	}
+	if moved[0].Fingerprint != issues[0].Fingerprint || issues[0].Fingerprint == issues[1].Fingerprint {
+		t.Errorf("unexpected fingerprints %q, %q, %q", issues[0].Fingerprint, moved[0].Fingerprint, issues[1].Fingerprint)
+	}
+}
@@ -0,0 +1,243 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"ptp/internal/domain"
+)
+
+// DefaultSlowerThreshold is the default relative slowdown (percent) reported by CompareRuns
+const DefaultSlowerThreshold = 20
+
+// MinSlowdownSeconds keeps sub-second noise on fast files out of the slower list
+const MinSlowdownSeconds = 0.5
+
+// CompareRuns classifies the failures of head against base (newly failing, fixed, still failing,
+// changed message) and lists the files that got at least threshold percent slower. Failures of
+// files that did not run in head are not reported as fixed. timings, when given, adds the
+// historical average duration of each slower file.
+func CompareRuns(base, head *domain.TestResultsOutput, threshold float64, timings map[string]*domain.TestTiming) *domain.RunDiff {
+	d := &domain.RunDiff{Base: base.Meta.RunInfo, Head: head.Meta.RunInfo, SlowerThreshold: threshold}
+
+	baseFailures := failuresByKey(base.Details)
+	headFailures := failuresByKey(head.Details)
+	ranInHead := ranFiles(head)
+
+	for key, h := range headFailures {
+		b, ok := baseFailures[key]
+		if !ok {
+			d.NewlyFailing = append(d.NewlyFailing, domain.FailureChange{FilePath: h.FilePath, TestName: h.TestName, HeadMessage: h.Message})
+			continue
+		}
+		change := domain.FailureChange{FilePath: h.FilePath, TestName: h.TestName, BaseMessage: b.Message, HeadMessage: h.Message}
+		if FirstLine(b.Message) == FirstLine(h.Message) {
+			d.StillFailing = append(d.StillFailing, change)
+		} else {
+			d.ChangedMessage = append(d.ChangedMessage, change)
+		}
+	}
+	for key, b := range baseFailures {
+		if _, ok := headFailures[key]; ok {
+			continue
+		}
+		if ranInHead != nil && !ranInHead[fileKey(b.FilePath)] {
+			d.NotRun++
+			continue
+		}
+		d.Fixed = append(d.Fixed, domain.FailureChange{FilePath: b.FilePath, TestName: b.TestName, BaseMessage: b.Message})
+	}
+	for _, changes := range [][]domain.FailureChange{d.NewlyFailing, d.Fixed, d.StillFailing, d.ChangedMessage} {
+		sortChanges(changes)
+	}
+
+	d.Slower = slowerFiles(base, head, threshold, timings)
+	return d
+}
+
+// failuresByKey indexes the real (non-flaky) failures of a run by their normalized key
+func failuresByKey(failures []domain.TestFailure) map[string]domain.TestFailure {
+	out := make(map[string]domain.TestFailure, len(failures))
+	for _, f := range domain.WithoutFlaky(failures) {
+		out[fileKey(f.FilePath)+"::"+strings.TrimSpace(f.TestName)] = f
+	}
+	return out
+}
+
+// ranFiles returns the files that ran to completion in a run, or nil if the run has no
+// per-file results (older result files), in which case every file is assumed to have run
+func ranFiles(run *domain.TestResultsOutput) map[string]bool {
+	if len(run.Files) == 0 {
+		return nil
+	}
+	ran := make(map[string]bool, len(run.Files))
+	for _, f := range run.Files {
+		if !f.Interrupted {
+			ran[fileKey(f.Path)] = true
+		}
+	}
+	return ran
+}
+
+// slowerFiles lists the files of head that took at least threshold percent (and MinSlowdownSeconds)
+// longer than in base, largest slowdown first
+func slowerFiles(base, head *domain.TestResultsOutput, threshold float64, timings map[string]*domain.TestTiming) []domain.DurationChange {
+	baseDurations := make(map[string]float64, len(base.Files))
+	for _, f := range base.Files {
+		if !f.Interrupted && !f.TimedOut {
+			baseDurations[fileKey(f.Path)] = f.DurationSeconds
+		}
+	}
+
+	var slower []domain.DurationChange
+	for _, f := range head.Files {
+		if f.Interrupted || f.TimedOut {
+			continue
+		}
+		before, ok := baseDurations[fileKey(f.Path)]
+		if !ok || before <= 0 {
+			continue
+		}
+		change := domain.DurationChange{FilePath: f.Path, BaseSeconds: before, HeadSeconds: f.DurationSeconds}
+		if change.HeadSeconds-change.BaseSeconds < MinSlowdownSeconds || change.Percent() < threshold {
+			continue
+		}
+		if t, ok := timings[f.Path]; ok {
+			change.AvgSeconds = t.Avg
+		}
+		slower = append(slower, change)
+	}
+	sort.Slice(slower, func(i, j int) bool {
+		di := slower[i].HeadSeconds - slower[i].BaseSeconds
+		dj := slower[j].HeadSeconds - slower[j].BaseSeconds
+		if di != dj {
+			return di > dj
+		}
+		return slower[i].FilePath < slower[j].FilePath
+	})
+	return slower
+}
+
+// fileKey normalizes a test file path so that the same file matches across runs and parsers
+// (slashes, .php suffix, namespace-cased paths from PHPUnit's text output)
+func fileKey(path string) string {
+	path = filepath.ToSlash(strings.ReplaceAll(path, "\\", "/"))
+	return strings.ToLower(strings.TrimSuffix(path, ".php"))
+}
+
+func sortChanges(changes []domain.FailureChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].FilePath != changes[j].FilePath {
+			return changes[i].FilePath < changes[j].FilePath
+		}
+		return changes[i].TestName < changes[j].TestName
+	})
+}
+
+// WriteDiff writes a run comparison in the given format ("json" or "markdown")
+func WriteDiff(w io.Writer, d *domain.RunDiff, format string) error {
+	switch format {
+	case "json":
+		return WriteDiffJSON(w, d)
+	case "markdown":
+		return WriteDiffMarkdown(w, d)
+	default:
+		return fmt.Errorf("unknown diff format %q (expected json or markdown)", format)
+	}
+}
+
+// WriteDiffFile writes a run comparison to path, creating parent directories as needed
+func WriteDiffFile(path string, d *domain.RunDiff, format string) error {
+	return writeFile(path, func(w io.Writer) error { return WriteDiff(w, d, format) })
+}
+
+// WriteDiffJSON writes a run comparison as indented JSON
+func WriteDiffJSON(w io.Writer, d *domain.RunDiff) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(d); err != nil {
+		return fmt.Errorf("encode diff: %w", err)
+	}
+	return nil
+}
+
+// WriteDiffMarkdown writes a run comparison as Markdown, e.g. for a pull request comment
+func WriteDiffMarkdown(w io.Writer, d *domain.RunDiff) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Test run diff: %s → %s\n\n", markdownRun(d.Base), markdownRun(d.Head))
+	fmt.Fprintf(&b, "| Newly failing | Fixed | Still failing | Changed message | Slower files |\n")
+	fmt.Fprintf(&b, "| ---: | ---: | ---: | ---: | ---: |\n")
+	fmt.Fprintf(&b, "| %d | %d | %d | %d | %d |\n", len(d.NewlyFailing), len(d.Fixed), len(d.StillFailing), len(d.ChangedMessage), len(d.Slower))
+
+	writeChanges := func(title string, changes []domain.FailureChange, message func(domain.FailureChange) string) {
+		if len(changes) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n### %s (%d)\n\n", title, len(changes))
+		for _, c := range changes {
+			fmt.Fprintf(&b, "- `%s::%s`", filepath.ToSlash(c.FilePath), c.TestName)
+			if msg := message(c); msg != "" {
+				fmt.Fprintf(&b, " — %s", msg)
+			}
+			b.WriteString("\n")
+		}
+	}
+	writeChanges("❌ Newly failing", d.NewlyFailing, func(c domain.FailureChange) string { return markdownText(c.HeadMessage) })
+	writeChanges("✅ Fixed", d.Fixed, func(domain.FailureChange) string { return "" })
+	writeChanges("🔁 Changed message", d.ChangedMessage, func(c domain.FailureChange) string {
+		return fmt.Sprintf("%s → %s", markdownText(c.BaseMessage), markdownText(c.HeadMessage))
+	})
+	writeChanges("Still failing", d.StillFailing, func(c domain.FailureChange) string { return markdownText(c.HeadMessage) })
+
+	if len(d.Slower) > 0 {
+		fmt.Fprintf(&b, "\n### 🐢 Slower files (%d, at least %g%% slower)\n\n", len(d.Slower), d.SlowerThreshold)
+		b.WriteString("| File | Before | After | Change | Average |\n| --- | ---: | ---: | ---: | ---: |\n")
+		for _, s := range d.Slower {
+			avg := "–"
+			if s.AvgSeconds > 0 {
+				avg = fmt.Sprintf("%.2fs", s.AvgSeconds)
+			}
+			fmt.Fprintf(&b, "| `%s` | %.2fs | %.2fs | +%.0f%% | %s |\n", filepath.ToSlash(s.FilePath), s.BaseSeconds, s.HeadSeconds, s.Percent(), avg)
+		}
+	}
+	if d.NotRun > 0 {
+		fmt.Fprintf(&b, "\n_%d failure(s) of the base run were not compared: their files did not run in the newer run._\n", d.NotRun)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// DescribeRun names a run as "id (branch@commit)"
+func DescribeRun(run domain.RunInfo) string {
+	commit := run.GitCommit
+	if len(commit) > 8 {
+		commit = commit[:8]
+	}
+	switch {
+	case commit == "":
+		return run.RunID
+	case run.GitBranch != "":
+		return fmt.Sprintf("%s (%s@%s)", run.RunID, run.GitBranch, commit)
+	default:
+		return fmt.Sprintf("%s (%s)", run.RunID, commit)
+	}
+}
+
+// markdownRun is DescribeRun with the run id as code
+func markdownRun(run domain.RunInfo) string {
+	return "`" + run.RunID + "`" + strings.TrimPrefix(DescribeRun(run), run.RunID)
+}
+
+// markdownText returns the first line of a failure message, safe to put in a list item
+func markdownText(s string) string {
+	s = FirstLine(s)
+	if s == "" {
+		return ""
+	}
+	return "`" + strings.ReplaceAll(s, "`", "'") + "`"
+}
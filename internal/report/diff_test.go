@@ -0,0 +1,105 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"ptp/internal/domain"
+)
+
+func TestCompareRuns(t *testing.T) {
+	base := &domain.TestResultsOutput{
+		Meta: domain.TestResultsMeta{RunInfo: domain.RunInfo{RunID: "20260101-100000"}},
+		Details: []domain.TestFailure{
+			{FilePath: "tests/Unit/UserTest.php", TestName: "testFixed", Message: "boom"},
+			{FilePath: "tests/Unit/UserTest.php", TestName: "testStill", Message: "Failed asserting that 1 is 2.\n/app/UserTest.php:10"},
+			{FilePath: "tests/Unit/UserTest.php", TestName: "testChanged", Message: "old message"},
+			{FilePath: "tests/Unit/SkippedTest.php", TestName: "testNotRun", Message: "boom"},
+		},
+		Files: []domain.TestFileResult{
+			{Path: "tests/Unit/UserTest.php", DurationSeconds: 2},
+			{Path: "tests/Unit/SlowTest.php", DurationSeconds: 4},
+			{Path: "tests/Unit/FastTest.php", DurationSeconds: 0.1},
+			{Path: "tests/Unit/SkippedTest.php", DurationSeconds: 1},
+		},
+	}
+	head := &domain.TestResultsOutput{
+		Meta: domain.TestResultsMeta{RunInfo: domain.RunInfo{RunID: "20260101-110000"}},
+		Details: []domain.TestFailure{
+			// Text-output fallback style path for the same file
+			{FilePath: "Tests\\Unit\\UserTest", TestName: "testStill", Message: "Failed asserting that 1 is 2.\n/app/UserTest.php:12"},
+			{FilePath: "tests/Unit/UserTest.php", TestName: "testChanged", Message: "new message"},
+			{FilePath: "tests/Unit/UserTest.php", TestName: "testNew", Message: "kaput"},
+			{FilePath: "tests/Unit/UserTest.php", TestName: "testFlaky", Message: "race", Flaky: true},
+		},
+		Files: []domain.TestFileResult{
+			{Path: "tests/Unit/UserTest.php", DurationSeconds: 2.1},
+			{Path: "tests/Unit/SlowTest.php", DurationSeconds: 6},
+			{Path: "tests/Unit/FastTest.php", DurationSeconds: 0.3},
+			{Path: "tests/Unit/SkippedTest.php", DurationSeconds: 1, Interrupted: true},
+		},
+	}
+	timings := map[string]*domain.TestTiming{"tests/Unit/SlowTest.php": {Count: 5, Avg: 4.2}}
+
+	d := CompareRuns(base, head, DefaultSlowerThreshold, timings)
+
+	names := func(changes []domain.FailureChange) string {
+		var out []string
+		for _, c := range changes {
+			out = append(out, c.TestName)
+		}
+		return strings.Join(out, ",")
+	}
+	if got := names(d.NewlyFailing); got != "testNew" {
+		t.Errorf("newly failing = %q, want testNew (flaky failures are not failures)", got)
+	}
+	if got := names(d.Fixed); got != "testFixed" {
+		t.Errorf("fixed = %q, want testFixed", got)
+	}
+	if got := names(d.StillFailing); got != "testStill" {
+		t.Errorf("still failing = %q, want testStill", got)
+	}
+	if got := names(d.ChangedMessage); got != "testChanged" {
+		t.Errorf("changed message = %q, want testChanged", got)
+	}
+	if d.NotRun != 1 {
+		t.Errorf("expected the failure of the interrupted file to be not compared, got NotRun=%d", d.NotRun)
+	}
+
+	// FastTest tripled but by less than MinSlowdownSeconds; UserTest is within the threshold
+	if len(d.Slower) != 1 || d.Slower[0].FilePath != "tests/Unit/SlowTest.php" {
+		t.Fatalf("expected only SlowTest to be slower, got %+v", d.Slower)
+	}
+	if s := d.Slower[0]; s.Percent() != 50 || s.AvgSeconds != 4.2 {
+		t.Errorf("unexpected slowdown %+v (%.0f%%)", s, s.Percent())
+	}
+}
+
+func TestWriteDiffMarkdown(t *testing.T) {
+	d := &domain.RunDiff{
+		Base:            domain.RunInfo{RunID: "20260101-100000", GitBranch: "main", GitCommit: "0123456789abcdef"},
+		Head:            domain.RunInfo{RunID: "20260101-110000"},
+		NewlyFailing:    []domain.FailureChange{{FilePath: "tests/Unit/UserTest.php", TestName: "testNew", HeadMessage: "Failed `x`\nmore"}},
+		Slower:          []domain.DurationChange{{FilePath: "tests/Unit/SlowTest.php", BaseSeconds: 4, HeadSeconds: 6}},
+		SlowerThreshold: 20,
+	}
+	var buf bytes.Buffer
+	if err := WriteDiff(&buf, d, "markdown"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"`20260101-100000` (main@01234567) → `20260101-110000`",
+		"### ❌ Newly failing (1)",
+		"- `tests/Unit/UserTest.php::testNew` — `Failed 'x'`",
+		"| `tests/Unit/SlowTest.php` | 4.00s | 6.00s | +50% | – |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected markdown to contain %q, got:\n%s", want, out)
+		}
+	}
+	if err := WriteDiff(&buf, d, "xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,268 @@
+package report
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"ptp/internal/domain"
+)
+
+// maxSlowestFiles is the number of files listed in the slowest files section of the HTML report
+const maxSlowestFiles = 20
+
+//go:embed html.tmpl
+var htmlTemplate string
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"seconds": func(s float64) string { return fmt.Sprintf("%.2fs", s) },
+	"percent": func(p float64) string { return fmt.Sprintf("%.3f%%", p) },
+}).Parse(htmlTemplate))
+
+type htmlReport struct {
+	Title    string
+	Run      string
+	Meta     domain.TestResultsMeta
+	Failed   bool
+	Failures []htmlFailure
+	Tree     []*htmlTreeNode
+	Slowest  []htmlTiming
+	Workers  []htmlWorker
+	Measured bool // the timeline has the recorded start times, not files laid back to back
+}
+
+type htmlFailure struct {
+	TestName   string
+	Location   string
+	Message    string
+	Flaky      bool
+	StackTrace string
+	Details    string
+	Attempts   []domain.TestAttempt
+}
+
+// htmlTreeNode is a directory or file of the failure tree
+type htmlTreeNode struct {
+	Name     string
+	Failures []string // test cases, for files
+	Children []*htmlTreeNode
+}
+
+type htmlTiming struct {
+	Path    string
+	Avg     float64
+	Count   int
+	Percent float64 // of the slowest average
+}
+
+type htmlWorker struct {
+	ID       int
+	Files    int
+	Busy     float64
+	Segments []htmlSegment
+}
+
+// htmlSegment is a file on a worker's timeline, positioned in percent of the longest worker
+type htmlSegment struct {
+	Path    string
+	Seconds float64
+	Left    float64
+	Width   float64
+	Status  string
+}
+
+// WriteHTML writes a run as a single self-contained HTML page (no external assets): the summary,
+// a sortable failure table with collapsible stack traces and error details, the failure tree, the
+// slowest files of the recorded timings and a timeline of the workers.
+func WriteHTML(w io.Writer, out *domain.TestResultsOutput) error {
+	if err := htmlReportTemplate.Execute(w, buildHTML(out)); err != nil {
+		return fmt.Errorf("render html report: %w", err)
+	}
+	return nil
+}
+
+// WriteHTMLFile writes the HTML report to path, creating parent directories as needed
+func WriteHTMLFile(path string, out *domain.TestResultsOutput) error {
+	return writeFile(path, func(w io.Writer) error { return WriteHTML(w, out) })
+}
+
+func buildHTML(out *domain.TestResultsOutput) htmlReport {
+	r := htmlReport{
+		Title:  "Test results",
+		Meta:   out.Meta,
+		Failed: out.Meta.FailedTestFiles > 0 || out.Meta.FailedTestCases > 0,
+		Tree:   failureTree(domain.WithoutFlaky(out.Details)),
+	}
+	if out.Meta.RunID != "" {
+		r.Title += " " + out.Meta.RunID
+		r.Run = DescribeRun(out.Meta.RunInfo)
+	}
+	for _, f := range out.Details {
+		location := filepath.ToSlash(f.FilePath)
+		if f.Line > 0 {
+			location = fmt.Sprintf("%s:%d", location, f.Line)
+		}
+		r.Failures = append(r.Failures, htmlFailure{
+			TestName:   f.TestName,
+			Location:   location,
+			Message:    strings.TrimSpace(f.Message),
+			Flaky:      f.Flaky,
+			StackTrace: strings.Join(f.StackTrace, "\n"),
+			Details:    prettyJSON(f.ErrorDetails),
+			Attempts:   f.Attempts,
+		})
+	}
+	r.Slowest = slowestFiles(out.Timings, maxSlowestFiles)
+	if len(out.Timeline) > 0 {
+		r.Workers, r.Measured = measuredTimeline(out.Timeline), true
+	} else {
+		r.Workers = workerTimeline(out.Files)
+	}
+	return r
+}
+
+// prettyJSON indents s when it is JSON and returns it unchanged otherwise
+func prettyJSON(s string) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(s), "", "  "); err != nil {
+		return strings.TrimSpace(s)
+	}
+	return buf.String()
+}
+
+// failureTree groups failures by directory and file, as the terminal summary does
+func failureTree(failures []domain.TestFailure) []*htmlTreeNode {
+	root := &htmlTreeNode{}
+	for _, f := range failures {
+		node := root
+		for _, part := range strings.Split(strings.TrimPrefix(filepath.ToSlash(f.FilePath), "./"), "/") {
+			if part == "" {
+				continue
+			}
+			var child *htmlTreeNode
+			for _, c := range node.Children {
+				if c.Name == part {
+					child = c
+					break
+				}
+			}
+			if child == nil {
+				child = &htmlTreeNode{Name: part}
+				node.Children = append(node.Children, child)
+			}
+			node = child
+		}
+		node.Failures = append(node.Failures, f.TestName)
+	}
+	var sortTree func(n *htmlTreeNode)
+	sortTree = func(n *htmlTreeNode) {
+		sort.Slice(n.Children, func(i, j int) bool { return n.Children[i].Name < n.Children[j].Name })
+		for _, c := range n.Children {
+			sortTree(c)
+		}
+	}
+	sortTree(root)
+	return root.Children
+}
+
+// slowestFiles returns the files with the highest average duration in the timings
+func slowestFiles(timings map[string]*domain.TestTiming, limit int) []htmlTiming {
+	var out []htmlTiming
+	for path, t := range timings {
+		if t != nil {
+			out = append(out, htmlTiming{Path: filepath.ToSlash(path), Avg: t.Avg, Count: t.Count})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Avg != out[j].Avg {
+			return out[i].Avg > out[j].Avg
+		}
+		return out[i].Path < out[j].Path
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	for i := range out {
+		if out[0].Avg > 0 {
+			out[i].Percent = out[i].Avg / out[0].Avg * 100
+		}
+	}
+	return out
+}
+
+// measuredTimeline places the files of each worker at the time they ran, so idle time shows as gaps
+func measuredTimeline(spans []domain.WorkerSpan) []htmlWorker {
+	byWorker := make(map[int]*htmlWorker)
+	var ids []int
+	var end float64
+	for _, s := range spans {
+		end = max(end, s.EndSeconds)
+	}
+	for _, s := range spans {
+		w := byWorker[s.WorkerID]
+		if w == nil {
+			w = &htmlWorker{ID: s.WorkerID}
+			byWorker[s.WorkerID] = w
+			ids = append(ids, s.WorkerID)
+		}
+		seconds := s.EndSeconds - s.StartSeconds
+		path := filepath.ToSlash(s.Path)
+		if s.Chunks > 1 {
+			path = fmt.Sprintf("%s [%d/%d]", path, s.Chunk, s.Chunks)
+		}
+		segment := htmlSegment{Path: path, Seconds: seconds, Status: s.Status}
+		if end > 0 {
+			segment.Left = s.StartSeconds / end * 100
+			segment.Width = seconds / end * 100
+		}
+		w.Segments = append(w.Segments, segment)
+		w.Busy += seconds
+		w.Files++
+	}
+	sort.Ints(ids)
+	out := make([]htmlWorker, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, *byWorker[id])
+	}
+	return out
+}
+
+// workerTimeline lays out the files of each worker back to back, in the order they completed; it is
+// the timeline of runs stored without the recorded start times
+func workerTimeline(files []domain.TestFileResult) []htmlWorker {
+	byWorker := make(map[int]*htmlWorker)
+	var ids []int
+	var longest float64
+	for _, f := range files {
+		w := byWorker[f.WorkerID]
+		if w == nil {
+			w = &htmlWorker{ID: f.WorkerID}
+			byWorker[f.WorkerID] = w
+			ids = append(ids, f.WorkerID)
+		}
+		status := domain.SpanStatus(f.Success, f.Interrupted, f.TimedOut)
+		w.Segments = append(w.Segments, htmlSegment{Path: filepath.ToSlash(f.Path), Seconds: f.DurationSeconds, Left: w.Busy, Status: status})
+		w.Busy += f.DurationSeconds
+		w.Files++
+		longest = max(longest, w.Busy)
+	}
+	sort.Ints(ids)
+	out := make([]htmlWorker, 0, len(ids))
+	for _, id := range ids {
+		w := byWorker[id]
+		if longest > 0 {
+			for i := range w.Segments {
+				w.Segments[i].Left = w.Segments[i].Left / longest * 100
+				w.Segments[i].Width = w.Segments[i].Seconds / longest * 100
+			}
+		}
+		out = append(out, *w)
+	}
+	return out
+}
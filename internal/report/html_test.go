@@ -0,0 +1,87 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"ptp/internal/domain"
+)
+
+func TestWriteHTML(t *testing.T) {
+	out := &domain.TestResultsOutput{
+		Meta: domain.TestResultsMeta{
+			RunInfo:         domain.RunInfo{RunID: "20260114-0932", GitBranch: "main", GitCommit: "0123456789abcdef"},
+			TotalTestFiles:  3,
+			FailedTestFiles: 1,
+			FailedTestCases: 1,
+			Workers:         2,
+		},
+		Details: []domain.TestFailure{{
+			TestName:     "testCreate",
+			FilePath:     "tests/Unit/UserTest.php",
+			Line:         12,
+			Message:      "Expected <b>200</b>",
+			ErrorDetails: `{"message":"Server Error"}`,
+			StackTrace:   []string{"/app/tests/Unit/UserTest.php:12"},
+		}},
+		Files: []domain.TestFileResult{
+			{Path: "tests/Unit/UserTest.php", DurationSeconds: 3, WorkerID: 1},
+			{Path: "tests/Unit/OrderTest.php", Success: true, DurationSeconds: 1, WorkerID: 2},
+			{Path: "tests/Unit/CartTest.php", Success: true, DurationSeconds: 1, WorkerID: 1},
+		},
+		Timings: map[string]*domain.TestTiming{
+			"tests/Unit/UserTest.php":  {Count: 4, Avg: 3},
+			"tests/Unit/OrderTest.php": {Count: 4, Avg: 1.5},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	html := buf.String()
+	for _, want := range []string{
+		"<title>Test results 20260114-0932</title>",
+		"20260114-0932 (main@01234567)",
+		"Expected &lt;b&gt;200&lt;/b&gt;",
+		"tests/Unit/UserTest.php:12",
+		"{\n  &#34;message&#34;: &#34;Server Error&#34;\n}",
+		`<span class="dir">Unit/</span><ul><li><span class="file">UserTest.php</span>`,
+		`data-sort="3">3.00s</td><td class="num">4</td>`,
+		`style="width: 50.000%"`,
+		`class="segment failed" style="left: 0.000%; width: 75.000%"`,
+		`class="segment passed" style="left: 75.000%; width: 25.000%"`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected the report to contain %q", want)
+		}
+	}
+	if strings.Contains(html, "<b>200</b>") || strings.Contains(html, "http") {
+		t.Error("expected escaped messages and no external assets")
+	}
+}
+
+func TestWriteHTML_MeasuredTimeline(t *testing.T) {
+	out := &domain.TestResultsOutput{
+		Timeline: []domain.WorkerSpan{
+			{WorkerID: 1, Path: "tests/Unit/UserTest.php", StartSeconds: 0, EndSeconds: 3, Status: domain.CaseFailed},
+			{WorkerID: 2, Path: "tests/Unit/OrderTest.php", Chunk: 2, Chunks: 2, StartSeconds: 1, EndSeconds: 2, Status: domain.CasePassed},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	html := buf.String()
+	for _, want := range []string{
+		"gaps are idle time",
+		`class="segment failed" style="left: 0.000%; width: 100.000%"`,
+		`class="segment passed" style="left: 33.333%; width: 33.333%" title="tests/Unit/OrderTest.php [2/2] (1.00s, passed)"`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected the report to contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"ptp/internal/domain"
+)
+
+type junitTestSuites struct {
+	XMLName   xml.Name         `xml:"testsuites"`
+	Name      string           `xml:"name,attr"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	Errors    int              `xml:"errors,attr"`
+	Skipped   int              `xml:"skipped,attr"`
+	Time      string           `xml:"time,attr"`
+	Timestamp string           `xml:"timestamp,attr,omitempty"`
+	Suites    []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	File       string          `xml:"file,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr,omitempty"`
+	File      string        `xml:"file,attr,omitempty"`
+	Line      int           `xml:"line,attr,omitempty"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	// FlakyFailure (Maven Surefire extension) records the failed attempt of a case that passed on a retry
+	FlakyFailure *junitProblem `xml:"flakyFailure,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// WriteJUnit writes a run as one merged JUnit XML document: one testsuite per test file (named
+// after its test class, with the path in the file attribute), one testcase per case, with failure
+// messages and stack traces from the run's failures.
+func WriteJUnit(w io.Writer, out *domain.TestResultsOutput) error {
+	doc := buildJUnit(out)
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("encode junit report: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteJUnitFile writes the JUnit report to path, creating parent directories as needed
+func WriteJUnitFile(path string, out *domain.TestResultsOutput) error {
+	return writeFile(path, func(w io.Writer) error { return WriteJUnit(w, out) })
+}
+
+func buildJUnit(out *domain.TestResultsOutput) junitTestSuites {
+	doc := junitTestSuites{
+		Name:      "ptp",
+		Time:      formatSeconds(out.Meta.DurationSeconds),
+		Timestamp: out.Meta.Timestamp,
+	}
+
+	for _, file := range out.Files {
+		failures := failuresForFile(out.Details, file.Path)
+		suite := junitTestSuite{
+			Name: suiteName(file),
+			File: file.Path,
+			Time: formatSeconds(file.DurationSeconds),
+		}
+		if file.WorkerID > 0 {
+			suite.Properties = []junitProperty{{Name: "worker", Value: strconv.Itoa(file.WorkerID)}}
+		}
+
+		if len(file.Cases) > 0 {
+			for _, c := range file.Cases {
+				tc := junitTestCase{
+					Name:      c.Name,
+					ClassName: c.Class,
+					File:      c.File,
+					Line:      c.Line,
+					Time:      formatSeconds(c.DurationSeconds),
+				}
+				switch c.Status {
+				case domain.CaseFailed:
+					tc.Failure = problemFor(file.Path, c, failures)
+				case domain.CaseError:
+					tc.Error = problemFor(file.Path, c, failures)
+				case domain.CaseSkipped:
+					tc.Skipped = &junitSkipped{Message: c.Message}
+				case domain.CaseFlaky:
+					tc.FlakyFailure = problemFor(file.Path, c, failures)
+				}
+				suite.Cases = append(suite.Cases, tc)
+			}
+		} else if failures := domain.WithoutFlaky(failures); len(failures) > 0 {
+			// No per-case data (PHPUnit did not write a log): report the parsed failures
+			for _, f := range failures {
+				suite.Cases = append(suite.Cases, junitTestCase{
+					Name:      f.TestName,
+					ClassName: f.Class,
+					File:      f.File,
+					Line:      f.Line,
+					Time:      formatSeconds(0),
+					Failure:   &junitProblem{Message: FirstLine(f.Message), Text: failureText(f)},
+				})
+			}
+		} else {
+			// Nothing but the file outcome is known
+			tc := junitTestCase{Name: filepath.Base(file.Path), File: file.Path, Time: formatSeconds(file.DurationSeconds)}
+			switch {
+			case file.Interrupted:
+				tc.Skipped = &junitSkipped{Message: "interrupted before the file finished"}
+			case !file.Success:
+				tc.Error = &junitProblem{Message: "test file failed without reporting test cases"}
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+
+		for _, tc := range suite.Cases {
+			suite.Tests++
+			switch {
+			case tc.Failure != nil:
+				suite.Failures++
+			case tc.Error != nil:
+				suite.Errors++
+			case tc.Skipped != nil:
+				suite.Skipped++
+			}
+		}
+		doc.Tests += suite.Tests
+		doc.Failures += suite.Failures
+		doc.Errors += suite.Errors
+		doc.Skipped += suite.Skipped
+		doc.Suites = append(doc.Suites, suite)
+	}
+	return doc
+}
+
+// suiteName is the test class of a file's cases, or the file name when no case names its class
+func suiteName(file domain.TestFileResult) string {
+	for _, c := range file.Cases {
+		if c.Class != "" {
+			return c.Class
+		}
+	}
+	return strings.TrimSuffix(filepath.Base(file.Path), ".php")
+}
+
+// problemFor builds the failure/error element of a case of the file at path, using the
+// TestFailure with the same file, class and name when available
+func problemFor(path string, c domain.TestCaseResult, failures []domain.TestFailure) *junitProblem {
+	for _, f := range failures {
+		if sameCase(f, path, c) {
+			return &junitProblem{Message: FirstLine(f.Message), Text: failureText(f)}
+		}
+	}
+	return &junitProblem{Message: FirstLine(c.Message), Text: c.Message}
+}
+
+// sameCase reports whether failure f belongs to case c of the file at path. The class is
+// compared only when both sides know it (the stdout parsers do not record it).
+func sameCase(f domain.TestFailure, path string, c domain.TestCaseResult) bool {
+	if f.TestName != c.Name || !samePath(f.FilePath, path) {
+		return false
+	}
+	return f.Class == "" || c.Class == "" || f.Class == c.Class
+}
+
+// failureText is the full failure body: message, error details and stack trace
+func failureText(f domain.TestFailure) string {
+	parts := []string{f.Message}
+	if f.ErrorDetails != "" {
+		parts = append(parts, f.ErrorDetails)
+	}
+	if len(f.StackTrace) > 0 {
+		parts = append(parts, strings.Join(f.StackTrace, "\n"))
+	}
+	return strings.TrimSpace(strings.Join(parts, "\n\n"))
+}
+
+// failuresForFile returns the failures recorded for a test file
+func failuresForFile(failures []domain.TestFailure, path string) []domain.TestFailure {
+	var out []domain.TestFailure
+	for _, f := range failures {
+		if samePath(f.FilePath, path) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// samePath compares test file paths the way failures report them: case-insensitive,
+// with or without .php, and relative or absolute.
+func samePath(a, b string) bool {
+	norm := func(p string) string {
+		p = strings.ReplaceAll(p, "\\", "/")
+		p = strings.TrimPrefix(p, "./")
+		return strings.ToLower(strings.TrimSuffix(p, ".php"))
+	}
+	a, b = norm(a), norm(b)
+	if a == "" || b == "" {
+		return false
+	}
+	return a == b || strings.HasSuffix(a, "/"+b) || strings.HasSuffix(b, "/"+a)
+}
+
+// FirstLine returns the first non-empty line of a failure message
+func FirstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return strings.TrimSpace(s[:i])
+	}
+	return s
+}
+
+func formatSeconds(s float64) string {
+	return strconv.FormatFloat(s, 'f', 6, 64)
+}
+
+// writeFile creates path (and its directory) and writes to it with fn
+func writeFile(path string, fn func(w io.Writer) error) error {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("create report dir: %w", err)
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create report file: %w", err)
+	}
+	if err := fn(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,150 @@
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"ptp/internal/domain"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	out := &domain.TestResultsOutput{
+		Meta: domain.TestResultsMeta{DurationSeconds: 3.5, Timestamp: "2026-01-01T00:00:00Z"},
+		Details: []domain.TestFailure{
+			{
+				TestName:   "testUpdate",
+				FilePath:   "Tests/Unit/UserTest",
+				Message:    "Failed asserting that false is true.\nmore",
+				StackTrace: []string{"/app/tests/Unit/UserTest.php:24"},
+			},
+			{TestName: "testFatal", FilePath: "tests/Unit/BoomTest.php", Message: "Boom"},
+		},
+		Files: []domain.TestFileResult{
+			{
+				Path: "tests/Unit/UserTest.php", Success: false, DurationSeconds: 2, WorkerID: 3,
+				Cases: []domain.TestCaseResult{
+					{Name: "testCreate", Status: domain.CasePassed, DurationSeconds: 0.5},
+					{Name: "testUpdate", Status: domain.CaseFailed, DurationSeconds: 1},
+					{Name: "testLater", Status: domain.CaseSkipped},
+				},
+			},
+			{Path: "tests/Unit/BoomTest.php", Success: false, DurationSeconds: 1, WorkerID: 1},
+			{Path: "tests/Unit/EmptyTest.php", Success: true, DurationSeconds: 0.5, WorkerID: 2},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var doc junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("report is not valid XML: %v\n%s", err, buf.String())
+	}
+	if len(doc.Suites) != 3 {
+		t.Fatalf("expected one testsuite per file, got %d", len(doc.Suites))
+	}
+	if doc.Tests != 5 || doc.Failures != 2 || doc.Skipped != 1 {
+		t.Errorf("unexpected totals: tests=%d failures=%d skipped=%d", doc.Tests, doc.Failures, doc.Skipped)
+	}
+
+	user := doc.Suites[0]
+	if len(user.Properties) != 1 || user.Properties[0].Name != "worker" || user.Properties[0].Value != "3" {
+		t.Errorf("expected worker property, got %+v", user.Properties)
+	}
+	failure := user.Cases[1].Failure
+	if failure == nil {
+		t.Fatal("expected failure element for testUpdate")
+	}
+	if failure.Message != "Failed asserting that false is true." {
+		t.Errorf("unexpected failure message %q", failure.Message)
+	}
+	if !strings.Contains(failure.Text, "UserTest.php:24") {
+		t.Errorf("expected stack trace in failure body, got %q", failure.Text)
+	}
+
+	boom := doc.Suites[1]
+	if len(boom.Cases) != 1 || boom.Cases[0].Name != "testFatal" || boom.Cases[0].Failure == nil {
+		t.Errorf("expected parsed failure for file without cases, got %+v", boom.Cases)
+	}
+	if empty := doc.Suites[2]; len(empty.Cases) != 1 || empty.Cases[0].Failure != nil || empty.Cases[0].Error != nil {
+		t.Errorf("expected a single passing case for a passed file without cases, got %+v", empty.Cases)
+	}
+}
+
+func TestWriteJUnit_Flaky(t *testing.T) {
+	out := &domain.TestResultsOutput{
+		Details: []domain.TestFailure{
+			{TestName: "testRace", FilePath: "tests/Unit/QueueTest.php", Message: "Timed out waiting for lock", Flaky: true},
+		},
+		Files: []domain.TestFileResult{
+			{
+				Path: "tests/Unit/QueueTest.php", Success: true, DurationSeconds: 1,
+				Cases: []domain.TestCaseResult{
+					{Name: "testRace", Status: domain.CaseFlaky, DurationSeconds: 1},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var doc junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("report is not valid XML: %v\n%s", err, buf.String())
+	}
+	if doc.Failures != 0 {
+		t.Errorf("expected flaky cases not to count as failures, got %d", doc.Failures)
+	}
+	tc := doc.Suites[0].Cases[0]
+	if tc.Failure != nil || tc.FlakyFailure == nil || tc.FlakyFailure.Message != "Timed out waiting for lock" {
+		t.Errorf("expected a flakyFailure element, got %+v", tc)
+	}
+}
+
+func TestWriteJUnit_SameCaseNameInTwoFiles(t *testing.T) {
+	out := &domain.TestResultsOutput{
+		Details: []domain.TestFailure{
+			{TestName: "testCreate", Class: "Tests\\Feature\\OrderTest", FilePath: "tests/Feature/OrderTest.php", Message: "order failed"},
+		},
+		Files: []domain.TestFileResult{
+			{
+				Path: "tests/Feature/UserTest.php", Success: false,
+				Cases: []domain.TestCaseResult{
+					{Name: "testCreate", Class: "Tests\\Feature\\UserTest", Status: domain.CaseFailed, Message: "user failed"},
+				},
+			},
+			{
+				Path: "tests/Feature/OrderTest.php", Success: false,
+				Cases: []domain.TestCaseResult{
+					{Name: "testCreate", Class: "Tests\\Feature\\OrderTest", Status: domain.CaseFailed},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var doc junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("report is not valid XML: %v\n%s", err, buf.String())
+	}
+
+	user, order := doc.Suites[0], doc.Suites[1]
+	if user.Name != "Tests\\Feature\\UserTest" || user.File != "tests/Feature/UserTest.php" {
+		t.Errorf("expected suite named after the class with the file attribute, got name=%q file=%q", user.Name, user.File)
+	}
+	if msg := user.Cases[0].Failure.Message; msg != "user failed" {
+		t.Errorf("expected UserTest to keep its own failure, got %q", msg)
+	}
+	if msg := order.Cases[0].Failure.Message; msg != "order failed" {
+		t.Errorf("expected OrderTest to get its failure, got %q", msg)
+	}
+}
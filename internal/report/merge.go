@@ -0,0 +1,135 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"ptp/internal/domain"
+)
+
+// ReadResults reads a results JSON file written by ptp (e.g. a shard's storage/test-results.json)
+func ReadResults(path string) (*domain.TestResultsOutput, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read results file: %w", err)
+	}
+	var output domain.TestResultsOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, fmt.Errorf("parse results %s: %w", path, err)
+	}
+	return &output, nil
+}
+
+// WriteResults writes output as a results JSON file
+func WriteResults(path string, output *domain.TestResultsOutput) error {
+	return writeFile(path, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(output); err != nil {
+			return fmt.Errorf("write results: %w", err)
+		}
+		return nil
+	})
+}
+
+// MergeResults combines the results of the shards of a CI run into one output. A file reported
+// by several outputs keeps the results (and failures) of the last one. The timings and flaky
+// history of each entry come from the output with the most samples: every shard starts from the
+// same stored history and only adds to the files it ran. The duration is the slowest shard's.
+func MergeResults(outputs ...*domain.TestResultsOutput) *domain.TestResultsOutput {
+	merged := &domain.TestResultsOutput{}
+	owner := make(map[string]int)
+	for i, out := range outputs {
+		for _, f := range out.Files {
+			owner[fileKey(f.Path)] = i
+		}
+	}
+
+	for i, out := range outputs {
+		m := out.Meta
+		if merged.Meta.GitCommit == "" {
+			merged.Meta.GitCommit, merged.Meta.GitBranch = m.GitCommit, m.GitBranch
+		}
+		if m.DurationSeconds > merged.Meta.DurationSeconds {
+			merged.Meta.DurationSeconds, merged.Meta.Duration = m.DurationSeconds, m.Duration
+		}
+		if m.Timestamp > merged.Meta.Timestamp {
+			merged.Meta.Timestamp = m.Timestamp
+		}
+		merged.Meta.Workers += m.Workers
+
+		for _, f := range out.Files {
+			if owner[fileKey(f.Path)] == i {
+				merged.Files = append(merged.Files, f)
+			}
+		}
+		for _, d := range out.Details {
+			if o, ok := owner[fileKey(d.FilePath)]; !ok || o == i {
+				merged.Details = append(merged.Details, d)
+			}
+		}
+		merged.Timings = mergeTimingMaps(merged.Timings, out.Timings)
+		merged.FlakyHistory = mergeFlakyMaps(merged.FlakyHistory, out.FlakyHistory)
+	}
+	sort.SliceStable(merged.Files, func(i, j int) bool { return merged.Files[i].Path < merged.Files[j].Path })
+	if merged.Details == nil {
+		merged.Details = []domain.TestFailure{}
+	}
+	merged.Summarize()
+	return merged
+}
+
+// MissingShards returns the "i/n" shards absent from outputs, when they were recorded with --shard
+func MissingShards(outputs ...*domain.TestResultsOutput) []string {
+	seen := make(map[string]bool)
+	total := 0
+	for _, out := range outputs {
+		var i, n int
+		if _, err := fmt.Sscanf(out.Meta.Shard, "%d/%d", &i, &n); err == nil {
+			seen[out.Meta.Shard] = true
+			total = max(total, n)
+		}
+	}
+	var missing []string
+	for i := 1; i <= total; i++ {
+		if shard := fmt.Sprintf("%d/%d", i, total); !seen[shard] {
+			missing = append(missing, shard)
+		}
+	}
+	return missing
+}
+
+func mergeTimingMaps(dst, src map[string]*domain.TestTiming) map[string]*domain.TestTiming {
+	for path, t := range src {
+		if t == nil {
+			continue
+		}
+		if dst == nil {
+			dst = make(map[string]*domain.TestTiming)
+		}
+		if cur, ok := dst[path]; !ok || t.Count > cur.Count {
+			cp := *t
+			dst[path] = &cp
+		}
+	}
+	return dst
+}
+
+func mergeFlakyMaps(dst, src map[string]*domain.FlakyStats) map[string]*domain.FlakyStats {
+	for key, s := range src {
+		if s == nil {
+			continue
+		}
+		if dst == nil {
+			dst = make(map[string]*domain.FlakyStats)
+		}
+		if cur, ok := dst[key]; !ok || s.Count > cur.Count {
+			cp := *s
+			dst[key] = &cp
+		}
+	}
+	return dst
+}
@@ -0,0 +1,85 @@
+package report
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"ptp/internal/domain"
+)
+
+func shardOutput(shard string, seconds float64, files []domain.TestFileResult, failures []domain.TestFailure, timings map[string]*domain.TestTiming) *domain.TestResultsOutput {
+	return &domain.TestResultsOutput{
+		Meta: domain.TestResultsMeta{
+			RunInfo:         domain.RunInfo{RunID: "run-" + shard, GitCommit: "abc123", Shard: shard},
+			DurationSeconds: seconds,
+			Workers:         4,
+		},
+		Details: failures,
+		Files:   files,
+		Timings: timings,
+	}
+}
+
+func TestMergeResults(t *testing.T) {
+	base := map[string]*domain.TestTiming{
+		"tests/ATest.php": {Count: 3, Avg: 2},
+		"tests/BTest.php": {Count: 3, Avg: 4},
+	}
+	first := shardOutput("1/2", 10,
+		[]domain.TestFileResult{{Path: "tests/BTest.php", Success: false, Cases: []domain.TestCaseResult{{Name: "testOne", Status: domain.CaseFailed}}}},
+		[]domain.TestFailure{{FilePath: "tests/BTest.php", TestName: "testOne"}},
+		map[string]*domain.TestTiming{"tests/ATest.php": base["tests/ATest.php"], "tests/BTest.php": {Count: 4, Avg: 5}},
+	)
+	second := shardOutput("2/2", 12,
+		[]domain.TestFileResult{{Path: "tests/ATest.php", Success: true, Cases: []domain.TestCaseResult{{Name: "testTwo", Status: domain.CasePassed}}}},
+		nil,
+		map[string]*domain.TestTiming{"tests/ATest.php": {Count: 4, Avg: 1}, "tests/BTest.php": base["tests/BTest.php"]},
+	)
+
+	merged := MergeResults(first, second)
+	if paths := []string{merged.Files[0].Path, merged.Files[1].Path}; !reflect.DeepEqual(paths, []string{"tests/ATest.php", "tests/BTest.php"}) {
+		t.Errorf("unexpected merged files %v", paths)
+	}
+	m := merged.Meta
+	if m.TotalTestFiles != 2 || m.FailedTestFiles != 1 || m.PassedTestFiles != 1 || m.FailedTestCases != 1 || m.TotalTestCases != 2 {
+		t.Errorf("unexpected merged counts: %+v", m)
+	}
+	if m.DurationSeconds != 12 || m.Workers != 8 || m.GitCommit != "abc123" || m.RunID != "" || m.Shard != "" {
+		t.Errorf("unexpected merged meta: %+v", m)
+	}
+	if a, b := merged.Timings["tests/ATest.php"], merged.Timings["tests/BTest.php"]; a.Count != 4 || a.Avg != 1 || b.Count != 4 || b.Avg != 5 {
+		t.Errorf("expected each shard's updated timings, got %+v and %+v", a, b)
+	}
+
+	// A file run again by a later output replaces the earlier result and failures
+	rerun := shardOutput("", 1, []domain.TestFileResult{{Path: "tests/BTest.php", Success: true}}, nil, nil)
+	if merged = MergeResults(first, second, rerun); len(merged.Details) != 0 || merged.Meta.FailedTestFiles != 0 {
+		t.Errorf("expected the rerun to replace the failure, got %+v", merged.Meta)
+	}
+}
+
+func TestMissingShards(t *testing.T) {
+	outputs := []*domain.TestResultsOutput{shardOutput("1/4", 0, nil, nil, nil), shardOutput("3/4", 0, nil, nil, nil)}
+	if got := MissingShards(outputs...); !reflect.DeepEqual(got, []string{"2/4", "4/4"}) {
+		t.Errorf("MissingShards() = %v", got)
+	}
+	if got := MissingShards(shardOutput("", 0, nil, nil, nil)); got != nil {
+		t.Errorf("expected no missing shards without --shard, got %v", got)
+	}
+}
+
+func TestWriteResults_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "merged", "results.json")
+	out := MergeResults(shardOutput("1/1", 2, []domain.TestFileResult{{Path: "tests/ATest.php", Success: true}}, nil, nil))
+	if err := WriteResults(path, out); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadResults(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Meta.TotalTestFiles != 1 || read.Files[0].Path != "tests/ATest.php" {
+		t.Errorf("unexpected round trip: %+v", read)
+	}
+}
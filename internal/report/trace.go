@@ -0,0 +1,113 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"ptp/internal/domain"
+)
+
+// ErrNoTimeline is returned for runs stored without a worker timeline (older runs, merged shards)
+var ErrNoTimeline = errors.New("the run has no worker timeline")
+
+// traceFile is the JSON object form of the Chrome trace event format
+type traceFile struct {
+	TraceEvents     []traceEvent      `json:"traceEvents"`
+	DisplayTimeUnit string            `json:"displayTimeUnit"`
+	OtherData       map[string]string `json:"otherData,omitempty"`
+}
+
+type traceEvent struct {
+	Name      string         `json:"name"`
+	Category  string         `json:"cat,omitempty"`
+	Color     string         `json:"cname,omitempty"`
+	Phase     string         `json:"ph"`
+	Timestamp int64          `json:"ts"`
+	Duration  int64          `json:"dur,omitempty"`
+	PID       int            `json:"pid"`
+	TID       int            `json:"tid"`
+	Scope     string         `json:"s,omitempty"`
+	Args      map[string]any `json:"args,omitempty"`
+}
+
+// WriteTrace writes the worker timeline of a run in the Chrome trace event format, for
+// chrome://tracing or ui.perfetto.dev: one thread per worker, one slice per test file (or chunk)
+// categorized and colored by its status, and a marker where the execution ended so idle tails stand out.
+func WriteTrace(w io.Writer, out *domain.TestResultsOutput) error {
+	if len(out.Timeline) == 0 {
+		return ErrNoTimeline
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	if err := enc.Encode(buildTrace(out)); err != nil {
+		return fmt.Errorf("encode trace: %w", err)
+	}
+	return nil
+}
+
+// WriteTraceFile writes the trace to path, creating parent directories as needed
+func WriteTraceFile(path string, out *domain.TestResultsOutput) error {
+	if len(out.Timeline) == 0 {
+		return ErrNoTimeline
+	}
+	return writeFile(path, func(w io.Writer) error { return WriteTrace(w, out) })
+}
+
+// spanColors are the reserved Chrome trace colors of the span statuses, so failures and hangs stand out
+var spanColors = map[string]string{
+	domain.CasePassed:      "good",
+	domain.CaseFailed:      "bad",
+	domain.SpanTimedOut:    "terrible",
+	domain.SpanInterrupted: "grey",
+}
+
+func buildTrace(out *domain.TestResultsOutput) traceFile {
+	name := "ptp run"
+	if out.Meta.RunID != "" {
+		name += " " + out.Meta.RunID
+	}
+	trace := traceFile{
+		DisplayTimeUnit: "ms",
+		TraceEvents:     []traceEvent{{Name: "process_name", Phase: "M", PID: 1, Args: map[string]any{"name": name}}},
+		OtherData:       map[string]string{"run_id": out.Meta.RunID, "git_commit": out.Meta.GitCommit, "git_branch": out.Meta.GitBranch},
+	}
+	seen := make(map[int]bool)
+	var end float64
+	for _, s := range out.Timeline {
+		if !seen[s.WorkerID] {
+			seen[s.WorkerID] = true
+			trace.TraceEvents = append(trace.TraceEvents,
+				traceEvent{Name: "thread_name", Phase: "M", PID: 1, TID: s.WorkerID, Args: map[string]any{"name": fmt.Sprintf("worker %d", s.WorkerID)}},
+				traceEvent{Name: "thread_sort_index", Phase: "M", PID: 1, TID: s.WorkerID, Args: map[string]any{"sort_index": s.WorkerID}},
+			)
+		}
+		args := map[string]any{"path": filepath.ToSlash(s.Path), "status": s.Status}
+		label := filepath.Base(s.Path)
+		if s.Chunks > 1 {
+			args["chunk"] = fmt.Sprintf("%d/%d", s.Chunk, s.Chunks)
+			label = fmt.Sprintf("%s [%d/%d]", label, s.Chunk, s.Chunks)
+		}
+		trace.TraceEvents = append(trace.TraceEvents, traceEvent{
+			Name:      label,
+			Category:  s.Status,
+			Color:     spanColors[s.Status],
+			Phase:     "X",
+			Timestamp: microseconds(s.StartSeconds),
+			Duration:  max(microseconds(s.EndSeconds)-microseconds(s.StartSeconds), 1),
+			PID:       1,
+			TID:       s.WorkerID,
+			Args:      args,
+		})
+		end = max(end, s.EndSeconds)
+	}
+	end = max(end, out.Meta.DurationSeconds)
+	trace.TraceEvents = append(trace.TraceEvents, traceEvent{Name: "execution finished", Phase: "i", Timestamp: microseconds(end), PID: 1, Scope: "g"})
+	return trace
+}
+
+func microseconds(seconds float64) int64 {
+	return int64(seconds * 1e6)
+}
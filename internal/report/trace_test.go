@@ -0,0 +1,55 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"ptp/internal/domain"
+)
+
+func TestWriteTrace(t *testing.T) {
+	out := &domain.TestResultsOutput{
+		Meta: domain.TestResultsMeta{RunInfo: domain.RunInfo{RunID: "20260114-0932"}, DurationSeconds: 4},
+		Timeline: []domain.WorkerSpan{
+			{WorkerID: 1, Path: "tests/Unit/UserTest.php", StartSeconds: 0, EndSeconds: 3.5, Status: domain.CaseFailed},
+			{WorkerID: 2, Path: "tests/Unit/OrderTest.php", Chunk: 1, Chunks: 2, StartSeconds: 0.25, EndSeconds: 1, Status: domain.CasePassed},
+			{WorkerID: 2, Path: "tests/Unit/HangTest.php", StartSeconds: 1, EndSeconds: 4, Status: domain.SpanTimedOut},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTrace(&buf, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var trace traceFile
+	if err := json.Unmarshal(buf.Bytes(), &trace); err != nil {
+		t.Fatalf("invalid trace JSON: %v", err)
+	}
+
+	var slices, threads int
+	for _, e := range trace.TraceEvents {
+		switch {
+		case e.Phase == "X":
+			slices++
+			if e.Name == "HangTest.php" && (e.Category != domain.SpanTimedOut || e.Color != "terrible") {
+				t.Errorf("expected the timed-out file to stand out, got %+v", e)
+			}
+			if e.TID == 2 && e.Name != "HangTest.php" && (e.Name != "OrderTest.php [1/2]" || e.Timestamp != 250000 || e.Duration != 750000 || e.Category != domain.CasePassed) {
+				t.Errorf("unexpected slice %+v", e)
+			}
+		case e.Phase == "M" && e.Name == "thread_name":
+			threads++
+		case e.Phase == "i" && e.Timestamp != 4000000:
+			t.Errorf("expected the end marker at the run duration, got %+v", e)
+		}
+	}
+	if slices != 3 || threads != 2 {
+		t.Errorf("expected 3 slices on 2 threads, got %d on %d", slices, threads)
+	}
+
+	if err := WriteTrace(&buf, &domain.TestResultsOutput{}); !errors.Is(err, ErrNoTimeline) {
+		t.Errorf("expected ErrNoTimeline without a timeline, got %v", err)
+	}
+}
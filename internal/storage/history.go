@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"ptp/internal/debug"
+	"ptp/internal/domain"
+)
+
+// runIDLayout formats run ids so that they sort chronologically
+const runIDLayout = "20060102-150405"
+
+// ListRuns returns the metadata of the retained runs, newest first.
+func (s *JSONStorage) ListRuns() ([]domain.TestResultsMeta, error) {
+	ids, err := s.runIDs()
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]domain.TestResultsMeta, 0, len(ids))
+	for i := len(ids) - 1; i >= 0; i-- {
+		data, err := os.ReadFile(s.runPath(ids[i]))
+		if err != nil {
+			return nil, fmt.Errorf("read run %s: %w", ids[i], err)
+		}
+		var run struct {
+			Meta domain.TestResultsMeta `json:"meta"`
+		}
+		if err := json.Unmarshal(data, &run); err != nil {
+			debug.Logf("storage: skipping unreadable run %s: %v", ids[i], err)
+			continue
+		}
+		runs = append(runs, run.Meta)
+	}
+	return runs, nil
+}
+
+// LoadRun reads a retained run by its id or a unique prefix of it.
+func (s *JSONStorage) LoadRun(id string) (*domain.TestResultsOutput, error) {
+	ids, err := s.runIDs()
+	if err != nil {
+		return nil, err
+	}
+	var matches []string
+	for _, candidate := range ids {
+		if candidate == id {
+			matches = []string{candidate}
+			break
+		}
+		if strings.HasPrefix(candidate, id) {
+			matches = append(matches, candidate)
+		}
+	}
+	switch {
+	case id == "" || len(matches) == 0:
+		return nil, fmt.Errorf("run %q not found (see ptp history)", id)
+	case len(matches) > 1:
+		return nil, fmt.Errorf("run id %q is ambiguous: matches %s", id, strings.Join(matches, ", "))
+	}
+
+	path := s.runPath(matches[0])
+	debug.Logf("storage: loading run %s from %s", matches[0], path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read run %s: %w", matches[0], err)
+	}
+	var output domain.TestResultsOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, fmt.Errorf("parse run %s: %w", matches[0], err)
+	}
+	return &output, nil
+}
+
+// newRunID returns an unused run id for a run started at t
+func (s *JSONStorage) newRunID(t time.Time) string {
+	id := t.Format(runIDLayout)
+	for n := 2; ; n++ {
+		if _, err := os.Stat(s.runPath(id)); errors.Is(err, os.ErrNotExist) {
+			return id
+		}
+		id = fmt.Sprintf("%s-%d", t.Format(runIDLayout), n)
+	}
+}
+
+// saveRun adds a run to the history and drops the oldest runs beyond the configured size.
+// The history copy leaves out the aggregated timings and flaky history, which only the
+// latest results file carries.
+func (s *JSONStorage) saveRun(output domain.TestResultsOutput) error {
+	if s.cfg.HistorySize <= 0 || output.Meta.RunID == "" {
+		return nil
+	}
+	output.Timings = nil
+	output.FlakyHistory = nil
+	data, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal run: %w", err)
+	}
+	if err := os.MkdirAll(s.cfg.GetHistoryDir(), 0755); err != nil {
+		return fmt.Errorf("create history dir: %w", err)
+	}
+	path := s.runPath(output.Meta.RunID)
+	debug.Logf("storage: saving run %s to %s (%d bytes)", output.Meta.RunID, path, len(data))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("write run: %w", err)
+	}
+
+	ids, err := s.runIDs()
+	if err != nil {
+		return err
+	}
+	for len(ids) > s.cfg.HistorySize {
+		debug.Logf("storage: dropping run %s from the history", ids[0])
+		if err := os.Remove(s.runPath(ids[0])); err != nil {
+			return fmt.Errorf("remove old run: %w", err)
+		}
+		ids = ids[1:]
+	}
+	return nil
+}
+
+// hasRun reports whether a run with exactly this id is in the history
+func (s *JSONStorage) hasRun(id string) bool {
+	if id == "" {
+		return false
+	}
+	_, err := os.Stat(s.runPath(id))
+	return err == nil
+}
+
+// runIDs returns the ids of the retained runs, oldest first
+func (s *JSONStorage) runIDs() ([]string, error) {
+	entries, err := os.ReadDir(s.cfg.GetHistoryDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read history dir: %w", err)
+	}
+	var ids []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			ids = append(ids, strings.TrimSuffix(e.Name(), ".json"))
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return runIDLess(ids[i], ids[j]) })
+	return ids, nil
+}
+
+// runIDLess orders run ids chronologically; "20260101-120000-10" comes after "...-2"
+func runIDLess(a, b string) bool {
+	if len(a) >= len(runIDLayout) && len(b) >= len(runIDLayout) && a[:len(runIDLayout)] != b[:len(runIDLayout)] {
+		return a < b
+	}
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+func (s *JSONStorage) runPath(id string) string {
+	return filepath.Join(s.cfg.GetHistoryDir(), id+".json")
+}
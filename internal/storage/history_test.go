@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"ptp/internal/config"
+	"ptp/internal/domain"
+)
+
+func newTestStorage(t *testing.T, historySize int) *JSONStorage {
+	t.Helper()
+	cfg := config.New()
+	cfg.ProjectPath = t.TempDir()
+	cfg.HistorySize = historySize
+	return NewJSONStorage(cfg)
+}
+
+func TestJSONStorage_RunHistory(t *testing.T) {
+	s := newTestStorage(t, 2)
+	results := []domain.TestResult{{TestPath: "tests/UserTest.php", Success: true, Duration: time.Second}}
+
+	for _, id := range []string{"20260101-100000", "20260101-110000", "20260101-120000"} {
+		if err := s.Save(results, nil, time.Second, 1, domain.RunInfo{RunID: id, GitBranch: "main"}); err != nil {
+			t.Fatalf("Save(%s) failed: %v", id, err)
+		}
+	}
+
+	runs, err := s.ListRuns()
+	if err != nil {
+		t.Fatalf("ListRuns failed: %v", err)
+	}
+	if len(runs) != 2 || runs[0].RunID != "20260101-120000" || runs[1].RunID != "20260101-110000" {
+		t.Fatalf("expected the 2 newest runs, newest first, got %+v", runs)
+	}
+	if runs[0].GitBranch != "main" || runs[0].TotalTestFiles != 1 {
+		t.Errorf("unexpected run metadata: %+v", runs[0])
+	}
+
+	run, err := s.LoadRun("20260101-11")
+	if err != nil {
+		t.Fatalf("LoadRun by prefix failed: %v", err)
+	}
+	if run.Meta.RunID != "20260101-110000" || len(run.Files) != 1 {
+		t.Errorf("unexpected run: %+v", run.Meta)
+	}
+	if run.Timings != nil {
+		t.Error("expected history entries to leave out the aggregated timings")
+	}
+	if _, err := s.LoadRun("20260101"); err == nil {
+		t.Error("expected an error for an ambiguous run id")
+	}
+	if _, err := s.LoadRun("20260101-100000"); err == nil {
+		t.Error("expected the oldest run to be dropped from the history")
+	}
+}
+
+func TestJSONStorage_SaveOutputOfOlderRun(t *testing.T) {
+	s := newTestStorage(t, 5)
+	failed := []domain.TestResult{{TestPath: "tests/UserTest.php", Duration: time.Second}}
+	failures := []domain.TestFailure{{FilePath: "tests/UserTest.php", TestName: "testCreate"}}
+	if err := s.Save(failed, failures, time.Second, 1, domain.RunInfo{RunID: "20260101-100000"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(failed, failures, time.Second, 1, domain.RunInfo{RunID: "20260101-110000"}); err != nil {
+		t.Fatal(err)
+	}
+
+	old, err := s.LoadRun("20260101-100000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old.Details = nil
+	if err := s.SaveOutput(old); err != nil {
+		t.Fatalf("SaveOutput failed: %v", err)
+	}
+
+	if reloaded, _ := s.LoadRun("20260101-100000"); len(reloaded.Details) != 0 {
+		t.Error("expected the history entry to be updated")
+	}
+	latest, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Meta.RunID != "20260101-110000" || len(latest.Details) != 1 {
+		t.Errorf("expected the latest results to be left alone, got run %s with %d failures", latest.Meta.RunID, len(latest.Details))
+	}
+}
+
+func TestJSONStorage_HistoryDisabled(t *testing.T) {
+	s := newTestStorage(t, 0)
+	if err := s.Save(nil, nil, time.Second, 1, domain.RunInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	latest, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Meta.RunID == "" {
+		t.Error("expected a run id even without history")
+	}
+	if _, err := os.Stat(s.cfg.GetHistoryDir()); !os.IsNotExist(err) {
+		t.Errorf("expected no history dir, got err=%v", err)
+	}
+}
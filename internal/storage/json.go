@@ -5,41 +5,126 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"ptp/internal/debug"
 	"ptp/internal/domain"
 )
 
-// Save writes test results and failures to the configured JSON output file.
-func (s *JSONStorage) Save(results []domain.TestResult, failures []domain.TestFailure, duration time.Duration, workers int) error {
-	passed := 0
-	failed := 0
-	for _, r := range results {
-		if r.Success {
-			passed++
-		} else {
-			failed++
-		}
+// Save writes test results and failures to the configured JSON output file and adds the run to
+// the run history. A run id is assigned when run has none.
+func (s *JSONStorage) Save(results []domain.TestResult, failures []domain.TestFailure, duration time.Duration, workers int, run domain.RunInfo) error {
+	prev, err := s.Load()
+	if err != nil {
+		prev = nil
+	}
+	started := time.Now()
+	now := started.Format(time.RFC3339)
+	if run.RunID == "" {
+		run.RunID = s.newRunID(started)
 	}
 
-	timings := s.mergeTimings(results)
-
+	files := fileResults(results)
 	output := domain.TestResultsOutput{
 		Meta: domain.TestResultsMeta{
-			TotalTestFiles:  len(results),
-			FailedTestFiles: failed,
-			PassedTestFiles: passed,
-			FailedTestCases: len(failures),
+			RunInfo:         run,
 			Duration:        duration.String(),
 			DurationSeconds: duration.Seconds(),
 			Workers:         workers,
-			Timestamp:       time.Now().Format(time.RFC3339),
+			Timestamp:       now,
 		},
-		Details: failures,
-		Timings: timings,
+		Details:      failures,
+		Files:        files,
+		Timings:      mergeTimings(prev, results),
+		FlakyHistory: mergeFlakyHistory(prev, failures, now),
+		Timeline:     timeline(results),
 	}
+	output.Summarize()
 
+	if err := s.writeOutput(&output); err != nil {
+		return err
+	}
+	if err := s.saveRun(output); err != nil {
+		debug.Logf("storage: history error: %v", err)
+		return fmt.Errorf("save run history: %w", err)
+	}
+	return nil
+}
+
+// Update merges the results of a partial run (e.g. ptp watch) into the last output: the files
+// that ran replace their earlier results and failures, everything else is kept; the timeline is
+// the partial run's. The run history is left alone.
+func (s *JSONStorage) Update(results []domain.TestResult, failures []domain.TestFailure) error {
+	prev, err := s.Load()
+	if err != nil {
+		prev = &domain.TestResultsOutput{}
+	}
+	now := time.Now().Format(time.RFC3339)
+
+	ran := make(map[string]bool, len(results))
+	for _, r := range results {
+		ran[filepath.Clean(r.TestPath)] = true
+	}
+	output := *prev
+	output.Meta.Timestamp = now
+	output.Files = fileResults(results)
+	for _, f := range prev.Files {
+		if !ran[filepath.Clean(f.Path)] {
+			output.Files = append(output.Files, f)
+		}
+	}
+	sort.Slice(output.Files, func(i, j int) bool { return output.Files[i].Path < output.Files[j].Path })
+	output.Details = nil
+	for _, f := range prev.Details {
+		if !ran[filepath.Clean(f.FilePath)] {
+			output.Details = append(output.Details, f)
+		}
+	}
+	output.Details = append(output.Details, failures...)
+	output.Timings = mergeTimings(prev, results)
+	output.FlakyHistory = mergeFlakyHistory(prev, failures, now)
+	output.Timeline = timeline(results)
+	output.Summarize()
+	debug.Logf("storage: merged %d results and %d failures into the last output", len(results), len(failures))
+	return s.writeOutput(&output)
+}
+
+// fileResults returns the per-file summaries of results, sorted by path
+func fileResults(results []domain.TestResult) []domain.TestFileResult {
+	files := make([]domain.TestFileResult, 0, len(results))
+	for _, r := range results {
+		files = append(files, domain.TestFileResult{
+			Path:            r.TestPath,
+			Success:         r.Success,
+			DurationSeconds: r.Duration.Seconds(),
+			WorkerID:        r.WorkerID,
+			Interrupted:     r.Interrupted,
+			TimedOut:        r.TimedOut,
+			Cases:           r.Cases,
+		})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
+
+// timeline returns the spans of the workers recorded in results, sorted by worker and start
+func timeline(results []domain.TestResult) []domain.WorkerSpan {
+	var spans []domain.WorkerSpan
+	for _, r := range results {
+		spans = append(spans, r.Spans...)
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].WorkerID != spans[j].WorkerID {
+			return spans[i].WorkerID < spans[j].WorkerID
+		}
+		return spans[i].StartSeconds < spans[j].StartSeconds
+	})
+	return spans
+}
+
+// writeOutput writes the output to the configured JSON file
+func (s *JSONStorage) writeOutput(output *domain.TestResultsOutput) error {
 	data, err := json.MarshalIndent(output, "", "  ")
 	if err != nil {
 		debug.Logf("storage: marshal error: %v", err)
@@ -77,12 +162,11 @@ func (s *JSONStorage) Load() (*domain.TestResultsOutput, error) {
 	return &output, nil
 }
 
-// mergeTimings loads historical timings and merges in new results using a running average.
-func (s *JSONStorage) mergeTimings(results []domain.TestResult) map[string]*domain.TestTiming {
+// mergeTimings merges new results into the previous run's timings using a running average.
+func mergeTimings(prev *domain.TestResultsOutput, results []domain.TestResult) map[string]*domain.TestTiming {
 	timings := make(map[string]*domain.TestTiming)
 
-	prev, err := s.Load()
-	if err == nil && prev != nil && prev.Timings != nil {
+	if prev != nil && prev.Timings != nil {
 		for k, v := range prev.Timings {
 			cp := *v
 			timings[k] = &cp
@@ -90,6 +174,10 @@ func (s *JSONStorage) mergeTimings(results []domain.TestResult) map[string]*doma
 	}
 
 	for _, r := range results {
+		if r.Interrupted || r.TimedOut {
+			// A cancelled or killed file's duration says nothing about how long it takes
+			continue
+		}
 		dur := r.Duration.Seconds()
 		if t, ok := timings[r.TestPath]; ok {
 			t.Avg = (float64(t.Count)*t.Avg + dur) / float64(t.Count+1)
@@ -102,6 +190,40 @@ func (s *JSONStorage) mergeTimings(results []domain.TestResult) map[string]*doma
 	return timings
 }
 
+// mergeFlakyHistory carries over the previous run's flaky history and counts this run's flaky failures.
+func mergeFlakyHistory(prev *domain.TestResultsOutput, failures []domain.TestFailure, now string) map[string]*domain.FlakyStats {
+	history := make(map[string]*domain.FlakyStats)
+	if prev != nil {
+		for k, v := range prev.FlakyHistory {
+			cp := *v
+			history[k] = &cp
+		}
+	}
+
+	for _, f := range failures {
+		if !f.Flaky {
+			continue
+		}
+		key := FlakyKey(f)
+		if h, ok := history[key]; ok {
+			h.Count++
+			h.LastSeen = now
+		} else {
+			history[key] = &domain.FlakyStats{FilePath: f.FilePath, TestName: f.TestName, Count: 1, FirstSeen: now, LastSeen: now}
+		}
+	}
+
+	if len(history) == 0 {
+		return nil
+	}
+	return history
+}
+
+// FlakyKey identifies a test case in the flaky history
+func FlakyKey(f domain.TestFailure) string {
+	return filepath.ToSlash(f.FilePath) + "::" + f.TestName
+}
+
 // LoadTimings returns historical per-test timing data, or nil if unavailable.
 func (s *JSONStorage) LoadTimings() map[string]*domain.TestTiming {
 	prev, err := s.Load()
@@ -112,7 +234,18 @@ func (s *JSONStorage) LoadTimings() map[string]*domain.TestTiming {
 }
 
 // SaveOutput writes the full output to the configured JSON file (e.g. after re-running selected tests).
+// An output loaded from the run history is written back to its history entry only, unless it is
+// also the latest run.
 func (s *JSONStorage) SaveOutput(output *domain.TestResultsOutput) error {
+	if s.hasRun(output.Meta.RunID) {
+		if err := s.saveRun(*output); err != nil {
+			debug.Logf("storage: history error (SaveOutput): %v", err)
+			return fmt.Errorf("save run history: %w", err)
+		}
+		if latest, err := s.Load(); err == nil && latest.Meta.RunID != output.Meta.RunID {
+			return nil
+		}
+	}
 	data, err := json.MarshalIndent(output, "", "  ")
 	if err != nil {
 		debug.Logf("storage: marshal error (SaveOutput): %v", err)
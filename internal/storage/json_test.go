@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"ptp/internal/domain"
+)
+
+func TestJSONStorage_Update(t *testing.T) {
+	s := newTestStorage(t, 5)
+	results := []domain.TestResult{
+		{TestPath: "tests/PostTest.php", Success: true, Duration: time.Second},
+		{TestPath: "tests/UserTest.php", Duration: time.Second},
+	}
+	failures := []domain.TestFailure{{FilePath: "tests/UserTest.php", TestName: "testCreate"}}
+	if err := s.Save(results, failures, time.Second, 2, domain.RunInfo{RunID: "20260101-100000"}); err != nil {
+		t.Fatal(err)
+	}
+
+	fixed := []domain.TestResult{{TestPath: "tests/UserTest.php", Success: true, Duration: 3 * time.Second}}
+	if err := s.Update(fixed, nil); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	out, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Details) != 0 || out.Meta.FailedTestCases != 0 {
+		t.Errorf("expected the fixed file's failures to be dropped, got %+v", out.Details)
+	}
+	if out.Meta.TotalTestFiles != 2 || out.Meta.PassedTestFiles != 2 || out.Meta.RunID != "20260101-100000" {
+		t.Errorf("unexpected meta after update: %+v", out.Meta)
+	}
+	if timing := out.Timings["tests/UserTest.php"]; timing == nil || timing.Count != 2 {
+		t.Errorf("expected the update to feed the timings, got %+v", timing)
+	}
+
+	broken := []domain.TestResult{{TestPath: "tests/PostTest.php", Duration: time.Second}}
+	if err := s.Update(broken, []domain.TestFailure{{FilePath: "tests/PostTest.php", TestName: "testList"}}); err != nil {
+		t.Fatal(err)
+	}
+	if out, _ = s.Load(); len(out.Details) != 1 || out.Meta.FailedTestFiles != 1 || out.Meta.PassedTestFiles != 1 {
+		t.Errorf("unexpected output after a new failure: %+v", out.Meta)
+	}
+
+	run, err := s.LoadRun("20260101-100000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(run.Details) != 1 || run.Details[0].TestName != "testCreate" {
+		t.Errorf("expected the run history to be left alone, got %+v", run.Details)
+	}
+}
+
+func TestJSONStorage_SaveTimeline(t *testing.T) {
+	s := newTestStorage(t, 5)
+	results := []domain.TestResult{
+		{TestPath: "tests/UserTest.php", WorkerID: 2, Spans: []domain.WorkerSpan{
+			{WorkerID: 2, Path: "tests/UserTest.php", Chunk: 1, Chunks: 2, StartSeconds: 1, EndSeconds: 2, Status: domain.CasePassed},
+			{WorkerID: 1, Path: "tests/UserTest.php", Chunk: 2, Chunks: 2, StartSeconds: 2, EndSeconds: 3, Status: domain.CaseFailed},
+		}},
+		{TestPath: "tests/PostTest.php", WorkerID: 1, Success: true, Spans: []domain.WorkerSpan{
+			{WorkerID: 1, Path: "tests/PostTest.php", StartSeconds: 0, EndSeconds: 2, Status: domain.CasePassed},
+		}},
+	}
+	if err := s.Save(results, nil, 3*time.Second, 2, domain.RunInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	out, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, span := range out.Timeline {
+		got = append(got, fmt.Sprintf("w%d %s#%d", span.WorkerID, span.Path, span.Chunk))
+	}
+	want := "[w1 tests/PostTest.php#0 w1 tests/UserTest.php#2 w2 tests/UserTest.php#1]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("expected the timeline %s, got %v", want, got)
+	}
+}
+
+func TestMergeTimings_SkipsUnfinishedFiles(t *testing.T) {
+	prev := &domain.TestResultsOutput{Timings: map[string]*domain.TestTiming{
+		"tests/SlowTest.php": {Count: 2, Avg: 4},
+	}}
+	results := []domain.TestResult{
+		{TestPath: "tests/SlowTest.php", TimedOut: true, Duration: 5 * time.Minute},
+		{TestPath: "tests/HungTest.php", TimedOut: true, Duration: 5 * time.Minute},
+		{TestPath: "tests/StoppedTest.php", Interrupted: true, Duration: time.Second},
+	}
+
+	timings := mergeTimings(prev, results)
+	if slow := timings["tests/SlowTest.php"]; slow.Count != 2 || slow.Avg != 4 {
+		t.Errorf("expected a timed-out run to leave the timing alone, got %+v", slow)
+	}
+	if len(timings) != 1 {
+		t.Errorf("expected no timings for timed-out or interrupted files, got %v", timings)
+	}
+}
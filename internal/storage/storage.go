@@ -9,8 +9,14 @@ import (
 
 // Storage persists and loads test run results (e.g. for the faills viewer).
 type Storage interface {
-	Save(results []domain.TestResult, failures []domain.TestFailure, duration time.Duration, workers int) error
+	Save(results []domain.TestResult, failures []domain.TestFailure, duration time.Duration, workers int, run domain.RunInfo) error
 	Load() (*domain.TestResultsOutput, error)
+	// ListRuns returns the metadata of the runs kept in the run history, newest first.
+	ListRuns() ([]domain.TestResultsMeta, error)
+	// LoadRun reads a run from the history by id (or unique id prefix).
+	LoadRun(id string) (*domain.TestResultsOutput, error)
+	// Update merges the results of a partial run into the last output, without a history entry.
+	Update(results []domain.TestResult, failures []domain.TestFailure) error
 	// SaveOutput writes the full output (e.g. after partial re-run updates).
 	SaveOutput(output *domain.TestResultsOutput) error
 	// LoadTimings returns historical per-test timing data (nil map if unavailable).
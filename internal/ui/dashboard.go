@@ -0,0 +1,350 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"ptp/internal/domain"
+	"ptp/internal/execution"
+	"ptp/internal/parser"
+	"ptp/internal/report"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-isatty"
+	"github.com/rivo/tview"
+)
+
+// Progress displays of `ptp run --ui`
+const (
+	UIPlain     = "plain"
+	UIDashboard = "dashboard"
+)
+
+// dashboardRefresh is how often the dashboard redraws the elapsed times and the ETA
+const dashboardRefresh = 250 * time.Millisecond
+
+// Dashboard is the live TUI of `ptp run --ui=dashboard`: each worker's current file, the failures
+// as they arrive and an ETA from the historical timings. It replaces the progress bar.
+type Dashboard struct {
+	parser        parser.Parser
+	total         int
+	testCaseCount int
+
+	mu    sync.Mutex
+	state *dashboardState
+
+	app      *tview.Application
+	header   *tview.TextView
+	workers  *tview.Table
+	failures *tview.TextView
+	footer   *tview.TextView
+	shown    int // failures already written to the failures view
+	keys     chan rune
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// workerStatus is what a worker is doing: the job it runs and since when ("" when idle)
+type workerStatus struct {
+	job     string
+	started time.Time
+}
+
+// dashboardState is the progress shown by the dashboard
+type dashboardState struct {
+	started  time.Time
+	workers  []workerStatus           // by worker ID - 1
+	pending  map[string]time.Duration // estimated duration of the files not completed yet
+	files    int
+	passed   int
+	failed   int
+	failures []domain.TestFailure
+	finished bool
+	status   string
+}
+
+func newDashboardState(tests []string, workers int, estimate func(string) time.Duration, now time.Time) *dashboardState {
+	s := &dashboardState{
+		started: now,
+		workers: make([]workerStatus, max(workers, 1)),
+		pending: make(map[string]time.Duration, len(tests)),
+	}
+	for _, t := range tests {
+		s.pending[t] = estimate(t)
+	}
+	return s
+}
+
+// eta returns the estimated time left: the predicted duration of the files not completed yet, less
+// the time the workers already spent on them, spread over the workers. It is negative when unknown.
+func (s *dashboardState) eta(now time.Time) time.Duration {
+	if len(s.pending) == 0 {
+		return 0
+	}
+	var left time.Duration
+	for _, d := range s.pending {
+		left += d
+	}
+	if left == 0 {
+		return -1
+	}
+	for _, w := range s.workers {
+		if w.job != "" {
+			left -= now.Sub(w.started)
+		}
+	}
+	return max(left, 0) / time.Duration(len(s.workers))
+}
+
+// DashboardSupported reports whether the dashboard can be shown: stdout must be a terminal, so CI
+// logs and pipes get the plain progress bar
+func DashboardSupported() bool {
+	fd := os.Stdout.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+// NewDashboard creates a dashboard for a run of tests on the given number of workers; estimate
+// predicts the duration of a test file for the ETA
+func NewDashboard(resultParser parser.Parser, tests []string, testCaseCount, workers int, estimate func(string) time.Duration) *Dashboard {
+	return &Dashboard{
+		parser:        resultParser,
+		total:         len(tests),
+		testCaseCount: testCaseCount,
+		state:         newDashboardState(tests, workers, estimate, time.Now()),
+		keys:          make(chan rune, 1),
+		done:          make(chan struct{}),
+	}
+}
+
+// Start takes over the terminal. Ctrl+C calls cancel to interrupt the run, as the signal would
+// without the dashboard.
+func (d *Dashboard) Start(cancel context.CancelFunc) error {
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return fmt.Errorf("failed to open the terminal: %w", err)
+	}
+
+	d.header = tview.NewTextView().SetDynamicColors(true)
+	d.header.SetBackgroundColor(faillsBgDark)
+
+	d.workers = tview.NewTable()
+	d.workers.SetBackgroundColor(faillsBgDark)
+	d.workers.SetBorder(true).SetTitle(" Workers ").SetBorderColor(faillsBorder).SetTitleColor(faillsAccent)
+
+	d.failures = tview.NewTextView().SetDynamicColors(true).SetScrollable(true)
+	d.failures.SetBackgroundColor(faillsBgDark)
+	d.failures.SetBorder(true).SetTitle(" Failures ").SetBorderColor(faillsBorder).SetTitleColor(faillsAccent)
+	d.failures.ScrollToEnd()
+
+	d.footer = tview.NewTextView().SetDynamicColors(true).SetTextAlign(tview.AlignCenter)
+	d.footer.SetBackgroundColor(faillsBorder)
+
+	root := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(d.header, 1, 0, false).
+		AddItem(d.workers, len(d.state.workers)+2, 0, false).
+		AddItem(d.failures, 0, 1, true).
+		AddItem(d.footer, 1, 0, false)
+
+	d.app = tview.NewApplication().SetScreen(screen).SetRoot(root, true).SetFocus(d.failures)
+	d.app.SetBeforeDrawFunc(func(screen tcell.Screen) bool {
+		screen.SetStyle(tcell.StyleDefault.Foreground(faillsFg).Background(faillsBgDark))
+		return false
+	})
+	d.app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		d.mu.Lock()
+		finished := d.state.finished
+		d.mu.Unlock()
+		switch {
+		case event.Key() == tcell.KeyCtrlC && !finished:
+			d.SetStatus("Stopping...")
+			cancel()
+			return nil
+		case !finished:
+			return event
+		case event.Key() == tcell.KeyRune && event.Rune() == 'f':
+			d.press('f')
+			return nil
+		case event.Key() == tcell.KeyCtrlC || event.Key() == tcell.KeyEnter || event.Key() == tcell.KeyEsc ||
+			(event.Key() == tcell.KeyRune && event.Rune() == 'q'):
+			d.press('q')
+			return nil
+		}
+		return event
+	})
+	d.render()
+
+	go func() {
+		defer close(d.done)
+		if err := d.app.Run(); err != nil {
+			d.press('q')
+		}
+	}()
+	go func() {
+		ticker := time.NewTicker(dashboardRefresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-d.done:
+				return
+			case <-ticker.C:
+				d.app.QueueUpdateDraw(d.render)
+			}
+		}
+	}()
+	return nil
+}
+
+func (d *Dashboard) press(key rune) {
+	select {
+	case d.keys <- key:
+	default:
+	}
+}
+
+// Update records the completed files and test case counts
+func (d *Dashboard) Update(filesCompleted, passedCases, failedCases int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.state.files, d.state.passed, d.state.failed = filesCompleted, passedCases, failedCases
+}
+
+// Finish marks the execution as complete; the dashboard stays up until Close
+func (d *Dashboard) Finish() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := range d.state.workers {
+		d.state.workers[i] = workerStatus{}
+	}
+}
+
+// WorkerStarted is part of execution.WorkerObserver; the dashboard lists every worker from the start
+func (d *Dashboard) WorkerStarted(workerID int, remote string) {}
+
+// JobStarted shows the job a worker started
+func (d *Dashboard) JobStarted(workerID int, job execution.Job) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if workerID >= 1 && workerID <= len(d.state.workers) {
+		d.state.workers[workerID-1] = workerStatus{job: job.String(), started: time.Now()}
+	}
+}
+
+// JobFinished shows a worker as idle
+func (d *Dashboard) JobFinished(workerID int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if workerID >= 1 && workerID <= len(d.state.workers) {
+		d.state.workers[workerID-1] = workerStatus{}
+	}
+}
+
+// FileFinished removes a completed file from the ETA and adds its failures to the list
+func (d *Dashboard) FileFinished(result domain.TestResult) {
+	var failures []domain.TestFailure
+	if !result.Success && !result.Interrupted {
+		failures = d.parser.ParseFailure(result)
+		if len(failures) == 0 {
+			failures = []domain.TestFailure{{FilePath: result.TestPath, Message: result.Output}}
+		}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.state.pending, result.TestPath)
+	d.state.failures = append(d.state.failures, failures...)
+}
+
+// SetStatus shows a message in the footer (e.g. while failed tests are retried)
+func (d *Dashboard) SetStatus(status string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.state.status = status
+}
+
+// Close gives the terminal back. With failures, it first waits for a key and returns true when it
+// was f, to browse the failures in the error viewer.
+func (d *Dashboard) Close(failures int) bool {
+	defer d.Stop()
+	if failures == 0 {
+		return false
+	}
+	d.mu.Lock()
+	d.state.finished = true
+	d.state.status = fmt.Sprintf("[red]%d test case(s) failed[-]  [#00bcd4]f[-] Browse failures  [#00bcd4]q[-]/[#00bcd4]Enter[-] Exit", failures)
+	d.mu.Unlock()
+	d.app.QueueUpdateDraw(d.render)
+	select {
+	case key := <-d.keys:
+		return key == 'f'
+	case <-d.done:
+		return false
+	}
+}
+
+// Stop ends the TUI and waits for the terminal to be restored. It can be called more than once.
+func (d *Dashboard) Stop() {
+	d.stopOnce.Do(func() {
+		d.app.Stop()
+		<-d.done
+	})
+}
+
+// render draws the state; it runs on the TUI goroutine
+func (d *Dashboard) render() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s := d.state
+	now := time.Now()
+
+	eta := "-"
+	if e := s.eta(now); e >= 0 {
+		eta = formatDashboardDuration(e)
+	}
+	cases := ""
+	if d.testCaseCount > 0 {
+		cases = fmt.Sprintf(" (%d test cases)", d.testCaseCount)
+	}
+	d.header.SetText(fmt.Sprintf(" [#00bcd4]Running tests[-]%s  %d/%d files  [green]passed: %d[-]  [red]failed: %d[-]  elapsed %s  ETA %s",
+		cases, s.files, d.total, s.passed, s.failed, formatDashboardDuration(now.Sub(s.started)), eta))
+
+	for i, w := range s.workers {
+		job, elapsed := "[gray]idle[-]", ""
+		if w.job != "" {
+			job = tview.Escape(w.job)
+			elapsed = formatDashboardDuration(now.Sub(w.started))
+		}
+		d.workers.SetCell(i, 0, tview.NewTableCell(fmt.Sprintf(" w%d ", i+1)).SetTextColor(faillsAccent))
+		d.workers.SetCell(i, 1, tview.NewTableCell(job).SetExpansion(1))
+		d.workers.SetCell(i, 2, tview.NewTableCell(elapsed+" ").SetAlign(tview.AlignRight))
+	}
+
+	for _, f := range s.failures[d.shown:] {
+		name := f.TestName
+		if name == "" {
+			name = f.FilePath
+		}
+		location := f.FilePath
+		if f.Line > 0 {
+			location = fmt.Sprintf("%s:%d", f.FilePath, f.Line)
+		}
+		fmt.Fprintf(d.failures, "[red]✗[-] %s  [gray]%s[-]\n    %s\n", tview.Escape(name), tview.Escape(location), tview.Escape(report.FirstLine(f.Message)))
+	}
+	d.shown = len(s.failures)
+
+	status := s.status
+	if status == "" {
+		status = "[#00bcd4]↑↓[-] Scroll failures  [#00bcd4]Ctrl+C[-] Stop"
+	}
+	d.footer.SetText(status)
+}
+
+// formatDashboardDuration formats elapsed times and the ETA to the second (tenths under a minute)
+func formatDashboardDuration(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(100 * time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
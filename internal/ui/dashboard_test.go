@@ -0,0 +1,62 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"ptp/internal/domain"
+)
+
+type stubParser struct{ failures []domain.TestFailure }
+
+func (p stubParser) ParseCases(domain.TestResult) []domain.TestCaseResult { return nil }
+func (p stubParser) ParseTestCounts(domain.TestResult) (int, int)         { return 0, 0 }
+func (p stubParser) ParseFailure(domain.TestResult) []domain.TestFailure  { return p.failures }
+
+func TestDashboardState_ETA(t *testing.T) {
+	estimates := map[string]time.Duration{"a": 8 * time.Second, "b": 4 * time.Second, "c": 4 * time.Second}
+	now := time.Now()
+	s := newDashboardState([]string{"a", "b", "c"}, 2, func(test string) time.Duration { return estimates[test] }, now)
+
+	if eta := s.eta(now); eta != 8*time.Second {
+		t.Errorf("expected 16s of work over 2 workers to take 8s, got %s", eta)
+	}
+	s.workers[0] = workerStatus{job: "a", started: now.Add(-2 * time.Second)}
+	if eta := s.eta(now); eta != 7*time.Second {
+		t.Errorf("expected the running time to be deducted, got %s", eta)
+	}
+	s.workers[0] = workerStatus{job: "a", started: now.Add(-time.Minute)}
+	if eta := s.eta(now); eta != 0 {
+		t.Errorf("expected an overrunning file not to make the ETA negative, got %s", eta)
+	}
+
+	s = newDashboardState([]string{"a"}, 1, func(string) time.Duration { return 0 }, now)
+	if eta := s.eta(now); eta >= 0 {
+		t.Errorf("expected an unknown ETA without estimates, got %s", eta)
+	}
+	delete(s.pending, "a")
+	if eta := s.eta(now); eta != 0 {
+		t.Errorf("expected no time left once every file completed, got %s", eta)
+	}
+}
+
+func TestDashboard_FileFinished(t *testing.T) {
+	parsed := []domain.TestFailure{{TestName: "testCreate", FilePath: "tests/UserTest.php"}}
+	d := NewDashboard(stubParser{failures: parsed}, []string{"tests/UserTest.php", "tests/OrderTest.php", "tests/FatalTest.php"}, 0, 2,
+		func(string) time.Duration { return time.Second })
+
+	d.FileFinished(domain.TestResult{TestPath: "tests/OrderTest.php", Success: true})
+	d.FileFinished(domain.TestResult{TestPath: "tests/UserTest.php"})
+	if len(d.state.failures) != 1 || d.state.failures[0].TestName != "testCreate" {
+		t.Errorf("expected the parsed failure, got %+v", d.state.failures)
+	}
+	if len(d.state.pending) != 1 {
+		t.Errorf("expected completed files to leave the ETA, %d pending", len(d.state.pending))
+	}
+
+	d.parser = stubParser{}
+	d.FileFinished(domain.TestResult{TestPath: "tests/FatalTest.php", Output: "PHP Fatal error"})
+	if last := d.state.failures[len(d.state.failures)-1]; last.FilePath != "tests/FatalTest.php" || last.Message != "PHP Fatal error" {
+		t.Errorf("expected a file failure without parsed test cases to be listed, got %+v", last)
+	}
+}
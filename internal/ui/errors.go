@@ -1,7 +1,7 @@
 package ui
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -9,12 +9,12 @@ import (
 	"strconv"
 	"strings"
 	"text/tabwriter"
-	"unicode"
 
 	"github.com/fatih/color"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 	"ptp/internal/config"
+	"ptp/internal/discovery"
 	"ptp/internal/domain"
 	"ptp/internal/parser"
 	"ptp/internal/storage"
@@ -33,7 +33,7 @@ var (
 
 // SingleTestRunner runs a single test case (file + filter). Used by ErrorViewer for rerun.
 type SingleTestRunner interface {
-	RunFiltered(testPath string, filter string, workerID int) domain.TestResult
+	RunFiltered(ctx context.Context, testPath string, filter string, workerID int) domain.TestResult
 }
 
 // ErrorViewer displays test failures in an interactive TUI
@@ -41,16 +41,16 @@ type ErrorViewer struct {
 	config  *config.Config
 	storage storage.Storage
 	runner  SingleTestRunner
-	parser  *parser.PHPUnitParser
+	parser  parser.Parser
 }
 
 // NewErrorViewer creates a new ErrorViewer
-func NewErrorViewer(cfg *config.Config, st storage.Storage, runner SingleTestRunner, phpUnitParser *parser.PHPUnitParser) *ErrorViewer {
+func NewErrorViewer(cfg *config.Config, st storage.Storage, runner SingleTestRunner, resultParser parser.Parser) *ErrorViewer {
 	return &ErrorViewer{
 		config:  cfg,
 		storage: st,
 		runner:  runner,
-		parser:  phpUnitParser,
+		parser:  resultParser,
 	}
 }
 
@@ -62,84 +62,10 @@ func failureKey(f *domain.TestFailure) string {
 func failureKeyNormalized(f *domain.TestFailure) string {
 	path := strings.ReplaceAll(f.FilePath, "\\", "/")
 	path = strings.TrimSuffix(path, ".php")
-	name := normalizeTestNameForSearch(f.TestName)
+	name := discovery.NormalizeTestName(f.TestName)
 	return path + "\x00" + name
 }
 
-// normalizeTestNameForSearch strips data provider suffix and trims (e.g. "test_foo with data set #0" -> "test_foo").
-func normalizeTestNameForSearch(s string) string {
-	s = strings.TrimSpace(s)
-	if i := strings.Index(s, " with "); i >= 0 {
-		s = s[:i]
-	}
-	return strings.TrimSpace(s)
-}
-
-// snakeToCamel converts test_foo_bar to testFooBar.
-func snakeToCamel(s string) string {
-	parts := strings.Split(s, "_")
-	for i := 1; i < len(parts); i++ {
-		if len(parts[i]) > 0 {
-			parts[i] = strings.ToUpper(string(parts[i][0])) + strings.ToLower(parts[i][1:])
-		}
-	}
-	return strings.Join(parts, "")
-}
-
-// camelToSnake converts testFooBar to test_foo_bar.
-func camelToSnake(s string) string {
-	var b strings.Builder
-	for i, r := range s {
-		if i > 0 && unicode.IsUpper(r) {
-			b.WriteByte('_')
-		}
-		b.WriteRune(unicode.ToLower(r))
-	}
-	return b.String()
-}
-
-// findTestFunctionLine returns the 1-based line number of the PHP function matching testName in the file, or 0 if not found.
-func findTestFunctionLine(filePath, testName string) int {
-	testName = normalizeTestNameForSearch(testName)
-	if testName == "" {
-		return 0
-	}
-	// Try exact name and common PHP naming variants (snake_case vs camelCase).
-	namesToTry := []string{testName}
-	if strings.Contains(testName, "_") {
-		namesToTry = append(namesToTry, snakeToCamel(testName))
-	} else {
-		namesToTry = append(namesToTry, camelToSnake(testName))
-	}
-
-	f, err := os.Open(filePath)
-	if err != nil {
-		return 0
-	}
-	defer f.Close()
-	scanner := bufio.NewScanner(f)
-	lineNum := 0
-	for scanner.Scan() {
-		lineNum++
-		line := scanner.Text()
-		idx := strings.Index(line, "function ")
-		if idx < 0 {
-			continue
-		}
-		after := strings.TrimSpace(line[idx+len("function "):])
-		// Strip trailing ( and everything after for comparison
-		if p := strings.Index(after, "("); p >= 0 {
-			after = strings.TrimSpace(after[:p])
-		}
-		for _, name := range namesToTry {
-			if after == name || strings.HasPrefix(after, name+" ") {
-				return lineNum
-			}
-		}
-	}
-	return 0
-}
-
 // View displays test failures in an interactive TUI (LaraMux-inspired design)
 func (ev *ErrorViewer) View(results *domain.TestResultsOutput) error {
 	if len(results.Details) == 0 {
@@ -147,6 +73,10 @@ func (ev *ErrorViewer) View(results *domain.TestResultsOutput) error {
 		return nil
 	}
 
+	// Reruns still in flight are killed when the viewer closes
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	// Marked test cases (temporary selection for group rerun). Key = failureKey(failure).
 	marked := make(map[string]bool)
 	// Running: test cases currently being re-run (show loader). Key = failureKey(failure).
@@ -214,10 +144,14 @@ func (ev *ErrorViewer) View(results *domain.TestResultsOutput) error {
 			}
 		}
 		if filterStr == "" {
+			counts := fmt.Sprintf("%d failures", len(results.Details))
+			if flaky := domain.CountFlaky(results.Details); flaky > 0 {
+				counts = fmt.Sprintf("%d failures  ·  %d flaky", len(results.Details)-flaky, flaky)
+			}
 			if markedCount > 0 {
-				headerRight.SetText(fmt.Sprintf("%d failures  ·  %d marked ", len(results.Details), markedCount))
+				headerRight.SetText(fmt.Sprintf("%s  ·  %d marked ", counts, markedCount))
 			} else {
-				headerRight.SetText(fmt.Sprintf("%d failures ", len(results.Details)))
+				headerRight.SetText(counts + " ")
 			}
 		} else {
 			if markedCount > 0 {
@@ -235,7 +169,7 @@ func (ev *ErrorViewer) View(results *domain.TestResultsOutput) error {
 			tview.NewFlex().
 				SetDirection(tview.FlexColumn).
 				AddItem(headerLeft, 0, 1, false).
-				AddItem(headerRight, 20, 0, false),
+				AddItem(headerRight, 40, 0, false),
 			1, 0, false,
 		).
 		AddItem(newSeparatorLine(faillsAccent), 1, 0, false)
@@ -256,6 +190,9 @@ func (ev *ErrorViewer) View(results *domain.TestResultsOutput) error {
 		if selected {
 			prefix = "[cyan]►[white] "
 		}
+		if failure.Flaky {
+			testName = "[black:yellow]FLAKY[white:-] " + testName
+		}
 		if runningKeys[failureKey(failure)] {
 			return fmt.Sprintf("%s[yellow]  ⟳  [white] %d. %s", prefix, listPos, testName)
 		}
@@ -524,7 +461,7 @@ func (ev *ErrorViewer) View(results *domain.TestResultsOutput) error {
 		}
 		line := failure.Line
 		if line <= 0 && failure.TestName != "" {
-			line = findTestFunctionLine(absPath, failure.TestName)
+			line = discovery.FindTestFunctionLine(absPath, failure.TestName)
 		}
 		if line > 0 {
 			args = append(args, "+"+strconv.Itoa(line))
@@ -637,7 +574,7 @@ func (ev *ErrorViewer) View(results *domain.TestResultsOutput) error {
 						runPath = runPath + ".php"
 					}
 				}
-				result := ev.runner.RunFiltered(runPath, f.TestName, 1)
+				result := ev.runner.RunFiltered(ctx, runPath, f.TestName, 1)
 				if result.Success {
 					toRemove[normKey] = true
 					toRemoveOrigKeys = append(toRemoveOrigKeys, origKey)
@@ -691,7 +628,8 @@ func (ev *ErrorViewer) View(results *domain.TestResultsOutput) error {
 					}
 				}
 				results.Details = newDetails
-				results.Meta.FailedTestCases = len(newDetails)
+				results.Meta.FlakyTestCases = domain.CountFlaky(newDetails)
+				results.Meta.FailedTestCases = len(newDetails) - results.Meta.FlakyTestCases
 
 				if err := ev.storage.SaveOutput(results); err != nil {
 					detailsView.SetText("[yellow]Failed to save results: " + err.Error() + "[white]")
@@ -840,13 +778,28 @@ func (ev *ErrorViewer) formatFailureDetails(failure domain.TestFailure) string {
 	var builder strings.Builder
 	w := tabwriter.NewWriter(&builder, 0, 0, 2, ' ', 0)
 
-	fmt.Fprintf(w, "[red]✗ Test: %s[white]\n\n", failure.TestName)
+	if failure.Flaky {
+		fmt.Fprintf(w, "[yellow]⚠ Flaky test: %s[white] (failed, then passed on a retry)\n\n", failure.TestName)
+	} else {
+		fmt.Fprintf(w, "[red]✗ Test: %s[white]\n\n", failure.TestName)
+	}
 	fmt.Fprintf(w, "[cyan]File: %s[white]\n", failure.FilePath)
 	if failure.File != "" && failure.Line > 0 {
 		fmt.Fprintf(w, "[yellow]Location: %s:%d[white]\n", failure.File, failure.Line)
 	}
 	fmt.Fprintf(w, "\n")
 
+	if len(failure.Attempts) > 0 {
+		fmt.Fprintf(w, "[yellow]Attempts:[white]\n")
+		for i, a := range failure.Attempts {
+			statusColor := "red"
+			if a.Status == domain.CasePassed {
+				statusColor = "green"
+			}
+			fmt.Fprintf(w, "  %d. [%s]%s[white] (%.2fs)\n", i+1, statusColor, a.Status, a.DurationSeconds)
+		}
+		fmt.Fprintf(w, "\n")
+	}
 	if failure.Message != "" {
 		fmt.Fprintf(w, "[yellow]Message:[white]\n%s\n\n", failure.Message)
 	}
@@ -7,11 +7,13 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"ptp/internal/config"
 	"ptp/internal/domain"
 	"ptp/internal/discovery"
+	"ptp/internal/report"
 )
 
 // Formatter formats and displays output
@@ -78,6 +80,34 @@ func (f *Formatter) PrintMetaStats() error {
 	color.Red("%-27d │\n", meta.FailedTestCases)
 	fmt.Println("├─────────────────────────────────┼─────────────────────────────┤")
 
+	// Skipped / incomplete test cases (only known when PHPUnit wrote a JUnit log)
+	if meta.SkippedTestCases > 0 {
+		fmt.Printf("│ %-31s │ ", "Skipped/Incomplete Test Cases")
+		color.Yellow("%-27d │\n", meta.SkippedTestCases)
+		fmt.Println("├─────────────────────────────────┼─────────────────────────────┤")
+	}
+
+	// Cases that failed and then passed on a retry
+	if meta.FlakyTestCases > 0 {
+		fmt.Printf("│ %-31s │ ", "Flaky Test Cases")
+		color.Yellow("%-27d │\n", meta.FlakyTestCases)
+		fmt.Println("├─────────────────────────────────┼─────────────────────────────┤")
+	}
+
+	// Files killed by --timeout (also counted as failed)
+	if meta.TimedOutFiles > 0 {
+		fmt.Printf("│ %-31s │ ", "Timed Out Test Files")
+		color.Red("%-27d │\n", meta.TimedOutFiles)
+		fmt.Println("├─────────────────────────────────┼─────────────────────────────┤")
+	}
+
+	// Files still running when the run was cancelled
+	if meta.InterruptedFiles > 0 {
+		fmt.Printf("│ %-31s │ ", "Interrupted Test Files")
+		color.Yellow("%-27d │\n", meta.InterruptedFiles)
+		fmt.Println("├─────────────────────────────────┼─────────────────────────────┤")
+	}
+
 	// Duration
 	fmt.Printf("│ %-31s │ ", "Duration")
 	durationStr := fmt.Sprintf("%.2fs", meta.DurationSeconds)
@@ -102,12 +132,266 @@ func (f *Formatter) PrintMetaStats() error {
 	} else {
 		color.Red("✗ %d test file(s) failed with %d test case failure(s)", meta.FailedTestFiles, meta.FailedTestCases)
 		fmt.Println()
-		f.printFailedTestsTree(output.Details)
+		f.printFailedTestsTree(domain.WithoutFlaky(output.Details))
+	}
+	if meta.FlakyTestCases > 0 {
+		fmt.Println()
+		color.Yellow("⚠ %d flaky test case(s) failed, then passed on a retry:", meta.FlakyTestCases)
+		for _, failure := range output.Details {
+			if failure.Flaky {
+				fmt.Printf("  %s::%s (%d attempts)\n", failure.FilePath, failure.TestName, len(failure.Attempts))
+			}
+		}
 	}
 
 	return nil
 }
 
+// PrintWorkerLoads prints predicted vs actual busy time per worker and the resulting tail,
+// with how much of the execution each worker was busy and how long it sat idle at the end
+func (f *Formatter) PrintWorkerLoads(loads []domain.WorkerLoad) {
+	if len(loads) == 0 {
+		return
+	}
+
+	fmt.Println()
+	color.Cyan("Worker load (predicted vs actual):")
+	fmt.Println("┌────────┬───────┬─────────────┬─────────────┬──────┬───────────┐")
+	fmt.Printf("│ %-6s │ %-5s │ %-11s │ %-11s │ %-4s │ %-9s │\n", "Worker", "Files", "Predicted", "Actual", "Busy", "Idle tail")
+	fmt.Println("├────────┼───────┼─────────────┼─────────────┼──────┼───────────┤")
+
+	minPredicted, maxPredicted := loads[0].Predicted, loads[0].Predicted
+	minActual, maxActual := loads[0].Actual, loads[0].Actual
+	var utilization float64
+	var maxIdle time.Duration
+	for _, l := range loads {
+		fmt.Printf("│ %-6d │ %-5d │ %-11s │ ", l.WorkerID, l.Files, fmt.Sprintf("%.2fs", l.Predicted.Seconds()))
+		fmt.Print(color.WhiteString("%-11s", fmt.Sprintf("%.2fs", l.Actual.Seconds())), " │ ")
+		busy := fmt.Sprintf("%3.0f%%", l.Utilization*100)
+		if l.Utilization < 0.75 {
+			busy = color.YellowString(busy)
+		}
+		fmt.Printf("%s │ %-9s │\n", busy, fmt.Sprintf("%.2fs", l.IdleTail.Seconds()))
+		minPredicted = min(minPredicted, l.Predicted)
+		maxPredicted = max(maxPredicted, l.Predicted)
+		minActual = min(minActual, l.Actual)
+		maxActual = max(maxActual, l.Actual)
+		utilization += l.Utilization
+		maxIdle = max(maxIdle, l.IdleTail)
+	}
+	fmt.Println("└────────┴───────┴─────────────┴─────────────┴──────┴───────────┘")
+
+	fmt.Printf("Tail (slowest - fastest worker): predicted %.2fs, ", (maxPredicted - minPredicted).Seconds())
+	tail := maxActual - minActual
+	if maxActual > 0 && tail*4 > maxActual {
+		color.Yellow("actual %.2fs", tail.Seconds())
+	} else {
+		color.Green("actual %.2fs", tail.Seconds())
+	}
+	fmt.Printf("Utilization: %.0f%% of %d worker(s), longest idle tail %.2fs\n", utilization/float64(len(loads))*100, len(loads), maxIdle.Seconds())
+}
+
+// PrintCoverage prints the line and branch coverage of the merged coverage report at output
+func (f *Formatter) PrintCoverage(summary domain.CoverageSummary, output string) {
+	fmt.Println()
+	color.Cyan("Code coverage:")
+	fmt.Println("┌──────────┬─────────┬─────────────────────┐")
+	fmt.Printf("│ %-8s │ %7s │ %-19s │\n", "", "Percent", "Covered / total")
+	fmt.Println("├──────────┼─────────┼─────────────────────┤")
+	printCoverageRow("Lines", summary.LinePercent(), summary.CoveredLines, summary.Lines)
+	if summary.Branches > 0 {
+		printCoverageRow("Branches", summary.BranchPercent(), summary.CoveredBranches, summary.Branches)
+	} else {
+		fmt.Printf("│ %-8s │ %7s │ %-19s │\n", "Branches", "-", "not recorded")
+	}
+	fmt.Println("└──────────┴─────────┴─────────────────────┘")
+	fmt.Printf("Coverage report: %s\n", output)
+}
+
+func printCoverageRow(name string, percent float64, covered, total int) {
+	value := fmt.Sprintf("%6.2f%%", percent)
+	switch {
+	case percent >= 80:
+		value = color.GreenString(value)
+	case percent >= 50:
+		value = color.YellowString(value)
+	default:
+		value = color.RedString(value)
+	}
+	fmt.Printf("│ %-8s │ %s │ %-19s │\n", name, value, fmt.Sprintf("%d / %d", covered, total))
+}
+
+// PrintWatchRunning shows the live status line of a watch-mode run; PrintWatchStatus replaces it
+func (f *Formatter) PrintWatchRunning(tests, changed int) {
+	fmt.Printf("\r\033[K%s", color.CyanString("⟳ %s running %d test file(s) for %d changed file(s)...", time.Now().Format("15:04:05"), tests, changed))
+}
+
+// PrintWatchStatus replaces the live status line with the outcome of a watch-mode run and lists
+// its failing test cases. total is the number of failing cases left in the stored results.
+func (f *Formatter) PrintWatchStatus(results []domain.TestResult, failures []domain.TestFailure, duration time.Duration, total int) {
+	fmt.Print("\r\033[K")
+	stamp := time.Now().Format("15:04:05")
+	failing := domain.WithoutFlaky(failures)
+	failedFiles := 0
+	for _, r := range results {
+		if !r.Success && !r.Interrupted {
+			failedFiles++
+		}
+	}
+	if len(failing) == 0 && failedFiles == 0 {
+		summary := fmt.Sprintf("✓ %s %d test file(s) passed in %.2fs", stamp, len(results), duration.Seconds())
+		if flaky := len(failures) - len(failing); flaky > 0 {
+			summary += fmt.Sprintf(" (%d flaky)", flaky)
+		}
+		color.Green(summary)
+	} else {
+		color.Red("✗ %s %d failing test case(s) in %d of %d test file(s) (%.2fs)", stamp, len(failing), failedFiles, len(results), duration.Seconds())
+		for _, fail := range failing {
+			path := fail.FilePath
+			if rel, err := filepath.Rel(f.config.ProjectPath, path); err == nil && !strings.HasPrefix(rel, "..") {
+				path = rel
+			}
+			fmt.Printf("    %s::%s  %s\n", filepath.ToSlash(path), fail.TestName, color.HiBlackString(report.FirstLine(fail.Message)))
+		}
+	}
+	if total > len(failing) {
+		color.Yellow("    %d failing test case(s) in the stored results (ptp faills)", total)
+	}
+}
+
+// PrintFlakyHistory lists the flakiest test cases, most frequent first (limit 0 = all)
+func (f *Formatter) PrintFlakyHistory(history map[string]*domain.FlakyStats, limit int) {
+	if len(history) == 0 {
+		color.Green("No flaky tests recorded. Run with --retries=N to detect them.")
+		return
+	}
+
+	stats := make([]*domain.FlakyStats, 0, len(history))
+	for _, s := range history {
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		if stats[i].LastSeen != stats[j].LastSeen {
+			return stats[i].LastSeen > stats[j].LastSeen
+		}
+		return stats[i].FilePath+stats[i].TestName < stats[j].FilePath+stats[j].TestName
+	})
+	if limit > 0 && len(stats) > limit {
+		stats = stats[:limit]
+	}
+
+	color.Cyan("Flakiest test cases (%d of %d):", len(stats), len(history))
+	fmt.Println("┌───────┬──────────────────┬──────────────────────────────")
+	fmt.Printf("│ %-5s │ %-16s │ %s\n", "Flaky", "Last seen", "Test")
+	fmt.Println("├───────┼──────────────────┼──────────────────────────────")
+	for _, s := range stats {
+		path := s.FilePath
+		if rel, err := filepath.Rel(f.config.ProjectPath, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+		lastSeen := s.LastSeen
+		if t, err := time.Parse(time.RFC3339, s.LastSeen); err == nil {
+			lastSeen = t.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Printf("│ ")
+		color.New(color.FgYellow).Printf("%-5d", s.Count)
+		fmt.Printf(" │ %-16s │ %s::%s\n", lastSeen, filepath.ToSlash(path), s.TestName)
+	}
+	fmt.Println("└───────┴──────────────────┴──────────────────────────────")
+}
+
+// PrintRunHistory lists the retained runs, newest first
+func (f *Formatter) PrintRunHistory(runs []domain.TestResultsMeta) {
+	if len(runs) == 0 {
+		color.Yellow("No runs in the history yet (history_size: %d).", f.config.HistorySize)
+		return
+	}
+
+	fmt.Printf("%-18s  %-16s  %-20s  %-8s  %5s  %6s  %5s  %9s  %s\n",
+		"Run", "Started", "Branch", "Commit", "Files", "Failed", "Flaky", "Duration", "Arguments")
+	for _, run := range runs {
+		started := run.Timestamp
+		if t, err := time.Parse(time.RFC3339, run.Timestamp); err == nil {
+			started = t.Local().Format("2006-01-02 15:04")
+		}
+		commit := run.GitCommit
+		if len(commit) > 8 {
+			commit = commit[:8]
+		}
+		branch := run.GitBranch
+		if runes := []rune(branch); len(runes) > 20 {
+			branch = string(runes[:19]) + "…"
+		}
+		fmt.Printf("%-18s  %-16s  %-20s  %-8s  %5d  ", run.RunID, started, branch, commit, run.TotalTestFiles)
+		failed := fmt.Sprintf("%6d", run.FailedTestFiles)
+		switch {
+		case run.FailedTestFiles > 0:
+			color.New(color.FgRed).Print(failed)
+		case run.InterruptedFiles > 0:
+			color.New(color.FgYellow).Print(failed)
+		default:
+			color.New(color.FgGreen).Print(failed)
+		}
+		fmt.Printf("  %5d  %9s  %s\n", run.FlakyTestCases, fmt.Sprintf("%.2fs", run.DurationSeconds), strings.Join(run.Args, " "))
+	}
+}
+
+// PrintRunDiff prints the comparison of two runs
+func (f *Formatter) PrintRunDiff(d *domain.RunDiff) {
+	color.Cyan("Comparing run %s with %s", report.DescribeRun(d.Base), report.DescribeRun(d.Head))
+	fmt.Println()
+
+	printChanges := func(title string, changes []domain.FailureChange, print func(string, ...interface{}), showMessage func(domain.FailureChange)) {
+		if len(changes) == 0 {
+			return
+		}
+		print("%s (%d):", title, len(changes))
+		for _, c := range changes {
+			fmt.Printf("  %s::%s\n", filepath.ToSlash(c.FilePath), c.TestName)
+			if showMessage != nil {
+				showMessage(c)
+			}
+		}
+		fmt.Println()
+	}
+	message := func(msg string) {
+		if line := report.FirstLine(msg); line != "" {
+			fmt.Printf("      %s\n", line)
+		}
+	}
+
+	printChanges("✗ Newly failing", d.NewlyFailing, color.Red, func(c domain.FailureChange) { message(c.HeadMessage) })
+	printChanges("✓ Fixed", d.Fixed, color.Green, nil)
+	printChanges("↻ Changed message", d.ChangedMessage, color.Yellow, func(c domain.FailureChange) {
+		color.New(color.FgHiBlack).Printf("      - %s\n", report.FirstLine(c.BaseMessage))
+		fmt.Printf("      + %s\n", report.FirstLine(c.HeadMessage))
+	})
+	printChanges("Still failing", d.StillFailing, color.White, nil)
+
+	if len(d.Slower) > 0 {
+		color.Yellow("Slower files (at least %g%% slower):", d.SlowerThreshold)
+		for _, s := range d.Slower {
+			fmt.Printf("  %-60s %8.2fs → %8.2fs  ", filepath.ToSlash(s.FilePath), s.BaseSeconds, s.HeadSeconds)
+			color.New(color.FgYellow).Printf("+%.0f%%", s.Percent())
+			if s.AvgSeconds > 0 {
+				fmt.Printf("  (avg %.2fs)", s.AvgSeconds)
+			}
+			fmt.Println()
+		}
+		fmt.Println()
+	}
+
+	if len(d.NewlyFailing)+len(d.Fixed)+len(d.ChangedMessage)+len(d.StillFailing)+len(d.Slower) == 0 {
+		color.Green("No differences.")
+	}
+	if d.NotRun > 0 {
+		fmt.Printf("%d failure(s) of the older run were not compared: their files did not run in the newer run.\n", d.NotRun)
+	}
+}
+
 // TreeNode represents a node in the file tree structure
 type TreeNode struct {
 	Name     string
@@ -234,8 +518,9 @@ func (f *Formatter) printTreeNode(node *TreeNode, prefix string, isLast bool, is
 // CountTestCases returns the total number of test cases across the given test files.
 func (f *Formatter) CountTestCases(tests []string) (int, error) {
 	var total int
+	include, exclude := f.config.GetGroups()
 	for _, test := range tests {
-		cases, err := f.parser.FindTestCases(test)
+		cases, err := f.parser.FindTestCasesInGroups(test, include, exclude)
 		if err != nil {
 			return 0, err
 		}
@@ -264,8 +549,9 @@ func (f *Formatter) PrintTestList(tests []string, showTestCases bool, failedPath
 		// Display tree view with test cases
 		color.Green("Found %d test file(s) with test cases:\n", len(tests))
 
+		include, exclude := f.config.GetGroups()
 		for i, test := range tests {
-			testCases, err := f.parser.FindTestCases(test)
+			testCases, err := f.parser.FindTestCasesInGroups(test, include, exclude)
 			if err != nil {
 				color.Red("Error reading test file %s: %v", test, err)
 				continue
@@ -58,7 +58,6 @@ func NewProgressBar(fileCount, testCaseCount int) *ProgressBar {
 // Update updates the progress bar. filesCompleted is the bar position (0..fileCount);
 // passedCases and failedCases are test case counts shown in the label.
 func (p *ProgressBar) Update(filesCompleted, passedCases, failedCases int) {
-	p.bar.Set(filesCompleted)
 	descCount := p.totalCount
 	descLabel := "files"
 	if p.testCaseCount > 0 {
@@ -72,6 +71,8 @@ func (p *ProgressBar) Update(filesCompleted, passedCases, failedCases int) {
 			" | " +
 			color.RedString("failed: %d]", failedCases),
 	)
+	// Set last: reaching the total ends the bar's line, so nothing may redraw it afterwards
+	p.bar.Set(filesCompleted)
 }
 
 // Finish completes the progress bar
@@ -0,0 +1,143 @@
+package vcs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"ptp/internal/debug"
+)
+
+// Info describes the checked-out revision of a git work tree
+type Info struct {
+	Commit string
+	Branch string // empty on a detached HEAD
+}
+
+// Detect returns the current commit and branch of the git work tree containing dir
+func Detect(dir string) (Info, error) {
+	commit, err := git(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return Info{}, err
+	}
+	info := Info{Commit: commit}
+	if branch, err := git(dir, "rev-parse", "--abbrev-ref", "HEAD"); err == nil && branch != "HEAD" {
+		info.Branch = branch
+	}
+	debug.Logf("vcs: %s is at %s (branch %q)", dir, info.Commit, info.Branch)
+	return info, nil
+}
+
+// Root returns the top-level directory of the git work tree containing dir
+func Root(dir string) (string, error) {
+	return git(dir, "rev-parse", "--show-toplevel")
+}
+
+// Revision identifies the exact contents of the work tree containing dir: the commit, followed by
+// "+" and a hash of the uncommitted changes (including untracked files) when there are any. Two
+// checkouts with the same revision run the same code. Changes below the exclude paths (relative to
+// dir, e.g. generated output) do not count.
+func Revision(dir string, exclude ...string) (string, error) {
+	commit, err := git(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	pathspec := []string{"--", "."}
+	for _, path := range exclude {
+		pathspec = append(pathspec, ":(exclude)"+filepath.ToSlash(path))
+	}
+	diff, err := git(dir, append([]string{"diff", "--binary", "HEAD"}, pathspec...)...)
+	if err != nil {
+		return "", err
+	}
+	untracked, err := git(dir, append([]string{"ls-files", "--others", "--exclude-standard"}, pathspec...)...)
+	if err != nil {
+		return "", err
+	}
+	if diff == "" && untracked == "" {
+		return commit, nil
+	}
+	h := sha256.New()
+	h.Write([]byte(diff))
+	for _, name := range strings.Split(untracked, "\n") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		content, _ := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		fmt.Fprintf(h, "\x00%s\x00%d\x00", name, len(content))
+		h.Write(content)
+	}
+	return commit + "+" + hex.EncodeToString(h.Sum(nil))[:12], nil
+}
+
+// git runs a git command in dir and returns its trimmed output
+func git(dir string, args ...string) (string, error) {
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Upstream is the ref `--changed` compares against by default: the branch's upstream, falling
+// back to HEAD (uncommitted changes only) when there is none
+const Upstream = "@{upstream}"
+
+// ChangedFiles returns the absolute paths of the files changed in the work tree containing dir
+// since the merge base of ref and HEAD: committed, staged and unstaged changes as well as
+// untracked files. Deleted files are included, they may still be referenced by tests.
+func ChangedFiles(dir, ref string) ([]string, error) {
+	root, err := Root(dir)
+	if err != nil {
+		return nil, err
+	}
+	if ref == "" {
+		ref = Upstream
+	}
+	if ref == Upstream {
+		if _, err := git(dir, "rev-parse", "--verify", "--quiet", ref); err != nil {
+			debug.Logf("vcs: no upstream branch, comparing against HEAD")
+			ref = "HEAD"
+		}
+	}
+	if _, err := git(dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+		return nil, fmt.Errorf("unknown git ref %q", ref)
+	}
+	base := ref
+	if ref != "HEAD" {
+		if mergeBase, err := git(dir, "merge-base", ref, "HEAD"); err == nil {
+			base = mergeBase
+		}
+	}
+
+	diff, err := git(dir, "diff", "--name-only", "--no-renames", base, "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := git(dir, "ls-files", "--others", "--exclude-standard", "--full-name")
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	for _, name := range strings.Split(diff+"\n"+untracked, "\n") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		files = append(files, filepath.Join(root, filepath.FromSlash(name)))
+	}
+	debug.Logf("vcs: %d files changed since %s (base %s)", len(files), ref, base)
+	return files, nil
+}
@@ -0,0 +1,142 @@
+package vcs
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func gitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.email=ptp@example.com", "-c", "user.name=ptp"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run("init", "-q", "-b", "main")
+	write("app/User.php", "<?php")
+	write("app/Post.php", "<?php")
+	run("add", "-A")
+	run("commit", "-q", "-m", "initial")
+	run("checkout", "-q", "-b", "feature")
+	write("app/User.php", "<?php // committed change")
+	run("commit", "-q", "-am", "change user")
+	write("app/Post.php", "<?php // uncommitted change")
+	write("tests/NewTest.php", "<?php")
+	return dir
+}
+
+func TestChangedFiles(t *testing.T) {
+	dir := gitRepo(t)
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel := func(files []string) []string {
+		var out []string
+		for _, f := range files {
+			r, err := filepath.Rel(root, f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, filepath.ToSlash(r))
+		}
+		sort.Strings(out)
+		return out
+	}
+
+	t.Run("against a branch", func(t *testing.T) {
+		files, err := ChangedFiles(dir, "main")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := rel(files); len(got) != 3 || got[0] != "app/Post.php" || got[1] != "app/User.php" || got[2] != "tests/NewTest.php" {
+			t.Errorf("unexpected changed files %v", got)
+		}
+	})
+
+	t.Run("without upstream falls back to HEAD", func(t *testing.T) {
+		files, err := ChangedFiles(dir, Upstream)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := rel(files); len(got) != 2 || got[0] != "app/Post.php" || got[1] != "tests/NewTest.php" {
+			t.Errorf("unexpected changed files %v", got)
+		}
+	})
+
+	t.Run("unknown ref", func(t *testing.T) {
+		if _, err := ChangedFiles(dir, "no-such-branch"); err == nil {
+			t.Error("expected an error for an unknown ref")
+		}
+	})
+}
+
+func TestDetect(t *testing.T) {
+	dir := gitRepo(t)
+	info, err := Detect(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(info.Commit) != 40 || info.Branch != "feature" {
+		t.Errorf("unexpected info %+v", info)
+	}
+}
+
+func TestRevision(t *testing.T) {
+	dir := gitRepo(t)
+	info, err := Detect(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirty, err := Revision(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(dirty, info.Commit+"+") {
+		t.Errorf("expected the uncommitted changes in the revision, got %q", dirty)
+	}
+	if again, _ := Revision(dir); again != dirty {
+		t.Errorf("revision is not stable: %q then %q", dirty, again)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "tests", "NewTest.php"), []byte("<?php // edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if edited, _ := Revision(dir); edited == dirty {
+		t.Error("expected an edit of an untracked file to change the revision")
+	}
+	excluded, _ := Revision(dir, "tests")
+	if err := os.WriteFile(filepath.Join(dir, "tests", "Other.php"), []byte("<?php"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := Revision(dir, "tests"); again != excluded {
+		t.Error("expected changes in an excluded directory not to change the revision")
+	}
+
+	if out, err := exec.Command("git", "-C", dir, "-c", "user.email=ptp@example.com", "-c", "user.name=ptp", "stash", "-q", "-u").CombinedOutput(); err != nil {
+		t.Fatalf("git stash: %v\n%s", err, out)
+	}
+	if clean, _ := Revision(dir); clean != info.Commit {
+		t.Errorf("expected the bare commit for a clean tree, got %q", clean)
+	}
+}
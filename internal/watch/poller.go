@@ -0,0 +1,151 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"ptp/internal/debug"
+)
+
+// fileState is what a poll compares to notice that a file changed
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Poller detects created, modified and deleted files by polling the tree, so it works the same on
+// every platform and file system (including network mounts and containers) without OS watches.
+type Poller struct {
+	roots    []string
+	skipDirs map[string]bool
+	exts     []string
+	files    map[string]fileState
+}
+
+// NewPoller creates a Poller for the files with one of the extensions under roots. Directories
+// named in skipDirs are not entered, except when they are a root themselves.
+func NewPoller(roots []string, skipDirs []string, exts ...string) *Poller {
+	skip := make(map[string]bool, len(skipDirs))
+	for _, dir := range skipDirs {
+		skip[dir] = true
+	}
+	abs := make([]string, 0, len(roots))
+	for _, root := range roots {
+		if a, err := filepath.Abs(root); err == nil {
+			root = a
+		}
+		abs = append(abs, root)
+	}
+	return &Poller{roots: abs, skipDirs: skip, exts: exts}
+}
+
+// Poll returns the absolute paths of the files created, modified or deleted since the previous
+// poll, sorted. The first poll only records the current state and returns nothing.
+func (p *Poller) Poll() []string {
+	current := p.scan()
+	if p.files == nil {
+		p.files = current
+		return nil
+	}
+	var changed []string
+	for path, state := range current {
+		if prev, ok := p.files[path]; !ok || prev != state {
+			changed = append(changed, path)
+		}
+	}
+	for path := range p.files {
+		if _, ok := current[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	p.files = current
+	sort.Strings(changed)
+	return changed
+}
+
+func (p *Poller) scan() map[string]fileState {
+	files := make(map[string]fileState)
+	for _, root := range p.roots {
+		err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				// Files can vanish between listing and stat while an editor saves
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() {
+				if path != root && (p.skipDirs[d.Name()] || strings.HasPrefix(d.Name(), ".")) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !p.matches(d.Name()) {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			files[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			debug.Logf("watch: scanning %s failed: %v", root, err)
+		}
+	}
+	return files
+}
+
+func (p *Poller) matches(name string) bool {
+	if len(p.exts) == 0 {
+		return true
+	}
+	for _, ext := range p.exts {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Watch polls every interval and calls onChange with the files changed in a burst of saves once
+// no further change was seen for the debounce period. onChange runs on the polling goroutine, so
+// changes made while it runs are reported by the next call. Watch returns when ctx is done.
+func Watch(ctx context.Context, p *Poller, interval, debounce time.Duration, onChange func(changed []string)) {
+	p.Poll()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	pending := make(map[string]bool)
+	var lastChange time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if changed := p.Poll(); len(changed) > 0 {
+				for _, path := range changed {
+					pending[path] = true
+				}
+				lastChange = now
+				debug.Logf("watch: %d file(s) changed, waiting %s for more", len(changed), debounce)
+				continue
+			}
+			if len(pending) == 0 || now.Sub(lastChange) < debounce {
+				continue
+			}
+			batch := make([]string, 0, len(pending))
+			for path := range pending {
+				batch = append(batch, path)
+			}
+			sort.Strings(batch)
+			pending = make(map[string]bool)
+			onChange(batch)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPoller_Poll(t *testing.T) {
+	dir := t.TempDir()
+	user := filepath.Join(dir, "app", "User.php")
+	post := filepath.Join(dir, "app", "Post.php")
+	migration := filepath.Join(dir, "database", "migrations", "create_users.php")
+	writeFile(t, user, "<?php")
+	writeFile(t, post, "<?php")
+	writeFile(t, filepath.Join(dir, "vendor", "lib.php"), "<?php")
+	writeFile(t, migration, "<?php")
+
+	p := NewPoller([]string{dir, filepath.Join(dir, "database", "migrations")}, []string{"vendor", "database"}, ".php")
+	if changed := p.Poll(); changed != nil {
+		t.Fatalf("expected the first poll to only record the state, got %v", changed)
+	}
+
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(user, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(post); err != nil {
+		t.Fatal(err)
+	}
+	created := filepath.Join(dir, "tests", "UserTest.php")
+	writeFile(t, created, "<?php")
+	writeFile(t, filepath.Join(dir, "vendor", "lib.php"), "<?php // ignored")
+	writeFile(t, filepath.Join(dir, "app", "notes.txt"), "not php")
+	writeFile(t, migration, "<?php // changed")
+
+	want := []string{post, user, migration, created}
+	if got := p.Poll(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Poll() = %v, want %v", got, want)
+	}
+	if got := p.Poll(); got != nil {
+		t.Errorf("expected no changes on an idle poll, got %v", got)
+	}
+}
+
+func TestWatch_Debounces(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "A.php")
+	second := filepath.Join(dir, "B.php")
+	p := NewPoller([]string{dir}, nil, ".php")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	batches := make(chan []string, 4)
+	go Watch(ctx, p, 10*time.Millisecond, 100*time.Millisecond, func(changed []string) {
+		batches <- changed
+	})
+
+	time.Sleep(50 * time.Millisecond)
+	writeFile(t, first, "<?php")
+	time.Sleep(30 * time.Millisecond)
+	writeFile(t, second, "<?php")
+
+	select {
+	case got := <-batches:
+		if want := []string{first, second}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected one batch with both files, got %v", got)
+		}
+	case <-ctx.Done():
+		t.Fatal("no batch reported")
+	}
+	select {
+	case got := <-batches:
+		t.Errorf("unexpected second batch %v", got)
+	case <-time.After(200 * time.Millisecond):
+	}
+}